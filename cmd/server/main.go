@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -13,14 +16,19 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 
 	_ "task-scheduler/docs"
+	"task-scheduler/internal/blobstore"
+	"task-scheduler/internal/cluster"
 	"task-scheduler/internal/database"
 	"task-scheduler/internal/executor"
 	"task-scheduler/internal/handlers"
 	"task-scheduler/internal/logger"
 	"task-scheduler/internal/metrics"
 	"task-scheduler/internal/middleware"
+	"task-scheduler/internal/models"
+	"task-scheduler/internal/ratelimit"
 	"task-scheduler/internal/repository"
 	"task-scheduler/internal/scheduler"
+	"task-scheduler/internal/secrets"
 )
 
 // @title Task Scheduler API
@@ -50,6 +58,11 @@ func main() {
 	// Initialize repositories
 	taskRepo := repository.NewTaskRepository(database.DB)
 	resultRepo := repository.NewResultRepository(database.DB)
+	cookieRepo := repository.NewCookieRepository(database.DB)
+	workflowRepo := repository.NewWorkflowRepository(database.DB)
+	webhookRepo := repository.NewWebhookRepository(database.DB)
+	calendarRepo := repository.NewCalendarRepository(database.DB)
+	schedulerStateRepo := repository.NewSchedulerStateRepository(database.DB)
 
 	// Initialize logging and metrics
 	logPath := "./logs/tasks.log"
@@ -72,13 +85,168 @@ func main() {
 	systemMetrics := metrics.NewMetrics()
 
 	// Initialize executor and scheduler
-	httpExecutor := executor.NewHTTPExecutor()
-	taskScheduler := scheduler.NewScheduler(taskRepo, resultRepo, httpExecutor, taskLogger, systemMetrics)
+	var executorOpts []executor.HTTPExecutorOption
+	var grpcOpts []executor.GRPCExecutorOption
+	var emailOpts []executor.EmailExecutorOption
+	if profilesFile := os.Getenv("TLS_PROFILES_FILE"); profilesFile != "" {
+		tlsProfiles, err := executor.LoadTLSProfiles(profilesFile, os.Getenv("TLS_ALLOW_INSECURE") == "true")
+		if err != nil {
+			log.Fatal("Failed to load TLS profiles:", err)
+		}
+		executorOpts = append(executorOpts, executor.WithTLSProfiles(tlsProfiles))
+		grpcOpts = append(grpcOpts, executor.WithGRPCTLSProfiles(tlsProfiles))
+		emailOpts = append(emailOpts, executor.WithEmailTLSProfiles(tlsProfiles))
+	}
+	if policyFile := os.Getenv("EGRESS_POLICY_FILE"); policyFile != "" {
+		egressPolicy, err := executor.LoadEgressPolicy(policyFile)
+		if err != nil {
+			log.Fatal("Failed to load egress policy:", err)
+		}
+		executorOpts = append(executorOpts, executor.WithEgressPolicy(egressPolicy))
+		grpcOpts = append(grpcOpts, executor.WithGRPCEgressPolicy(egressPolicy))
+		emailOpts = append(emailOpts, executor.WithEmailEgressPolicy(egressPolicy))
+	}
+
+	blobDir := "./data/blobs"
+	if dir := os.Getenv("BLOB_STORE_DIR"); dir != "" {
+		blobDir = dir
+	}
+	blobStore, err := blobstore.New(blobDir)
+	if err != nil {
+		log.Fatal("Failed to initialize blob store:", err)
+	}
+	executorOpts = append(executorOpts,
+		executor.WithBlobStore(blobStore),
+		executor.WithCookieStore(cookieRepo),
+		executor.WithMaxResponseBytes(int64(getEnvInt("MAX_RESPONSE_BYTES", 0))))
+
+	httpExecutor := executor.NewHTTPExecutor(executorOpts...)
+
+	var commandAllowList []string
+	if allowList := os.Getenv("COMMAND_ALLOWLIST"); allowList != "" {
+		commandAllowList = strings.Split(allowList, ",")
+	}
+	var commandDirs []string
+	if dirs := os.Getenv("COMMAND_DIRS"); dirs != "" {
+		commandDirs = strings.Split(dirs, ",")
+	}
+	commandExecutor := executor.NewCommandExecutor(commandAllowList, executor.WithCommandDirs(commandDirs))
+
+	sqlExecutor, err := executor.NewSQLExecutor(executor.SQLConnectionsConfig{}, nil)
+	if sqlConnectionsFile := os.Getenv("SQL_CONNECTIONS_FILE"); sqlConnectionsFile != "" {
+		schedulerDB, dbErr := database.DB.DB()
+		if dbErr != nil {
+			log.Fatal("Failed to get database handle:", dbErr)
+		}
+		sqlExecutor, err = executor.LoadSQLExecutor(sqlConnectionsFile, schedulerDB)
+	}
+	if err != nil {
+		log.Fatal("Failed to load sql connections:", err)
+	}
+
+	grpcExecutor := executor.NewGRPCExecutor(grpcOpts...)
+
+	secretsDir := "/run/secrets"
+	if dir := os.Getenv("SECRETS_DIR"); dir != "" {
+		secretsDir = dir
+	}
+	secretResolver := secrets.NewResolver(secretsDir)
+	emailExecutor := executor.NewEmailExecutor(secretResolver, emailOpts...)
+
+	// Cancelled on shutdown so runs waiting on a host rate limit give up
+	// instead of holding the process for up to the maximum wait
+	shutdownCtx, shutdown := context.WithCancel(context.Background())
+	defer shutdown()
+
+	var hostLimiter *ratelimit.HostLimiter
+	if rateLimitsFile := os.Getenv("RATE_LIMITS_FILE"); rateLimitsFile != "" {
+		hostLimiter, err = ratelimit.Load(rateLimitsFile)
+		if err != nil {
+			log.Fatal("Failed to load rate limits:", err)
+		}
+	}
+	circuitBreakers := executor.NewCircuitBreakers(executor.CircuitBreakerConfig{
+		WindowSize:           getEnvInt("CIRCUIT_WINDOW_SIZE", 0),
+		MinRequests:          getEnvInt("CIRCUIT_MIN_REQUESTS", 0),
+		FailureRateThreshold: float64(getEnvInt("CIRCUIT_FAILURE_RATE_PERCENT", 0)) / 100,
+		CoolDown:             time.Duration(getEnvInt("CIRCUIT_COOLDOWN_SECONDS", 0)) * time.Second,
+	})
+
+	// Every action kind is registered here with its validation hook
+	actions := executor.NewRegistry()
+	if err := actions.Register(models.ActionTypeHTTP, httpExecutor, httpExecutor.ValidateTask); err != nil {
+		log.Fatal("Failed to register action:", err)
+	}
+	if err := actions.Register(models.ActionTypeCommand, commandExecutor, commandExecutor.ValidateTask); err != nil {
+		log.Fatal("Failed to register action:", err)
+	}
+	if err := actions.Register(models.ActionTypeSQL, sqlExecutor, sqlExecutor.ValidateTask); err != nil {
+		log.Fatal("Failed to register action:", err)
+	}
+	if err := actions.Register(models.ActionTypeGRPC, grpcExecutor, grpcExecutor.ValidateTask); err != nil {
+		log.Fatal("Failed to register action:", err)
+	}
+	if err := actions.Register(models.ActionTypeEmail, emailExecutor, emailExecutor.ValidateTask); err != nil {
+		log.Fatal("Failed to register action:", err)
+	}
+	stepsExecutor := executor.NewHTTPStepsExecutor(httpExecutor)
+	stepsExecutor.EnableHostLimits(shutdownCtx, hostLimiter, circuitBreakers)
+	if err := actions.Register(models.ActionTypeHTTPSteps, stepsExecutor, stepsExecutor.ValidateTask); err != nil {
+		log.Fatal("Failed to register action:", err)
+	}
+
+	var taskExecutor executor.ExecutorInterface = actions
+	if hostLimiter != nil {
+		taskExecutor = executor.NewRateLimitedExecutor(shutdownCtx, actions, hostLimiter)
+	}
+
+	// Retries happen inside the circuit breaker so a dead destination costs
+	// one short-circuited result instead of a full retry loop. Command
+	// actions are never retried.
+	taskExecutor = executor.NewCircuitBreakerExecutor(
+		executor.NewRetryExecutor(taskExecutor, 2, 5*time.Second), circuitBreakers)
+
+	taskScheduler := scheduler.NewScheduler(taskRepo, resultRepo, taskExecutor, taskLogger, systemMetrics)
+	taskScheduler.EnableWorkflows(workflowRepo)
+	taskScheduler.EnableCalendars(calendarRepo)
+	taskScheduler.EnableMaintenance(schedulerStateRepo)
+	taskScheduler.EnableOccurrences(repository.NewOccurrenceRepository(database.DB))
+
+	instanceID := cluster.InstanceID()
+
+	// In cluster mode the replicas sharing the database elect one leader,
+	// which alone schedules tasks; the others serve the API
+	if os.Getenv("CLUSTER_MODE") == "true" {
+		leaseTimeout := time.Duration(getEnvInt("CLUSTER_LEASE_TIMEOUT_SECONDS", 0)) * time.Second
+		elector := cluster.NewElector(repository.NewLeaseRepository(database.DB), instanceID, leaseTimeout)
+		taskScheduler.EnableCluster(elector)
+		log.Printf("Cluster mode enabled as instance %s", instanceID)
+	}
+
+	// With the run queue, due runs go through the task_runs table and any
+	// instance's workers may execute them
+	if os.Getenv("RUN_QUEUE") == "true" {
+		taskScheduler.EnableRunQueue(repository.NewRunRepository(database.DB), scheduler.RunQueueConfig{
+			WorkerID:          instanceID,
+			Workers:           getEnvInt("WORKER_CONCURRENCY", 4),
+			VisibilityTimeout: time.Duration(getEnvInt("RUN_VISIBILITY_TIMEOUT_SECONDS", 0)) * time.Second,
+			MaxAttempts:       getEnvInt("RUN_MAX_ATTEMPTS", 0),
+		})
+	} else {
+		log.Println("RUN_QUEUE is off: scheduled runs execute in memory and fire at most once, not exactly once")
+	}
 
 	// Initialize handlers
-	taskHandler := handlers.NewTaskHandler(taskRepo, resultRepo)
-	resultHandler := handlers.NewResultHandler(resultRepo)
+	taskHandler := handlers.NewTaskHandler(taskRepo, resultRepo, actions)
+	taskHandler.EnableCalendars(calendarRepo)
+	taskHandler.EnableScheduling(taskScheduler)
+	resultHandler := handlers.NewResultHandler(resultRepo, blobStore)
 	metricsHandler := handlers.NewMetricsHandler(systemMetrics)
+	circuitHandler := handlers.NewCircuitHandler(circuitBreakers)
+	workflowHandler := handlers.NewWorkflowHandler(workflowRepo, taskRepo, taskScheduler)
+	webhookHandler := handlers.NewWebhookHandler(taskRepo, webhookRepo, secretResolver, taskScheduler)
+	calendarHandler := handlers.NewCalendarHandler(calendarRepo, taskRepo)
+	adminHandler := handlers.NewAdminHandler(taskScheduler)
 
 	// Start scheduler
 	if err := taskScheduler.Start(); err != nil {
@@ -91,6 +259,7 @@ func main() {
 	go func() {
 		<-c
 		log.Println("Shutting down gracefully...")
+		shutdown()
 		taskScheduler.Stop()
 		os.Exit(0)
 	}()
@@ -105,34 +274,36 @@ func main() {
 	r.Use(middleware.CORS())
 	r.Use(gin.Recovery())
 
-	// Health check endpoint
-	r.GET("/health", func(c *gin.Context) {
+	// Health check endpoint; the scheduler component reports the
+	// maintenance mode when it is not running normally
+	healthCheck := func(c *gin.Context) {
+		schedulerHealth := "healthy"
+		mode := taskScheduler.Mode()
+		if mode != models.SchedulerRunning {
+			schedulerHealth = string(mode)
+		}
 		c.JSON(200, gin.H{
 			"status":    "healthy",
 			"timestamp": time.Now().Format(time.RFC3339),
 			"version":   "1.0.0",
+			"mode":      mode,
+			"role":      taskScheduler.Role(),
 			"components": gin.H{
 				"database":  "healthy",
-				"scheduler": "healthy",
+				"scheduler": schedulerHealth,
 			},
 		})
-	})
+	}
+	r.GET("/health", healthCheck)
+
+	// Inbound webhook deliveries; the token in the path is the credential
+	r.POST("/hooks/:token", webhookHandler.ReceiveWebhook)
 
 	// API routes
 	api := r.Group("/api/v1")
 	{
 		// Health check endpoint in API namespace too
-		api.GET("/health", func(c *gin.Context) {
-			c.JSON(200, gin.H{
-				"status":    "healthy",
-				"timestamp": time.Now().Format(time.RFC3339),
-				"version":   "1.0.0",
-				"components": gin.H{
-					"database":  "healthy",
-					"scheduler": "healthy",
-				},
-			})
-		})
+		api.GET("/health", healthCheck)
 
 		// Task routes
 		api.POST("/tasks", taskHandler.CreateTask)
@@ -141,6 +312,7 @@ func main() {
 		api.PUT("/tasks/:id", taskHandler.UpdateTask)
 		api.DELETE("/tasks/:id", taskHandler.DeleteTask)
 		api.GET("/tasks/:id/results", taskHandler.GetTaskResults)
+		api.GET("/tasks/:id/schedule", taskHandler.PreviewTaskSchedule)
 
 		// Task control routes
 		api.POST("/tasks/:id/execute", taskHandler.ExecuteTask)
@@ -149,9 +321,43 @@ func main() {
 
 		// Result routes
 		api.GET("/results", resultHandler.GetResults)
+		api.GET("/results/:id/body", resultHandler.GetResultBody)
 
 		// Metrics routes
 		api.GET("/metrics", metricsHandler.GetMetrics)
+
+		// Circuit breaker routes
+		api.GET("/circuits", circuitHandler.GetCircuits)
+
+		// Workflow routes
+		api.POST("/workflows", workflowHandler.CreateWorkflow)
+		api.GET("/workflows", workflowHandler.GetWorkflows)
+		api.GET("/workflows/:id", workflowHandler.GetWorkflow)
+		api.DELETE("/workflows/:id", workflowHandler.DeleteWorkflow)
+		api.POST("/workflows/:id/run", workflowHandler.RunWorkflow)
+		api.GET("/workflows/:id/runs", workflowHandler.GetWorkflowRuns)
+		api.GET("/workflows/:id/runs/:run_id", workflowHandler.GetWorkflowRun)
+
+		// Calendar routes
+		api.POST("/calendars", calendarHandler.CreateCalendar)
+		api.GET("/calendars", calendarHandler.GetCalendars)
+		api.GET("/calendars/:id", calendarHandler.GetCalendar)
+		api.PUT("/calendars/:id", calendarHandler.UpdateCalendar)
+		api.DELETE("/calendars/:id", calendarHandler.DeleteCalendar)
+		api.POST("/calendars/:id/import", calendarHandler.ImportCalendar)
+		api.GET("/calendars/:id/check", calendarHandler.CheckCalendar)
+
+		// Admin routes can stop every run, so they are only mounted behind
+		// the bearer token in ADMIN_TOKEN
+		if adminToken := os.Getenv("ADMIN_TOKEN"); adminToken != "" {
+			admin := api.Group("/admin", middleware.AdminToken(adminToken))
+			admin.GET("/scheduler", adminHandler.GetSchedulerMode)
+			admin.POST("/scheduler/pause", adminHandler.PauseScheduler)
+			admin.POST("/scheduler/drain", adminHandler.DrainScheduler)
+			admin.POST("/scheduler/resume", adminHandler.ResumeScheduler)
+		} else {
+			log.Println("WARNING: ADMIN_TOKEN is not set; the /admin endpoints are disabled")
+		}
 	}
 
 	// Swagger documentation
@@ -167,3 +373,10 @@ func main() {
 		log.Fatal("Failed to start server:", err)
 	}
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,116 @@
+package blobstore
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ErrNotFound is returned by Open when no blob has the requested digest
+var ErrNotFound = errors.New("blob not found")
+
+// Store is a content-addressed blob store on local disk. Blobs are keyed by
+// the hex SHA-256 of their uncompressed content and stored gzip-compressed
+// under a two-character fan-out directory.
+type Store struct {
+	dir string
+}
+
+func New(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create blob directory: %w", err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Put streams r into the store and returns its digest and uncompressed size.
+// Writing the same content twice stores it once.
+func (s *Store) Put(r io.Reader) (digest string, size int64, err error) {
+	tmp, err := os.CreateTemp(s.dir, "upload-*")
+	if err != nil {
+		return "", 0, err
+	}
+	defer func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}()
+
+	hash := sha256.New()
+	gz := gzip.NewWriter(tmp)
+
+	size, err = io.Copy(io.MultiWriter(gz, hash), r)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := gz.Close(); err != nil {
+		return "", 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", 0, err
+	}
+
+	digest = hex.EncodeToString(hash.Sum(nil))
+	target := s.path(digest)
+	if _, err := os.Stat(target); err == nil {
+		return digest, size, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return "", 0, err
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return "", 0, err
+	}
+
+	return digest, size, nil
+}
+
+// Open returns a reader over the uncompressed content of a blob
+func (s *Store) Open(digest string) (io.ReadCloser, error) {
+	if !validDigest(digest) {
+		return nil, ErrNotFound
+	}
+
+	file, err := os.Open(s.path(digest))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &blobReader{Reader: gz, file: file}, nil
+}
+
+func (s *Store) path(digest string) string {
+	return filepath.Join(s.dir, digest[:2], digest+".gz")
+}
+
+func validDigest(digest string) bool {
+	if len(digest) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(digest)
+	return err == nil
+}
+
+type blobReader struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (r *blobReader) Close() error {
+	r.Reader.Close()
+	return r.file.Close()
+}
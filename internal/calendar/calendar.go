@@ -0,0 +1,161 @@
+// Package calendar decides whether a time falls inside one of a calendar's
+// blackouts and imports blackout dates from iCalendar files.
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"task-scheduler/internal/models"
+)
+
+const dateLayout = "2006-01-02"
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Blackout is the blackout a time fell in
+type Blackout struct {
+	Reason string
+	// Until is the first moment after the blackout, and after any
+	// blackouts directly following it
+	Until time.Time
+}
+
+// Validate checks a calendar's time zone, windows, dates and periods
+func Validate(cal *models.Calendar) error {
+	if _, err := location(cal); err != nil {
+		return err
+	}
+	for _, window := range cal.Windows {
+		if _, _, err := windowMinutes(window); err != nil {
+			return err
+		}
+		for _, day := range window.Days {
+			if _, ok := weekdays[strings.ToLower(day)]; !ok {
+				return fmt.Errorf("window %q: unknown day %q", window.Name, day)
+			}
+		}
+	}
+	for _, date := range cal.Dates {
+		if _, err := time.Parse(dateLayout, date.Date); err != nil {
+			return fmt.Errorf("invalid date %q, expected YYYY-MM-DD", date.Date)
+		}
+	}
+	for _, period := range cal.Periods {
+		if !period.End.After(period.Start) {
+			return fmt.Errorf("period %q must end after it starts", period.Name)
+		}
+	}
+	return nil
+}
+
+// Check reports whether t falls inside a blackout on cal
+func Check(cal *models.Calendar, t time.Time) (*Blackout, bool) {
+	loc, err := location(cal)
+	if err != nil {
+		return nil, false
+	}
+
+	reason, until, blocked := find(cal, t.In(loc), loc)
+	if !blocked {
+		return nil, false
+	}
+
+	// Follow back-to-back blackouts so a deferred run does not land in
+	// the next one; the bound guards against a calendar that is never clear
+	for i := 0; i < 366; i++ {
+		_, next, more := find(cal, until, loc)
+		if !more {
+			break
+		}
+		until = next
+	}
+	return &Blackout{Reason: reason, Until: until}, true
+}
+
+func find(cal *models.Calendar, t time.Time, loc *time.Location) (string, time.Time, bool) {
+	day := t.Format(dateLayout)
+	for _, date := range cal.Dates {
+		if date.Date == day {
+			midnight := time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			return describe("date", date.Name, date.Date), midnight, true
+		}
+	}
+
+	for _, period := range cal.Periods {
+		if !t.Before(period.Start) && t.Before(period.End) {
+			return describe("period", period.Name, period.Start.Format(time.RFC3339)), period.End.In(loc), true
+		}
+	}
+
+	for _, window := range cal.Windows {
+		start, end, err := windowMinutes(window)
+		if err != nil {
+			continue
+		}
+		// A window that runs past midnight may have started yesterday
+		for _, offset := range []int{0, -1} {
+			base := time.Date(t.Year(), t.Month(), t.Day()+offset, 0, 0, 0, 0, loc)
+			if !onDay(window, base.Weekday()) {
+				continue
+			}
+			from := base.Add(time.Duration(start) * time.Minute)
+			to := base.Add(time.Duration(end) * time.Minute)
+			if end <= start {
+				to = to.AddDate(0, 0, 1)
+			}
+			if !t.Before(from) && t.Before(to) {
+				return describe("window", window.Name, window.Start+"-"+window.End), to, true
+			}
+		}
+	}
+
+	return "", time.Time{}, false
+}
+
+func describe(kind, name, detail string) string {
+	if name == "" {
+		return fmt.Sprintf("blackout %s %s", kind, detail)
+	}
+	return fmt.Sprintf("blackout %s %q (%s)", kind, name, detail)
+}
+
+func onDay(window models.BlackoutWindow, weekday time.Weekday) bool {
+	if len(window.Days) == 0 {
+		return true
+	}
+	for _, day := range window.Days {
+		if weekdays[strings.ToLower(day)] == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// windowMinutes returns a window's start and end as minutes past midnight
+func windowMinutes(window models.BlackoutWindow) (int, int, error) {
+	start, err := time.Parse("15:04", window.Start)
+	if err != nil {
+		return 0, 0, fmt.Errorf("window %q: start must look like 15:04", window.Name)
+	}
+	end, err := time.Parse("15:04", window.End)
+	if err != nil {
+		return 0, 0, fmt.Errorf("window %q: end must look like 15:04", window.Name)
+	}
+	return start.Hour()*60 + start.Minute(), end.Hour()*60 + end.Minute(), nil
+}
+
+func location(cal *models.Calendar) (*time.Location, error) {
+	if cal.Timezone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(cal.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", cal.Timezone)
+	}
+	return loc, nil
+}
@@ -0,0 +1,172 @@
+package calendar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"task-scheduler/internal/models"
+)
+
+// Import is what ParseICS read from an iCalendar file
+type Import struct {
+	Dates   []models.CalendarDate
+	Periods []models.BlackoutPeriod
+	// Skipped counts events that could not be turned into blackouts,
+	// such as recurring (RRULE) events
+	Skipped int
+}
+
+// ParseICS reads the VEVENTs in an iCalendar file. All-day events become
+// dates, one per day they cover; timed events become periods.
+func ParseICS(r io.Reader) (*Import, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Import{}
+	var event map[string]property
+	sawCalendar := false
+
+	for _, line := range lines {
+		switch {
+		case strings.EqualFold(line, "BEGIN:VCALENDAR"):
+			sawCalendar = true
+		case strings.EqualFold(line, "BEGIN:VEVENT"):
+			event = make(map[string]property)
+		case strings.EqualFold(line, "END:VEVENT"):
+			if event != nil {
+				addEvent(result, event)
+			}
+			event = nil
+		case event != nil:
+			prop, ok := parseProperty(line)
+			if ok {
+				event[prop.name] = prop
+			}
+		}
+	}
+
+	if !sawCalendar {
+		return nil, fmt.Errorf("not an iCalendar file: missing BEGIN:VCALENDAR")
+	}
+	return result, nil
+}
+
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// unfold joins continuation lines, which start with a space or tab
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+func parseProperty(line string) (property, bool) {
+	head, value, ok := strings.Cut(line, ":")
+	if !ok {
+		return property{}, false
+	}
+	parts := strings.Split(head, ";")
+	prop := property{
+		name:   strings.ToUpper(parts[0]),
+		params: make(map[string]string),
+		value:  value,
+	}
+	for _, param := range parts[1:] {
+		key, val, _ := strings.Cut(param, "=")
+		prop.params[strings.ToUpper(key)] = strings.Trim(val, `"`)
+	}
+	return prop, true
+}
+
+func addEvent(result *Import, event map[string]property) {
+	start, ok := event["DTSTART"]
+	if !ok {
+		result.Skipped++
+		return
+	}
+	if _, recurring := event["RRULE"]; recurring {
+		result.Skipped++
+		return
+	}
+	name := unescape(event["SUMMARY"].value)
+
+	if isDate(start) {
+		first, err := time.Parse("20060102", start.value)
+		if err != nil {
+			result.Skipped++
+			return
+		}
+		// DTEND is exclusive; without it the event covers one day
+		last := first
+		if end, ok := event["DTEND"]; ok {
+			if day, err := time.Parse("20060102", end.value); err == nil && day.After(first) {
+				last = day.AddDate(0, 0, -1)
+			}
+		}
+		for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+			result.Dates = append(result.Dates, models.CalendarDate{Date: day.Format(dateLayout), Name: name})
+		}
+		return
+	}
+
+	end, ok := event["DTEND"]
+	if !ok {
+		result.Skipped++
+		return
+	}
+	from, err := parseDateTime(start)
+	if err != nil {
+		result.Skipped++
+		return
+	}
+	to, err := parseDateTime(end)
+	if err != nil || !to.After(from) {
+		result.Skipped++
+		return
+	}
+	result.Periods = append(result.Periods, models.BlackoutPeriod{Name: name, Start: from, End: to})
+}
+
+func isDate(prop property) bool {
+	return strings.EqualFold(prop.params["VALUE"], "DATE") || len(prop.value) == len("20060102")
+}
+
+// parseDateTime reads a UTC ("Z"), TZID-qualified or floating date-time;
+// floating times are read as UTC
+func parseDateTime(prop property) (time.Time, error) {
+	if strings.HasSuffix(prop.value, "Z") {
+		return time.Parse("20060102T150405Z", prop.value)
+	}
+	loc := time.UTC
+	if tzid := prop.params["TZID"]; tzid != "" {
+		var err error
+		if loc, err = time.LoadLocation(tzid); err != nil {
+			return time.Time{}, fmt.Errorf("unknown TZID %q", tzid)
+		}
+	}
+	return time.ParseInLocation("20060102T150405", prop.value, loc)
+}
+
+func unescape(value string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
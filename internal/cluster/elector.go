@@ -0,0 +1,140 @@
+// Package cluster elects one leader among scheduler instances sharing a
+// database, using a lease the leader keeps renewing.
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// leaseName is the lease the scheduler instances compete for
+const leaseName = "scheduler"
+
+// InstanceLeasePrefix starts the name of the lease each instance renews for
+// itself, followed by its ID. An instance whose lease has run out is gone,
+// so work it owned can be cleaned up by the others.
+const InstanceLeasePrefix = "instance:"
+
+// DefaultLeaseTimeout is used when no positive lease timeout is given
+const DefaultLeaseTimeout = 15 * time.Second
+
+// LeaseStore keeps the shared lease
+type LeaseStore interface {
+	TryAcquire(name, holder string, ttl time.Duration) (bool, error)
+	Release(name, holder string) error
+}
+
+// Elector competes for the lease on behalf of one instance. The leader
+// renews it every quarter of the lease timeout and followers try to take
+// it just as often, so a dead leader is replaced within about 1.25 lease
+// timeouts.
+type Elector struct {
+	store LeaseStore
+	id    string
+	ttl   time.Duration
+
+	mu        sync.RWMutex
+	leader    bool
+	renewedAt time.Time
+}
+
+func NewElector(store LeaseStore, id string, ttl time.Duration) *Elector {
+	if ttl <= 0 {
+		ttl = DefaultLeaseTimeout
+	}
+	return &Elector{store: store, id: id, ttl: ttl}
+}
+
+// InstanceID names this process in the lease table
+func InstanceID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.NewString()[:8])
+}
+
+func (e *Elector) ID() string {
+	return e.id
+}
+
+func (e *Elector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.leader
+}
+
+// Run competes for the lease until ctx is done, calling onElected when
+// this instance becomes leader and onDemoted when it stops being leader.
+// It renews the instance's own lease as it goes. A leader gives the
+// scheduler lease up on the way out, and every instance gives up its own.
+func (e *Elector) Run(ctx context.Context, onElected, onDemoted func()) {
+	ticker := time.NewTicker(e.ttl / 4)
+	defer ticker.Stop()
+
+	for {
+		e.tick(onElected, onDemoted)
+
+		select {
+		case <-ctx.Done():
+			if e.IsLeader() {
+				e.setLeader(false)
+				onDemoted()
+				if err := e.store.Release(leaseName, e.id); err != nil {
+					log.Printf("Failed to release scheduler lease: %v", err)
+				}
+			}
+			if err := e.store.Release(InstanceLeasePrefix+e.id, e.id); err != nil {
+				log.Printf("Failed to release instance lease: %v", err)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *Elector) tick(onElected, onDemoted func()) {
+	if _, err := e.store.TryAcquire(InstanceLeasePrefix+e.id, e.id, e.ttl); err != nil {
+		log.Printf("Failed to renew instance lease: %v", err)
+	}
+
+	acquired, err := e.store.TryAcquire(leaseName, e.id, e.ttl)
+	now := time.Now()
+
+	wasLeader := e.IsLeader()
+	if err != nil {
+		log.Printf("Failed to renew scheduler lease: %v", err)
+		// The lease in the database is still ours until it runs out
+		e.mu.RLock()
+		stillValid := wasLeader && now.Sub(e.renewedAt) < e.ttl
+		e.mu.RUnlock()
+		acquired = stillValid
+	} else if acquired {
+		e.mu.Lock()
+		e.renewedAt = now
+		e.mu.Unlock()
+	}
+
+	switch {
+	case acquired && !wasLeader:
+		log.Printf("Instance %s is now the scheduler leader", e.id)
+		e.setLeader(true)
+		onElected()
+	case !acquired && wasLeader:
+		log.Printf("Instance %s lost the scheduler lease", e.id)
+		e.setLeader(false)
+		onDemoted()
+	}
+}
+
+func (e *Elector) setLeader(leader bool) {
+	e.mu.Lock()
+	e.leader = leader
+	e.mu.Unlock()
+}
@@ -0,0 +1,138 @@
+// Package coalesce folds bursts of task triggers into fewer runs according
+// to each task's debounce or throttle policy.
+//
+// Pending runs live in the memory of one process. With several replicas,
+// triggers are only folded with others that reach the same instance, so a
+// burst of webhook deliveries spread across replicas runs once per replica,
+// and a pending run is lost if its instance stops.
+package coalesce
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"task-scheduler/internal/models"
+)
+
+// Coalescer tracks the pending run of every task with a coalesce policy
+type Coalescer struct {
+	mu      sync.Mutex
+	entries map[uuid.UUID]*entry
+}
+
+type entry struct {
+	throttle time.Duration
+	lastRun  time.Time
+
+	// A pending run fires from timer; generation tells a stale timer,
+	// replaced by a later trigger, from the current one
+	pending    bool
+	timer      *time.Timer
+	generation int
+	fire       func(coalesced int)
+	coalesced  int
+}
+
+func New() *Coalescer {
+	return &Coalescer{entries: make(map[uuid.UUID]*entry)}
+}
+
+// Trigger calls fire now, later, or not at all when the trigger is folded
+// into a run that is already pending. fire gets the number of other
+// triggers folded into its run. A later trigger replaces the pending run's
+// fire, so the run goes ahead with the most recent trigger.
+func (c *Coalescer) Trigger(taskID uuid.UUID, policy *models.CoalescePolicy, fire func(coalesced int)) {
+	debounce, throttle := policy.Debounce(), policy.Throttle()
+	if debounce <= 0 && throttle <= 0 {
+		fire(0)
+		return
+	}
+
+	c.mu.Lock()
+	e := c.entries[taskID]
+	if e == nil {
+		e = &entry{}
+		c.entries[taskID] = e
+	}
+	e.throttle = throttle
+
+	if e.pending {
+		e.coalesced++
+		e.fire = fire
+		if debounce > 0 {
+			c.arm(taskID, e, debounce)
+		}
+		c.mu.Unlock()
+		return
+	}
+
+	if debounce > 0 {
+		c.start(taskID, e, fire, debounce)
+		c.mu.Unlock()
+		return
+	}
+
+	wait := time.Until(e.lastRun.Add(throttle))
+	if wait > 0 {
+		c.start(taskID, e, fire, wait)
+		c.mu.Unlock()
+		return
+	}
+	e.lastRun = time.Now()
+	c.mu.Unlock()
+	fire(0)
+}
+
+// Stop drops every pending run
+func (c *Coalescer) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, e := range c.entries {
+		if e.timer != nil {
+			e.timer.Stop()
+		}
+	}
+	c.entries = make(map[uuid.UUID]*entry)
+}
+
+func (c *Coalescer) start(taskID uuid.UUID, e *entry, fire func(int), wait time.Duration) {
+	e.pending = true
+	e.fire = fire
+	e.coalesced = 0
+	c.arm(taskID, e, wait)
+}
+
+// arm (re)starts the pending run's timer
+func (c *Coalescer) arm(taskID uuid.UUID, e *entry, wait time.Duration) {
+	if e.timer != nil {
+		e.timer.Stop()
+	}
+	e.generation++
+	generation := e.generation
+	e.timer = time.AfterFunc(wait, func() { c.flush(taskID, generation) })
+}
+
+func (c *Coalescer) flush(taskID uuid.UUID, generation int) {
+	c.mu.Lock()
+	e := c.entries[taskID]
+	if e == nil || !e.pending || e.generation != generation {
+		c.mu.Unlock()
+		return
+	}
+
+	fire, coalesced := e.fire, e.coalesced
+	e.pending = false
+	e.fire = nil
+	e.timer = nil
+	e.lastRun = time.Now()
+	if e.throttle <= 0 {
+		// Only throttles need to remember when the task last ran
+		delete(c.entries, taskID)
+	}
+	c.mu.Unlock()
+
+	fire(coalesced)
+}
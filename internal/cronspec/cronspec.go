@@ -0,0 +1,199 @@
+// Package cronspec parses the scheduler's cron expressions: standard
+// 5-field expressions plus a Jenkins-style H token, and per-task jitter.
+//
+// H stands for a value hashed from the task ID, so tasks sharing an
+// expression such as "H * * * *" spread across the hour while each task
+// keeps a stable minute. It may be used as H, H(a-b) for a hashed value in
+// a range, or H/n and H(a-b)/n for a step with a hashed offset. In the
+// day-of-month field a bare H stays within 1-28 so it exists in every month.
+package cronspec
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+)
+
+// MaxJitter bounds the per-task jitter
+const MaxJitter = time.Hour
+
+type field struct {
+	name     string
+	min, max int
+	// hashMax is the top of a bare H's range, when below max
+	hashMax int
+}
+
+var fields = []field{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31, hashMax: 28},
+	{name: "month", min: 1, max: 12},
+	{name: "day of week", min: 0, max: 6},
+}
+
+// Expand replaces the H tokens in expr with values hashed from id.
+// Descriptors such as @hourly, and expressions without an H item, are
+// returned unchanged. A leading CRON_TZ= or TZ= zone is kept as it is.
+func Expand(expr string, id uuid.UUID) (string, error) {
+	expr = strings.TrimSpace(expr)
+
+	zone, spec := "", expr
+	if strings.HasPrefix(expr, "CRON_TZ=") || strings.HasPrefix(expr, "TZ=") {
+		zone, spec, _ = strings.Cut(expr, " ")
+		spec = strings.TrimSpace(spec)
+	}
+	if strings.HasPrefix(spec, "@") || !hasHashItem(spec) {
+		return expr, nil
+	}
+
+	parts := strings.Fields(spec)
+	if len(parts) != len(fields) {
+		return "", fmt.Errorf("expected %d fields, found %d: %s", len(fields), len(parts), spec)
+	}
+
+	for i, part := range parts {
+		items := strings.Split(part, ",")
+		for j, item := range items {
+			if !strings.HasPrefix(item, "H") {
+				continue
+			}
+			expanded, err := expandHash(item, fields[i], hash(id, i))
+			if err != nil {
+				return "", err
+			}
+			items[j] = expanded
+		}
+		parts[i] = strings.Join(items, ",")
+	}
+	if zone != "" {
+		parts = append([]string{zone}, parts...)
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// hasHashItem reports whether any item of any field of spec is an H token
+func hasHashItem(spec string) bool {
+	for _, part := range strings.Fields(spec) {
+		for _, item := range strings.Split(part, ",") {
+			if strings.HasPrefix(item, "H") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// expandHash turns one H, H(a-b), H/n or H(a-b)/n item into plain cron
+func expandHash(item string, f field, h uint64) (string, error) {
+	lo, hi := f.min, f.max
+	if f.hashMax > 0 {
+		hi = f.hashMax
+	}
+
+	rest := item[1:]
+	if strings.HasPrefix(rest, "(") {
+		end := strings.Index(rest, ")")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated range in %q", item)
+		}
+		from, to, ok := strings.Cut(rest[1:end], "-")
+		a, errA := strconv.Atoi(from)
+		b, errB := strconv.Atoi(to)
+		if !ok || errA != nil || errB != nil || a > b || a < f.min || b > f.max {
+			return "", fmt.Errorf("invalid %s range in %q", f.name, item)
+		}
+		lo, hi = a, b
+		rest = rest[end+1:]
+	}
+
+	if rest == "" {
+		return strconv.Itoa(lo + int(h%uint64(hi-lo+1))), nil
+	}
+
+	if !strings.HasPrefix(rest, "/") {
+		return "", fmt.Errorf("invalid H expression %q", item)
+	}
+	step, err := strconv.Atoi(rest[1:])
+	if err != nil || step < 1 || step > hi-lo+1 {
+		return "", fmt.Errorf("invalid %s step in %q", f.name, item)
+	}
+	start := lo + int(h%uint64(step))
+	return fmt.Sprintf("%d-%d/%d", start, hi, step), nil
+}
+
+// hash gives each task a stable value per field, so H in the minute and
+// hour fields do not always land on the same number
+func hash(id uuid.UUID, fieldIndex int) uint64 {
+	hasher := fnv.New64a()
+	hasher.Write(id[:])
+	hasher.Write([]byte{byte(fieldIndex)})
+	return hasher.Sum64()
+}
+
+// Parse expands expr for id and parses it, applying jitter
+func Parse(expr string, id uuid.UUID, jitter time.Duration) (cron.Schedule, error) {
+	expanded, err := Expand(expr, id)
+	if err != nil {
+		return nil, err
+	}
+	schedule, err := cron.ParseStandard(expanded)
+	if err != nil {
+		return nil, err
+	}
+	if offset := Jitter(id, jitter); offset > 0 {
+		return jittered{schedule: schedule, offset: offset}, nil
+	}
+	return schedule, nil
+}
+
+// Validate checks expr the way Parse would for any task
+func Validate(expr string) error {
+	_, err := Parse(expr, uuid.Nil, 0)
+	return err
+}
+
+// Jitter returns a task's delay in [0, max), derived from its ID so it is
+// the same on every fire and across restarts
+func Jitter(id uuid.UUID, max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	seconds := int64(max / time.Second)
+	if seconds <= 0 {
+		return 0
+	}
+	return time.Duration(hash(id, len(fields))%uint64(seconds)) * time.Second
+}
+
+// Preview returns the next count fire times after from
+func Preview(schedule cron.Schedule, from time.Time, count int) []time.Time {
+	times := make([]time.Time, 0, count)
+	for i := 0; i < count; i++ {
+		from = schedule.Next(from)
+		if from.IsZero() {
+			break
+		}
+		times = append(times, from)
+	}
+	return times
+}
+
+// jittered shifts every fire of schedule by offset
+type jittered struct {
+	schedule cron.Schedule
+	offset   time.Duration
+}
+
+func (j jittered) Next(t time.Time) time.Time {
+	next := j.schedule.Next(t.Add(-j.offset))
+	if next.IsZero() {
+		return next
+	}
+	return next.Add(j.offset)
+}
@@ -60,7 +60,17 @@ func Connect() {
 }
 
 func Migrate() {
-	err := DB.AutoMigrate(&models.Task{}, &models.TaskResult{})
+	err := DB.AutoMigrate(&models.Task{}, &models.TaskResult{}, &models.TaskCookieJar{},
+		&models.Workflow{}, &models.WorkflowRun{}, &models.WebhookDelivery{}, &models.Calendar{},
+		&models.SchedulerState{}, &models.QueuedFire{}, &models.SchedulerLease{}, &models.TaskRun{},
+		&models.TaskOccurrence{})
+	if err != nil {
+		log.Fatal("Failed to migrate database:", err)
+	}
+
+	// Resume used to set active, which the scheduler never ran
+	err = DB.Model(&models.Task{}).Where("status = ?", models.TaskStatusActive).
+		Update("status", models.TaskStatusScheduled).Error
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
@@ -0,0 +1,295 @@
+package executor
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"task-scheduler/internal/models"
+)
+
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"
+	CircuitOpen     CircuitState = "open"
+	CircuitHalfOpen CircuitState = "half_open"
+)
+
+// CircuitBreakerConfig controls when a destination's circuit trips. The
+// failure rate is computed over the last WindowSize runs once at least
+// MinRequests have been seen.
+type CircuitBreakerConfig struct {
+	WindowSize           int
+	MinRequests          int
+	FailureRateThreshold float64
+	CoolDown             time.Duration
+	HalfOpenProbes       int
+}
+
+// DefaultCircuitBreakerConfig trips at 50% failures over the last 20 runs
+// and probes again after 30 seconds
+func DefaultCircuitBreakerConfig() CircuitBreakerConfig {
+	return CircuitBreakerConfig{
+		WindowSize:           20,
+		MinRequests:          5,
+		FailureRateThreshold: 0.5,
+		CoolDown:             30 * time.Second,
+		HalfOpenProbes:       1,
+	}
+}
+
+// CircuitStatus is a point-in-time view of one destination's breaker
+type CircuitStatus struct {
+	Host        string       `json:"host"`
+	State       CircuitState `json:"state"`
+	Requests    int          `json:"requests"`
+	Failures    int          `json:"failures"`
+	FailureRate float64      `json:"failure_rate"`
+	OpenedAt    *time.Time   `json:"opened_at,omitempty"`
+	RetryAt     *time.Time   `json:"retry_at,omitempty"`
+}
+
+// CircuitBreakers keeps one breaker per destination host
+type CircuitBreakers struct {
+	config CircuitBreakerConfig
+	now    func() time.Time
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+type breaker struct {
+	state    CircuitState
+	outcomes []bool
+	next     int
+	openedAt time.Time
+	probes   int
+	passed   int
+}
+
+func NewCircuitBreakers(config CircuitBreakerConfig) *CircuitBreakers {
+	defaults := DefaultCircuitBreakerConfig()
+	if config.WindowSize <= 0 {
+		config.WindowSize = defaults.WindowSize
+	}
+	if config.MinRequests <= 0 {
+		config.MinRequests = defaults.MinRequests
+	}
+	if config.FailureRateThreshold <= 0 || config.FailureRateThreshold > 1 {
+		config.FailureRateThreshold = defaults.FailureRateThreshold
+	}
+	if config.CoolDown <= 0 {
+		config.CoolDown = defaults.CoolDown
+	}
+	if config.HalfOpenProbes <= 0 {
+		config.HalfOpenProbes = defaults.HalfOpenProbes
+	}
+
+	return &CircuitBreakers{
+		config:   config,
+		now:      time.Now,
+		breakers: make(map[string]*breaker),
+	}
+}
+
+// Allow reports whether a run against host may proceed. Once the cool-down
+// has elapsed an open circuit moves to half-open and admits a limited number
+// of probe runs.
+func (c *CircuitBreakers) Allow(host string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b := c.breakerFor(host)
+	switch b.state {
+	case CircuitOpen:
+		if c.now().Sub(b.openedAt) < c.config.CoolDown {
+			return false
+		}
+		b.state = CircuitHalfOpen
+		b.probes = 0
+		b.passed = 0
+		log.Printf("Circuit for %s is half-open", host)
+		fallthrough
+	case CircuitHalfOpen:
+		if b.probes >= c.config.HalfOpenProbes {
+			return false
+		}
+		b.probes++
+	}
+	return true
+}
+
+// Record feeds the outcome of an allowed run back into host's breaker
+func (c *CircuitBreakers) Record(host string, success bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b := c.breakerFor(host)
+	if b.state == CircuitHalfOpen {
+		if !success {
+			c.trip(host, b)
+			return
+		}
+		b.passed++
+		if b.passed >= c.config.HalfOpenProbes {
+			log.Printf("Circuit for %s closed", host)
+			b.state = CircuitClosed
+			b.outcomes = b.outcomes[:0]
+			b.next = 0
+		}
+		return
+	}
+
+	if len(b.outcomes) < c.config.WindowSize {
+		b.outcomes = append(b.outcomes, success)
+	} else {
+		b.outcomes[b.next] = success
+		b.next = (b.next + 1) % c.config.WindowSize
+	}
+
+	requests, failures := b.counts()
+	if b.state == CircuitClosed && requests >= c.config.MinRequests &&
+		float64(failures)/float64(requests) >= c.config.FailureRateThreshold {
+		c.trip(host, b)
+	}
+}
+
+// Release returns a probe slot taken by Allow for a run that never reached
+// the destination
+func (c *CircuitBreakers) Release(host string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if b := c.breakerFor(host); b.state == CircuitHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+// Snapshot returns the state of every breaker, sorted by host
+func (c *CircuitBreakers) Snapshot() []CircuitStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	statuses := make([]CircuitStatus, 0, len(c.breakers))
+	for host, b := range c.breakers {
+		requests, failures := b.counts()
+		status := CircuitStatus{
+			Host:     host,
+			State:    b.state,
+			Requests: requests,
+			Failures: failures,
+		}
+		if requests > 0 {
+			status.FailureRate = float64(failures) / float64(requests)
+		}
+		if b.state != CircuitClosed {
+			openedAt := b.openedAt
+			retryAt := b.openedAt.Add(c.config.CoolDown)
+			status.OpenedAt = &openedAt
+			status.RetryAt = &retryAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Host < statuses[j].Host
+	})
+	return statuses
+}
+
+func (c *CircuitBreakers) breakerFor(host string) *breaker {
+	b, exists := c.breakers[host]
+	if !exists {
+		b = &breaker{state: CircuitClosed}
+		c.breakers[host] = b
+	}
+	return b
+}
+
+func (c *CircuitBreakers) trip(host string, b *breaker) {
+	log.Printf("Circuit for %s opened", host)
+	b.state = CircuitOpen
+	b.openedAt = c.now()
+}
+
+func (b *breaker) counts() (requests, failures int) {
+	for _, success := range b.outcomes {
+		if !success {
+			failures++
+		}
+	}
+	return len(b.outcomes), failures
+}
+
+// CircuitBreakerExecutor short-circuits runs against destinations whose
+// circuit is open instead of performing the full retry loop
+type CircuitBreakerExecutor struct {
+	executor ExecutorInterface
+	breakers *CircuitBreakers
+}
+
+func NewCircuitBreakerExecutor(executor ExecutorInterface, breakers *CircuitBreakers) *CircuitBreakerExecutor {
+	return &CircuitBreakerExecutor{
+		executor: executor,
+		breakers: breakers,
+	}
+}
+
+func (c *CircuitBreakerExecutor) Execute(task *models.Task) *models.TaskResult {
+	return c.execute(task, func() *models.TaskResult {
+		return c.executor.Execute(task)
+	})
+}
+
+func (c *CircuitBreakerExecutor) ExecuteWithTimeout(task *models.Task, timeout time.Duration) *models.TaskResult {
+	return c.execute(task, func() *models.TaskResult {
+		return c.executor.ExecuteWithTimeout(task, timeout)
+	})
+}
+
+func (c *CircuitBreakerExecutor) execute(task *models.Task, run func() *models.TaskResult) *models.TaskResult {
+	host := destinationHost(task)
+	if host == "" {
+		return run()
+	}
+
+	if !c.breakers.Allow(host) {
+		now := time.Now()
+		return &models.TaskResult{
+			ID:           uuid.New(),
+			TaskID:       task.ID,
+			RunAt:        now,
+			Outcome:      models.OutcomeCircuitOpen,
+			ErrorMessage: stringPtr(fmt.Sprintf("circuit for %s is open", host)),
+			CreatedAt:    now,
+		}
+	}
+
+	result := run()
+	if result.NotAttempted() {
+		c.breakers.Release(host)
+		return result
+	}
+
+	c.breakers.Record(host, !isDestinationFailure(result))
+	return result
+}
+
+// isDestinationFailure treats transport errors, 5xx and 429 responses as a
+// sign the destination is unhealthy. Other 4xx responses and errors raised
+// before the request went out, such as template or egress policy errors,
+// are task problems.
+func isDestinationFailure(result *models.TaskResult) bool {
+	if result.Success {
+		return false
+	}
+	if result.StatusCode == nil {
+		return result.TransportFailed
+	}
+	return *result.StatusCode >= 500 || *result.StatusCode == 429
+}
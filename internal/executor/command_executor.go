@@ -0,0 +1,260 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"task-scheduler/internal/models"
+)
+
+const (
+	defaultCommandTimeout   = 5 * time.Minute
+	defaultCommandMaxOutput = 64 * 1024
+)
+
+// CommandExecutor runs command actions. Only binaries on the admin
+// allow-list may be started; entries are either absolute paths, matched
+// against the resolved executable, or bare names matched against argv[0].
+// A command may only run in an admin-listed directory, and may not set
+// variables that change which code the binary loads.
+type CommandExecutor struct {
+	allowed map[string]bool
+	dirs    []string
+}
+
+// CommandExecutorOption configures a CommandExecutor
+type CommandExecutorOption func(*CommandExecutor)
+
+// WithCommandDirs lists the directories, and everything below them, that
+// commands may run in. Without it commands may not set dir.
+func WithCommandDirs(dirs []string) CommandExecutorOption {
+	return func(e *CommandExecutor) {
+		for _, dir := range dirs {
+			if dir = strings.TrimSpace(dir); dir == "" {
+				continue
+			}
+			dir = filepath.Clean(dir)
+			if real, err := filepath.EvalSymlinks(dir); err == nil {
+				dir = real
+			}
+			e.dirs = append(e.dirs, dir)
+		}
+	}
+}
+
+func NewCommandExecutor(allowList []string, opts ...CommandExecutorOption) *CommandExecutor {
+	allowed := make(map[string]bool, len(allowList))
+	for _, entry := range allowList {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			allowed[entry] = true
+		}
+	}
+	e := &CommandExecutor{allowed: allowed}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// ValidateTask checks a command action's configuration and allow-listing
+func (e *CommandExecutor) ValidateTask(task *models.Task) error {
+	if task.ActionType != models.ActionTypeCommand {
+		return nil
+	}
+
+	action, err := decodeCommandAction(task)
+	if err != nil {
+		return err
+	}
+	if action.TimeoutSeconds < 0 || action.MaxOutputBytes < 0 {
+		return fmt.Errorf("command timeout_seconds and max_output_bytes must not be negative")
+	}
+	if action.Dir != "" && !filepath.IsAbs(action.Dir) {
+		return fmt.Errorf("command dir must be an absolute path")
+	}
+	if err := e.checkDir(action.Dir); err != nil {
+		return err
+	}
+	if err := checkCommandEnv(action.Env); err != nil {
+		return err
+	}
+	_, err = e.resolve(action.Argv[0])
+	return err
+}
+
+func (e *CommandExecutor) Execute(task *models.Task) *models.TaskResult {
+	return e.ExecuteWithTimeout(task, 0)
+}
+
+// ExecuteWithTimeout runs the command, killing its whole process group if
+// the timeout (or the action's own timeout, when shorter) expires
+func (e *CommandExecutor) ExecuteWithTimeout(task *models.Task, timeout time.Duration) *models.TaskResult {
+	startTime := time.Now()
+
+	result := &models.TaskResult{
+		ID:        uuid.New(),
+		TaskID:    task.ID,
+		RunAt:     startTime,
+		CreatedAt: startTime,
+	}
+
+	action, err := decodeCommandAction(task)
+	if err != nil {
+		result.ErrorMessage = stringPtr(fmt.Sprintf("Failed to prepare command: %v", err))
+		return result
+	}
+
+	path, err := e.resolve(action.Argv[0])
+	if err == nil {
+		err = e.checkDir(action.Dir)
+	}
+	if err == nil {
+		err = checkCommandEnv(action.Env)
+	}
+	if err != nil {
+		result.ErrorMessage = stringPtr(fmt.Sprintf("Failed to prepare command: %v", err))
+		return result
+	}
+
+	if action.TimeoutSeconds > 0 {
+		actionTimeout := time.Duration(action.TimeoutSeconds) * time.Second
+		if timeout <= 0 || actionTimeout < timeout {
+			timeout = actionTimeout
+		}
+	}
+	if timeout <= 0 {
+		timeout = defaultCommandTimeout
+	}
+
+	maxOutput := action.MaxOutputBytes
+	if maxOutput <= 0 {
+		maxOutput = defaultCommandMaxOutput
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, path, action.Argv[1:]...)
+	cmd.Dir = action.Dir
+	cmd.Env = commandEnv(action.Env)
+	stdout := &headBuffer{limit: maxOutput}
+	stderr := &headBuffer{limit: maxOutput}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	killProcessGroup(cmd)
+
+	err = cmd.Run()
+	result.DurationMs = int(time.Since(startTime).Milliseconds())
+
+	stdoutStr, stderrStr := stdout.String(), stderr.String()
+	result.Stdout = &stdoutStr
+	result.Stderr = &stderrStr
+
+	if cmd.ProcessState != nil {
+		exitCode := cmd.ProcessState.ExitCode()
+		result.ExitCode = &exitCode
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		result.Success = true
+	case ctx.Err() == context.DeadlineExceeded:
+		result.ErrorMessage = stringPtr(fmt.Sprintf("Command timed out after %s", timeout))
+	case errors.As(err, &exitErr):
+		result.ErrorMessage = stringPtr(fmt.Sprintf("Command exited with code %d", exitErr.ExitCode()))
+	default:
+		result.ErrorMessage = stringPtr(fmt.Sprintf("Command failed: %v", err))
+	}
+
+	return result
+}
+
+// resolve finds the executable for name and checks it against the allow-list
+func (e *CommandExecutor) resolve(name string) (string, error) {
+	if len(e.allowed) == 0 {
+		return "", fmt.Errorf("command actions are disabled")
+	}
+
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return "", fmt.Errorf("command %q not found", name)
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		if e.allowed[resolved] {
+			return path, nil
+		}
+	}
+
+	if e.allowed[path] || (!strings.ContainsRune(name, filepath.Separator) && e.allowed[name]) {
+		return path, nil
+	}
+	return "", fmt.Errorf("command %q is not on the allow-list", name)
+}
+
+// checkDir checks a working directory against the admin list. Symlinks are
+// resolved first so a link inside a listed directory cannot lead out of it.
+func (e *CommandExecutor) checkDir(dir string) error {
+	if dir == "" {
+		return nil
+	}
+	if len(e.dirs) == 0 {
+		return fmt.Errorf("command dir is not allowed")
+	}
+
+	resolved := filepath.Clean(dir)
+	if real, err := filepath.EvalSymlinks(resolved); err == nil {
+		resolved = real
+	}
+	for _, allowed := range e.dirs {
+		rel, err := filepath.Rel(allowed, resolved)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("command dir %q is not in an allowed directory", dir)
+}
+
+// checkCommandEnv rejects variables that would make an allow-listed binary
+// load or run code the admin did not list: dynamic loader settings and PATH
+func checkCommandEnv(vars map[string]string) error {
+	for key := range vars {
+		upper := strings.ToUpper(key)
+		if upper == "PATH" || strings.HasPrefix(upper, "LD_") || strings.HasPrefix(upper, "DYLD_") {
+			return fmt.Errorf("command env may not set %s", key)
+		}
+	}
+	return nil
+}
+
+func decodeCommandAction(task *models.Task) (*models.CommandAction, error) {
+	var action models.CommandAction
+	if err := task.DecodeActionConfig(&action); err != nil {
+		return nil, fmt.Errorf("invalid command configuration: %w", err)
+	}
+	if len(action.Argv) == 0 {
+		return nil, fmt.Errorf("command argv is required")
+	}
+	return &action, nil
+}
+
+// commandEnv gives commands PATH plus their own variables; the scheduler's
+// environment (database credentials and the like) is not inherited
+func commandEnv(vars map[string]string) []string {
+	env := []string{"PATH=" + os.Getenv("PATH")}
+	for key, value := range vars {
+		env = append(env, key+"="+value)
+	}
+	return env
+}
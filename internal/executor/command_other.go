@@ -0,0 +1,14 @@
+//go:build !unix
+
+package executor
+
+import (
+	"os/exec"
+	"time"
+)
+
+// killProcessGroup falls back to killing only the direct child on platforms
+// without process groups
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.WaitDelay = 5 * time.Second
+}
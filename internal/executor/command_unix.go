@@ -0,0 +1,19 @@
+//go:build unix
+
+package executor
+
+import (
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// killProcessGroup starts the command in its own process group so that
+// cancelling it also kills any children it spawned
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = 5 * time.Second
+}
@@ -0,0 +1,143 @@
+package executor
+
+import (
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"task-scheduler/internal/models"
+)
+
+// CookieStore persists each task's cookie jar between runs
+type CookieStore interface {
+	LoadCookies(taskID uuid.UUID) (models.StoredCookies, error)
+	SaveCookies(taskID uuid.UUID, cookies models.StoredCookies) error
+}
+
+// taskJar is a cookiejar.Jar that remembers every cookie it was given so
+// the jar can be written back to the CookieStore after a run
+type taskJar struct {
+	jar *cookiejar.Jar
+
+	mu      sync.Mutex
+	cookies map[string]models.StoredCookie
+}
+
+func newTaskJar(stored models.StoredCookies) (*taskJar, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &taskJar{jar: jar, cookies: make(map[string]models.StoredCookie)}
+	now := time.Now()
+	for _, cookie := range stored {
+		if cookie.Expires != nil && cookie.Expires.Before(now) {
+			continue
+		}
+		u, err := url.Parse(cookie.URL)
+		if err != nil {
+			continue
+		}
+		j.SetCookies(u, []*http.Cookie{toHTTPCookie(cookie)})
+	}
+	return j, nil
+}
+
+// loadJar builds a jar from the task's stored cookies. A failed load is
+// logged and starts the run with an empty jar.
+func (e *HTTPExecutor) loadJar(taskID uuid.UUID) (*taskJar, error) {
+	stored, err := e.cookies.LoadCookies(taskID)
+	if err != nil {
+		log.Printf("Failed to load cookie jar for task %s: %v", taskID, err)
+	}
+	return newTaskJar(stored)
+}
+
+func (e *HTTPExecutor) saveJar(taskID uuid.UUID, jar *taskJar) {
+	if err := e.cookies.SaveCookies(taskID, jar.export()); err != nil {
+		log.Printf("Failed to save cookie jar for task %s: %v", taskID, err)
+	}
+}
+
+func (j *taskJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jar.SetCookies(u, cookies)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, cookie := range cookies {
+		stored := models.StoredCookie{
+			URL:      u.Scheme + "://" + u.Host + "/",
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Domain:   cookie.Domain,
+			Path:     cookie.Path,
+			Secure:   cookie.Secure,
+			HttpOnly: cookie.HttpOnly,
+		}
+		if stored.Domain == "" {
+			stored.Domain = u.Hostname()
+		}
+
+		key := stored.Domain + ";" + stored.Path + ";" + stored.Name
+		switch {
+		case cookie.MaxAge < 0:
+			delete(j.cookies, key)
+			continue
+		case cookie.MaxAge > 0:
+			expires := time.Now().Add(time.Duration(cookie.MaxAge) * time.Second)
+			stored.Expires = &expires
+		case !cookie.Expires.IsZero():
+			if cookie.Expires.Before(time.Now()) {
+				delete(j.cookies, key)
+				continue
+			}
+			expires := cookie.Expires
+			stored.Expires = &expires
+		}
+		j.cookies[key] = stored
+	}
+}
+
+func (j *taskJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// export returns the unexpired cookies in the jar
+func (j *taskJar) export() models.StoredCookies {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	cookies := make(models.StoredCookies, 0, len(j.cookies))
+	for _, cookie := range j.cookies {
+		if cookie.Expires != nil && cookie.Expires.Before(now) {
+			continue
+		}
+		cookies = append(cookies, cookie)
+	}
+	return cookies
+}
+
+func toHTTPCookie(stored models.StoredCookie) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     stored.Name,
+		Value:    stored.Value,
+		Path:     stored.Path,
+		Secure:   stored.Secure,
+		HttpOnly: stored.HttpOnly,
+	}
+	if u, err := url.Parse(stored.URL); err == nil && stored.Domain != u.Hostname() {
+		cookie.Domain = stored.Domain
+	}
+	if stored.Expires != nil {
+		cookie.Expires = *stored.Expires
+	}
+	return cookie
+}
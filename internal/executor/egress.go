@@ -0,0 +1,402 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EgressRules is one side (allow or deny) of an egress policy
+type EgressRules struct {
+	CIDRs []string `json:"cidrs,omitempty"`
+	Hosts []string `json:"hosts,omitempty"`
+	Ports []int    `json:"ports,omitempty"`
+}
+
+// EgressPolicyConfig is the on-disk representation of an egress policy.
+// Hosts are glob patterns such as "*.partner.example.com"; proxies are
+// host:port glob patterns a task is allowed to route through.
+//
+// Link-local, loopback and private ranges are always denied except that
+// AllowPrivateNetworks lifts the loopback and private ones. Tasks may only
+// use the listed proxies unless AllowAnyProxy is set.
+type EgressPolicyConfig struct {
+	Allow                EgressRules `json:"allow"`
+	Deny                 EgressRules `json:"deny"`
+	Proxies              []string    `json:"proxies,omitempty"`
+	AllowPrivateNetworks bool        `json:"allow_private_networks,omitempty"`
+	AllowAnyProxy        bool        `json:"allow_any_proxy,omitempty"`
+}
+
+// EgressPolicy decides which destinations tasks may reach. Deny rules always
+// win; a non-empty allow list restricts its dimension to the listed values.
+type EgressPolicy struct {
+	allowNets  []*net.IPNet
+	denyNets   []*net.IPNet
+	allowHosts []string
+	denyHosts  []string
+	allowPorts map[int]bool
+	denyPorts  map[int]bool
+	proxies    []string
+	anyProxy   bool
+	resolver   *net.Resolver
+}
+
+// ErrEgressDenied is wrapped by every policy violation
+var ErrEgressDenied = errors.New("egress policy violation")
+
+var proxySchemes = map[string]bool{
+	"http":    true,
+	"https":   true,
+	"socks5":  true,
+	"socks5h": true,
+}
+
+// linkLocalCIDRs include the cloud metadata endpoint 169.254.169.254 and
+// are denied by every policy
+var linkLocalCIDRs = []string{"169.254.0.0/16", "fe80::/10"}
+
+// privateCIDRs are loopback, unspecified (which connects to loopback),
+// private and carrier-grade NAT ranges, where internal admin ports live;
+// they are denied unless a policy sets allow_private_networks
+var privateCIDRs = []string{
+	"127.0.0.0/8", "::1/128",
+	"0.0.0.0/8", "::/128",
+	"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16",
+	"100.64.0.0/10",
+	"fc00::/7",
+}
+
+// proxyPorts are the ports a transport dials for a proxy URL without one
+var proxyPorts = map[string]string{
+	"http":    "80",
+	"https":   "443",
+	"socks5":  "1080",
+	"socks5h": "1080",
+}
+
+// DefaultEgressPolicy blocks link-local, loopback and private ranges and
+// per-task proxies
+func DefaultEgressPolicy() *EgressPolicy {
+	policy, _ := NewEgressPolicy(EgressPolicyConfig{})
+	return policy
+}
+
+// PermissiveEgressPolicy only blocks link-local ranges, for tests and
+// development setups whose destinations listen on loopback
+func PermissiveEgressPolicy() *EgressPolicy {
+	policy, _ := NewEgressPolicy(EgressPolicyConfig{AllowPrivateNetworks: true})
+	return policy
+}
+
+func NewEgressPolicy(config EgressPolicyConfig) (*EgressPolicy, error) {
+	policy := &EgressPolicy{
+		allowHosts: lowerAll(config.Allow.Hosts),
+		denyHosts:  lowerAll(config.Deny.Hosts),
+		allowPorts: portSet(config.Allow.Ports),
+		denyPorts:  portSet(config.Deny.Ports),
+		proxies:    lowerAll(config.Proxies),
+		anyProxy:   config.AllowAnyProxy,
+		resolver:   net.DefaultResolver,
+	}
+
+	deny := append(append([]string{}, config.Deny.CIDRs...), linkLocalCIDRs...)
+	if !config.AllowPrivateNetworks {
+		deny = append(deny, privateCIDRs...)
+	}
+
+	var err error
+	if policy.allowNets, err = parseCIDRs(config.Allow.CIDRs); err != nil {
+		return nil, err
+	}
+	if policy.denyNets, err = parseCIDRs(deny); err != nil {
+		return nil, err
+	}
+
+	for _, pattern := range append(append([]string{}, policy.allowHosts...), policy.denyHosts...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid host pattern %q: %w", pattern, err)
+		}
+	}
+
+	return policy, nil
+}
+
+// LoadEgressPolicy reads an EgressPolicyConfig JSON document from path
+func LoadEgressPolicy(path string) (*EgressPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read egress policy: %w", err)
+	}
+
+	var config EgressPolicyConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse egress policy: %w", err)
+	}
+
+	return NewEgressPolicy(config)
+}
+
+// CheckURL applies the hostname, port and literal-IP rules to a target URL
+// without touching DNS
+func (p *EgressPolicy) CheckURL(target *url.URL) error {
+	if p == nil {
+		return nil
+	}
+
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return fmt.Errorf("%w: unsupported scheme %q", ErrEgressDenied, target.Scheme)
+	}
+
+	host := strings.ToLower(target.Hostname())
+	if host == "" {
+		return fmt.Errorf("%w: missing host", ErrEgressDenied)
+	}
+
+	if err := p.checkPort(urlPort(target)); err != nil {
+		return err
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return p.checkIP(ip)
+	}
+
+	return p.checkHost(host)
+}
+
+// CheckResolved resolves host and verifies every address it maps to
+func (p *EgressPolicy) CheckResolved(ctx context.Context, host string) error {
+	if p == nil {
+		return nil
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return p.checkIP(ip)
+	}
+
+	addrs, err := p.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+
+	for _, addr := range addrs {
+		if err := p.checkIP(addr.IP); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckProxy validates a task's proxy URL
+func (p *EgressPolicy) CheckProxy(proxy *url.URL) error {
+	if !proxySchemes[proxy.Scheme] {
+		return fmt.Errorf("unsupported proxy scheme %q", proxy.Scheme)
+	}
+	if proxy.Host == "" {
+		return fmt.Errorf("proxy URL must include a host")
+	}
+	if p == nil || p.anyProxy {
+		return nil
+	}
+	if !p.isAllowedProxy(proxy.Host) {
+		return fmt.Errorf("%w: proxy %s is not allowed", ErrEgressDenied, proxy.Host)
+	}
+	return nil
+}
+
+// ValidateTarget is the create-time check: URL rules, proxy rules and a
+// best-effort DNS check. Resolution failures are not treated as violations
+// since the name may only be resolvable from the execution environment.
+func (p *EgressPolicy) ValidateTarget(rawURL string, proxy *string) error {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	if err := p.CheckURL(target); err != nil {
+		return err
+	}
+
+	if proxy != nil && *proxy != "" {
+		proxyURL, err := url.Parse(*proxy)
+		if err != nil {
+			return fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		if err := p.CheckProxy(proxyURL); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := p.CheckResolved(ctx, target.Hostname()); errors.Is(err, ErrEgressDenied) {
+		return err
+	}
+	return nil
+}
+
+// DialContext returns a dial function that resolves the destination itself,
+// checks each address and connects to the vetted IP. Connecting to the exact
+// address that was checked defeats DNS rebinding.
+func (p *EgressPolicy) DialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return p.ProxyDialContext(dialer, nil)
+}
+
+// ProxyDialContext is DialContext for a transport that routes through
+// proxy. Only dials to that proxy's own address skip the IP checks, and
+// only when the proxy is on the policy's list, since a listed proxy may
+// live on a private network. The proxy resolves the targets it connects
+// to itself, so those only get the pre-flight CheckResolved.
+func (p *EgressPolicy) ProxyDialContext(dialer *net.Dialer, proxy *url.URL) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	proxyAddr := ""
+	if p != nil && proxy != nil && p.isAllowedProxy(proxy.Host) {
+		proxyAddr = proxy.Host
+		if proxy.Port() == "" {
+			proxyAddr = net.JoinHostPort(proxy.Hostname(), proxyPorts[proxy.Scheme])
+		}
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if p == nil {
+			return dialer.DialContext(ctx, network, addr)
+		}
+
+		host, portStr, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		if proxyAddr != "" && strings.EqualFold(addr, proxyAddr) {
+			return dialer.DialContext(ctx, network, addr)
+		}
+
+		port, _ := strconv.Atoi(portStr)
+		if err := p.checkPort(port); err != nil {
+			return nil, err
+		}
+
+		var ips []net.IP
+		if ip := net.ParseIP(host); ip != nil {
+			ips = []net.IP{ip}
+		} else {
+			addrs, err := p.resolver.LookupIPAddr(ctx, host)
+			if err != nil {
+				return nil, err
+			}
+			for _, a := range addrs {
+				ips = append(ips, a.IP)
+			}
+		}
+
+		var lastErr error
+		for _, ip := range ips {
+			if err := p.checkIP(ip); err != nil {
+				return nil, err
+			}
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), portStr))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		if lastErr == nil {
+			lastErr = fmt.Errorf("no addresses found for %s", host)
+		}
+		return nil, lastErr
+	}
+}
+
+func (p *EgressPolicy) checkHost(host string) error {
+	if matchAny(p.denyHosts, host) {
+		return fmt.Errorf("%w: host %s is denied", ErrEgressDenied, host)
+	}
+	if len(p.allowHosts) > 0 && !matchAny(p.allowHosts, host) {
+		return fmt.Errorf("%w: host %s is not in the allow list", ErrEgressDenied, host)
+	}
+	return nil
+}
+
+func (p *EgressPolicy) checkPort(port int) error {
+	if p.denyPorts[port] {
+		return fmt.Errorf("%w: port %d is denied", ErrEgressDenied, port)
+	}
+	if len(p.allowPorts) > 0 && !p.allowPorts[port] {
+		return fmt.Errorf("%w: port %d is not in the allow list", ErrEgressDenied, port)
+	}
+	return nil
+}
+
+func (p *EgressPolicy) checkIP(ip net.IP) error {
+	for _, network := range p.denyNets {
+		if network.Contains(ip) {
+			return fmt.Errorf("%w: address %s is in denied range %s", ErrEgressDenied, ip, network)
+		}
+	}
+	if len(p.allowNets) == 0 {
+		return nil
+	}
+	for _, network := range p.allowNets {
+		if network.Contains(ip) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: address %s is not in the allow list", ErrEgressDenied, ip)
+}
+
+func (p *EgressPolicy) isAllowedProxy(hostPort string) bool {
+	return matchAny(p.proxies, strings.ToLower(hostPort))
+}
+
+func urlPort(u *url.URL) int {
+	if port, err := strconv.Atoi(u.Port()); err == nil {
+		return port
+	}
+	if u.Scheme == "https" {
+		return 443
+	}
+	return 80
+}
+
+func matchAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+		}
+		nets = append(nets, network)
+	}
+	return nets, nil
+}
+
+func portSet(ports []int) map[int]bool {
+	set := make(map[int]bool, len(ports))
+	for _, port := range ports {
+		set[port] = true
+	}
+	return set
+}
+
+func lowerAll(values []string) []string {
+	lowered := make([]string, len(values))
+	for i, value := range values {
+		lowered[i] = strings.ToLower(value)
+	}
+	return lowered
+}
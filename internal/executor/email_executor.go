@@ -0,0 +1,537 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/textproto"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"task-scheduler/internal/models"
+	"task-scheduler/internal/secrets"
+	"task-scheduler/internal/templating"
+)
+
+const (
+	defaultEmailTimeout = time.Minute
+	maxAttachmentBytes  = 10 << 20
+)
+
+// EmailExecutor sends email actions over SMTP
+type EmailExecutor struct {
+	secrets     *secrets.Resolver
+	tlsProfiles *TLSProfiles
+	egress      *EgressPolicy
+
+	// client fetches attachments
+	client *http.Client
+}
+
+// EmailExecutorOption configures optional EmailExecutor behaviour
+type EmailExecutorOption func(*EmailExecutor)
+
+// WithEmailTLSProfiles makes the given TLS profiles available for STARTTLS
+func WithEmailTLSProfiles(profiles *TLSProfiles) EmailExecutorOption {
+	return func(e *EmailExecutor) {
+		e.tlsProfiles = profiles
+	}
+}
+
+// WithEmailEgressPolicy applies an egress policy to SMTP servers and
+// attachment URLs
+func WithEmailEgressPolicy(policy *EgressPolicy) EmailExecutorOption {
+	return func(e *EmailExecutor) {
+		e.egress = policy
+	}
+}
+
+func NewEmailExecutor(resolver *secrets.Resolver, opts ...EmailExecutorOption) *EmailExecutor {
+	e := &EmailExecutor{secrets: resolver, egress: DefaultEgressPolicy()}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	e.client = &http.Client{
+		Transport: &http.Transport{
+			DialContext:         e.egress.DialContext(&net.Dialer{Timeout: 10 * time.Second}),
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= models.DefaultMaxRedirects {
+				return fmt.Errorf("stopped after %d redirects", len(via))
+			}
+			return e.egress.CheckURL(req.URL)
+		},
+	}
+	return e
+}
+
+// ValidateTask checks an email action's configuration, templates, secret
+// reference and destinations
+func (e *EmailExecutor) ValidateTask(task *models.Task) error {
+	if task.ActionType != models.ActionTypeEmail {
+		return nil
+	}
+
+	action, err := decodeEmailAction(task)
+	if err != nil {
+		return err
+	}
+	if action.TimeoutSeconds < 0 {
+		return fmt.Errorf("email timeout_seconds must not be negative")
+	}
+	if _, _, err := renderEmail(task, action, time.Now()); err != nil {
+		return err
+	}
+	if action.PasswordSecret != "" {
+		if action.Username == "" {
+			return fmt.Errorf("email password_secret requires a username")
+		}
+		if _, err := e.secrets.Resolve(action.PasswordSecret); err != nil {
+			return err
+		}
+	}
+	if action.TLSProfile != "" && !e.tlsProfiles.Has(action.TLSProfile) {
+		return fmt.Errorf("unknown TLS profile %q", action.TLSProfile)
+	}
+
+	if err := e.egress.ValidateTarget("https://"+smtpAddress(action), nil); err != nil {
+		return err
+	}
+	for _, attachment := range action.Attachments {
+		if err := e.egress.ValidateTarget(attachment.URL, nil); err != nil {
+			return fmt.Errorf("attachment %s: %w", attachment.URL, err)
+		}
+	}
+	return nil
+}
+
+func (e *EmailExecutor) Execute(task *models.Task) *models.TaskResult {
+	return e.ExecuteWithTimeout(task, 0)
+}
+
+// ExecuteWithTimeout fetches the attachments and delivers the message,
+// bounded by the timeout (or the action's own timeout, when shorter)
+func (e *EmailExecutor) ExecuteWithTimeout(task *models.Task, timeout time.Duration) *models.TaskResult {
+	startTime := time.Now()
+
+	result := &models.TaskResult{
+		ID:        uuid.New(),
+		TaskID:    task.ID,
+		RunAt:     startTime,
+		CreatedAt: startTime,
+	}
+
+	action, err := decodeEmailAction(task)
+	if err != nil {
+		result.ErrorMessage = stringPtr(fmt.Sprintf("Failed to prepare email: %v", err))
+		return result
+	}
+
+	if action.TimeoutSeconds > 0 {
+		actionTimeout := time.Duration(action.TimeoutSeconds) * time.Second
+		if timeout <= 0 || actionTimeout < timeout {
+			timeout = actionTimeout
+		}
+	}
+	if timeout <= 0 {
+		timeout = defaultEmailTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	message, err := e.buildMessage(ctx, task, action, startTime)
+	if err != nil {
+		result.DurationMs = int(time.Since(startTime).Milliseconds())
+		result.ErrorMessage = stringPtr(fmt.Sprintf("Failed to prepare email: %v", err))
+		return result
+	}
+
+	session := &smtpSession{}
+	err = e.send(ctx, action, message, session)
+	result.DurationMs = int(time.Since(startTime).Milliseconds())
+	result.SMTPReplies = session.replies
+
+	var protoErr *textproto.Error
+	switch {
+	case err == nil:
+		result.Success = true
+	case errors.As(err, &protoErr):
+		result.ErrorMessage = stringPtr(fmt.Sprintf("SMTP %s rejected with %d: %s", session.lastCommand, protoErr.Code, protoErr.Msg))
+	case ctx.Err() == context.DeadlineExceeded:
+		result.ErrorMessage = stringPtr(fmt.Sprintf("Email timed out after %s", timeout))
+	default:
+		result.ErrorMessage = stringPtr(fmt.Sprintf("Failed to send email: %v", err))
+	}
+
+	return result
+}
+
+// smtpSession is one SMTP conversation; every reply is recorded
+type smtpSession struct {
+	text        *textproto.Conn
+	replies     models.SMTPReplies
+	lastCommand string
+}
+
+// cmd sends a command and reads the reply. label is what gets recorded, so
+// credentials never reach the result. expect is a reply class (2 for 2xx)
+// or an exact code.
+func (s *smtpSession) cmd(expect int, label string, format string, args ...interface{}) (string, error) {
+	s.lastCommand = label
+	if format != "" {
+		if err := s.text.PrintfLine(format, args...); err != nil {
+			return "", err
+		}
+	}
+	code, message, err := s.text.ReadResponse(expect)
+	if code != 0 {
+		s.replies = append(s.replies, models.SMTPReply{Command: label, Code: code, Message: message})
+	}
+	return message, err
+}
+
+func (e *EmailExecutor) send(ctx context.Context, action *models.EmailAction, message []byte, session *smtpSession) error {
+	conn, err := e.egress.DialContext(&net.Dialer{})(ctx, "tcp", smtpAddress(action))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	session.text = textproto.NewConn(conn)
+
+	if _, err := session.cmd(220, "CONNECT", ""); err != nil {
+		return err
+	}
+	extensions, err := session.cmd(2, "EHLO", "EHLO localhost")
+	if err != nil {
+		return err
+	}
+
+	encrypted := false
+	if action.StartTLS {
+		if !hasExtension(extensions, "STARTTLS") {
+			return fmt.Errorf("server does not support STARTTLS")
+		}
+		if _, err := session.cmd(220, "STARTTLS", "STARTTLS"); err != nil {
+			return err
+		}
+
+		config, err := e.tlsConfig(action)
+		if err != nil {
+			return err
+		}
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return fmt.Errorf("STARTTLS handshake failed: %w", err)
+		}
+		session.text = textproto.NewConn(tlsConn)
+		encrypted = true
+
+		if extensions, err = session.cmd(2, "EHLO", "EHLO localhost"); err != nil {
+			return err
+		}
+	}
+
+	if action.Username != "" {
+		// As net/smtp does, credentials only travel in the clear to loopback
+		if !encrypted && !isLoopbackHost(action.Host) {
+			return fmt.Errorf("refusing to send SMTP credentials without STARTTLS")
+		}
+		if !hasAuthMechanism(extensions, "PLAIN") {
+			return fmt.Errorf("server does not support AUTH PLAIN")
+		}
+		var password string
+		if action.PasswordSecret != "" {
+			if password, err = e.secrets.Resolve(action.PasswordSecret); err != nil {
+				return err
+			}
+		}
+		credentials := base64.StdEncoding.EncodeToString([]byte("\x00" + action.Username + "\x00" + password))
+		if _, err := session.cmd(235, "AUTH PLAIN", "AUTH PLAIN %s", credentials); err != nil {
+			return err
+		}
+	}
+
+	from, _ := mail.ParseAddress(action.From)
+	if _, err := session.cmd(2, "MAIL FROM", "MAIL FROM:<%s>", from.Address); err != nil {
+		return err
+	}
+	for _, recipient := range append(append([]string{}, action.To...), action.Cc...) {
+		addr, _ := mail.ParseAddress(recipient)
+		if _, err := session.cmd(2, "RCPT TO:<"+addr.Address+">", "RCPT TO:<%s>", addr.Address); err != nil {
+			return err
+		}
+	}
+
+	if _, err := session.cmd(354, "DATA", "DATA"); err != nil {
+		return err
+	}
+	writer := session.text.DotWriter()
+	if _, err := writer.Write(message); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	if _, err := session.cmd(2, "END DATA", ""); err != nil {
+		return err
+	}
+
+	// The message is accepted at this point; a failed QUIT does not matter
+	session.cmd(221, "QUIT", "QUIT")
+	return nil
+}
+
+func (e *EmailExecutor) tlsConfig(action *models.EmailAction) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if action.TLSProfile != "" {
+		profile, err := e.tlsProfiles.Config(action.TLSProfile)
+		if err != nil {
+			return nil, err
+		}
+		config = profile
+	}
+	if config.ServerName == "" {
+		config.ServerName = action.Host
+	}
+	return config, nil
+}
+
+type emailAttachment struct {
+	filename    string
+	contentType string
+	data        []byte
+}
+
+func (e *EmailExecutor) buildMessage(ctx context.Context, task *models.Task, action *models.EmailAction, now time.Time) ([]byte, error) {
+	subject, body, err := renderEmail(task, action, now)
+	if err != nil {
+		return nil, err
+	}
+
+	attachments := make([]emailAttachment, 0, len(action.Attachments))
+	for _, attachment := range action.Attachments {
+		fetched, err := e.fetchAttachment(ctx, attachment)
+		if err != nil {
+			return nil, fmt.Errorf("attachment %s: %w", attachment.URL, err)
+		}
+		attachments = append(attachments, fetched)
+	}
+
+	var msg bytes.Buffer
+	writeHeader := func(name, value string) {
+		fmt.Fprintf(&msg, "%s: %s\r\n", name, value)
+	}
+	writeHeader("From", formatAddresses([]string{action.From}))
+	writeHeader("To", formatAddresses(action.To))
+	if len(action.Cc) > 0 {
+		writeHeader("Cc", formatAddresses(action.Cc))
+	}
+	writeHeader("Subject", mime.QEncoding.Encode("utf-8", subject))
+	writeHeader("Date", now.Format(time.RFC1123Z))
+	writeHeader("Message-ID", "<"+uuid.NewString()+"@task-scheduler>")
+	writeHeader("MIME-Version", "1.0")
+
+	bodyType := "text/plain; charset=utf-8"
+	if action.HTML {
+		bodyType = "text/html; charset=utf-8"
+	}
+
+	if len(attachments) == 0 {
+		writeHeader("Content-Type", bodyType)
+		writeHeader("Content-Transfer-Encoding", "quoted-printable")
+		msg.WriteString("\r\n")
+		if err := writeQuotedPrintable(&msg, body); err != nil {
+			return nil, err
+		}
+		return msg.Bytes(), nil
+	}
+
+	parts := multipart.NewWriter(&msg)
+	writeHeader("Content-Type", "multipart/mixed; boundary="+parts.Boundary())
+	msg.WriteString("\r\n")
+
+	part, err := parts.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {bodyType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := writeQuotedPrintable(part, body); err != nil {
+		return nil, err
+	}
+
+	for _, attachment := range attachments {
+		part, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {attachment.contentType},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.filename})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		encoded := base64.StdEncoding.EncodeToString(attachment.data)
+		for len(encoded) > 76 {
+			io.WriteString(part, encoded[:76]+"\r\n")
+			encoded = encoded[76:]
+		}
+		io.WriteString(part, encoded+"\r\n")
+	}
+
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	return msg.Bytes(), nil
+}
+
+func (e *EmailExecutor) fetchAttachment(ctx context.Context, attachment models.EmailAttachment) (emailAttachment, error) {
+	target, err := url.Parse(attachment.URL)
+	if err != nil {
+		return emailAttachment{}, err
+	}
+	if err := e.egress.CheckURL(target); err != nil {
+		return emailAttachment{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, attachment.URL, nil)
+	if err != nil {
+		return emailAttachment{}, err
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return emailAttachment{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return emailAttachment{}, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxAttachmentBytes+1))
+	if err != nil {
+		return emailAttachment{}, err
+	}
+	if len(data) > maxAttachmentBytes {
+		return emailAttachment{}, fmt.Errorf("larger than %d bytes", maxAttachmentBytes)
+	}
+
+	filename := attachment.Filename
+	if filename == "" {
+		filename = path.Base(target.Path)
+		if filename == "/" || filename == "." {
+			filename = "attachment"
+		}
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return emailAttachment{filename: filename, contentType: contentType, data: data}, nil
+}
+
+func renderEmail(task *models.Task, action *models.EmailAction, now time.Time) (string, string, error) {
+	data := templating.NewData(task, now)
+	subject, err := templating.Render(action.Subject, data)
+	if err != nil {
+		return "", "", err
+	}
+	body, err := templating.Render(action.Body, data)
+	if err != nil {
+		return "", "", err
+	}
+	return subject, body, nil
+}
+
+func writeQuotedPrintable(w io.Writer, text string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := io.WriteString(qp, text); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func formatAddresses(addresses []string) string {
+	formatted := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		if addr, err := mail.ParseAddress(address); err == nil {
+			formatted = append(formatted, addr.String())
+		}
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// hasExtension reports whether an EHLO reply advertises an extension
+func hasExtension(ehlo, extension string) bool {
+	for _, line := range strings.Split(ehlo, "\n") {
+		if name, _, _ := strings.Cut(strings.TrimSpace(line), " "); strings.EqualFold(name, extension) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAuthMechanism(ehlo, mechanism string) bool {
+	for _, line := range strings.Split(ehlo, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || !strings.EqualFold(fields[0], "AUTH") {
+			continue
+		}
+		for _, field := range fields[1:] {
+			if strings.EqualFold(field, mechanism) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func smtpAddress(action *models.EmailAction) string {
+	return net.JoinHostPort(action.Host, strconv.Itoa(action.Port))
+}
+
+func decodeEmailAction(task *models.Task) (*models.EmailAction, error) {
+	var action models.EmailAction
+	if err := task.DecodeActionConfig(&action); err != nil {
+		return nil, fmt.Errorf("invalid email configuration: %w", err)
+	}
+	if action.Host == "" || action.Port <= 0 || action.Port > 65535 {
+		return nil, fmt.Errorf("email host and port are required")
+	}
+	if len(action.To) == 0 {
+		return nil, fmt.Errorf("email needs at least one recipient")
+	}
+	for _, address := range append(append([]string{action.From}, action.To...), action.Cc...) {
+		if _, err := mail.ParseAddress(address); err != nil {
+			return nil, fmt.Errorf("invalid email address %q: %w", address, err)
+		}
+	}
+	return &action, nil
+}
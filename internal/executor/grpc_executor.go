@@ -0,0 +1,391 @@
+package executor
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"task-scheduler/internal/models"
+)
+
+const defaultGRPCTimeout = 30 * time.Second
+
+// GRPCExecutor invokes unary gRPC methods, mapping JSON requests onto
+// dynamically resolved protobuf messages
+type GRPCExecutor struct {
+	tlsProfiles *TLSProfiles
+	egress      *EgressPolicy
+}
+
+// GRPCExecutorOption configures optional GRPCExecutor behaviour
+type GRPCExecutorOption func(*GRPCExecutor)
+
+// WithGRPCTLSProfiles makes the given TLS profiles available to grpc actions
+func WithGRPCTLSProfiles(profiles *TLSProfiles) GRPCExecutorOption {
+	return func(e *GRPCExecutor) {
+		e.tlsProfiles = profiles
+	}
+}
+
+// WithGRPCEgressPolicy applies an egress policy to grpc targets
+func WithGRPCEgressPolicy(policy *EgressPolicy) GRPCExecutorOption {
+	return func(e *GRPCExecutor) {
+		e.egress = policy
+	}
+}
+
+func NewGRPCExecutor(opts ...GRPCExecutorOption) *GRPCExecutor {
+	e := &GRPCExecutor{egress: DefaultEgressPolicy()}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// ValidateTask checks a grpc action's configuration. The method is only
+// resolved here when a descriptor set is supplied; reflection needs the
+// server, which may not be reachable from where the task is created.
+func (e *GRPCExecutor) ValidateTask(task *models.Task) error {
+	if task.ActionType != models.ActionTypeGRPC {
+		return nil
+	}
+
+	action, err := decodeGRPCAction(task)
+	if err != nil {
+		return err
+	}
+	if action.TimeoutSeconds < 0 {
+		return fmt.Errorf("grpc timeout_seconds must not be negative")
+	}
+	if len(action.Request) > 0 && !json.Valid(action.Request) {
+		return fmt.Errorf("grpc request must be valid JSON")
+	}
+	if action.TLSProfile != "" && !e.tlsProfiles.Has(action.TLSProfile) {
+		return fmt.Errorf("unknown TLS profile %q", action.TLSProfile)
+	}
+	if err := e.egress.ValidateTarget("https://"+action.Target, nil); err != nil {
+		return err
+	}
+
+	if len(action.DescriptorSet) > 0 {
+		service, method, _ := splitGRPCMethod(action.Method)
+		files, err := filesFromDescriptorSet(action.DescriptorSet)
+		if err != nil {
+			return err
+		}
+		if _, err := findMethod(files, service, method); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *GRPCExecutor) Execute(task *models.Task) *models.TaskResult {
+	return e.ExecuteWithTimeout(task, 0)
+}
+
+// ExecuteWithTimeout invokes the method with the timeout (or the action's
+// own timeout, when shorter) as its deadline
+func (e *GRPCExecutor) ExecuteWithTimeout(task *models.Task, timeout time.Duration) *models.TaskResult {
+	startTime := time.Now()
+
+	result := &models.TaskResult{
+		ID:        uuid.New(),
+		TaskID:    task.ID,
+		RunAt:     startTime,
+		CreatedAt: startTime,
+	}
+
+	action, err := decodeGRPCAction(task)
+	if err != nil {
+		result.ErrorMessage = stringPtr(fmt.Sprintf("Failed to prepare call: %v", err))
+		return result
+	}
+
+	if action.TimeoutSeconds > 0 {
+		actionTimeout := time.Duration(action.TimeoutSeconds) * time.Second
+		if timeout <= 0 || actionTimeout < timeout {
+			timeout = actionTimeout
+		}
+	}
+	if timeout <= 0 {
+		timeout = defaultGRPCTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err = e.invoke(ctx, action, result)
+	result.DurationMs = int(time.Since(startTime).Milliseconds())
+
+	code := status.Code(err)
+	result.GRPCStatus = stringPtr(grpcStatusName(code))
+	if err == nil {
+		result.Success = true
+		return result
+	}
+
+	if st, ok := status.FromError(err); ok {
+		result.ErrorMessage = stringPtr(fmt.Sprintf("gRPC status %s: %s", grpcStatusName(code), st.Message()))
+	} else {
+		result.ErrorMessage = stringPtr(fmt.Sprintf("gRPC call failed: %v", err))
+	}
+	return result
+}
+
+func (e *GRPCExecutor) invoke(ctx context.Context, action *models.GRPCAction, result *models.TaskResult) error {
+	creds, err := e.credentials(action)
+	if err != nil {
+		return err
+	}
+
+	dial := e.egress.DialContext(&net.Dialer{Timeout: 10 * time.Second})
+	conn, err := grpc.DialContext(ctx, action.Target,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return dial(ctx, "tcp", addr)
+		}),
+	)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	service, methodName, _ := splitGRPCMethod(action.Method)
+	var files *protoregistry.Files
+	if len(action.DescriptorSet) > 0 {
+		files, err = filesFromDescriptorSet(action.DescriptorSet)
+	} else {
+		files, err = filesFromReflection(ctx, conn, service)
+	}
+	if err != nil {
+		return err
+	}
+	method, err := findMethod(files, service, methodName)
+	if err != nil {
+		return err
+	}
+
+	request := dynamicpb.NewMessage(method.Input())
+	if len(action.Request) > 0 {
+		if err := protojson.Unmarshal(action.Request, request); err != nil {
+			return status.Errorf(codes.InvalidArgument, "request does not match %s: %v", method.Input().FullName(), err)
+		}
+	}
+	response := dynamicpb.NewMessage(method.Output())
+
+	for key, value := range action.Metadata {
+		ctx = metadata.AppendToOutgoingContext(ctx, key, value)
+	}
+
+	var header, trailer metadata.MD
+	err = conn.Invoke(ctx, "/"+service+"/"+methodName, request, response, grpc.Header(&header), grpc.Trailer(&trailer))
+
+	responseHeaders := make(models.MultiHeaders, len(header)+len(trailer))
+	for key, values := range metadata.Join(header, trailer) {
+		responseHeaders[key] = values
+	}
+	result.ResponseHeaders = responseHeaders
+
+	if err != nil {
+		return err
+	}
+
+	body, err := protojson.Marshal(response)
+	if err != nil {
+		return err
+	}
+	result.ResponseBody = stringPtr(string(body))
+	return nil
+}
+
+func (e *GRPCExecutor) credentials(action *models.GRPCAction) (credentials.TransportCredentials, error) {
+	if action.Plaintext {
+		return insecure.NewCredentials(), nil
+	}
+	if action.TLSProfile == "" {
+		return credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12}), nil
+	}
+	config, err := e.tlsProfiles.Config(action.TLSProfile)
+	if err != nil {
+		return nil, err
+	}
+	return credentials.NewTLS(config), nil
+}
+
+// filesFromReflection asks the server for the file defining service and
+// every file it depends on
+func filesFromReflection(ctx context.Context, conn *grpc.ClientConn, service string) (*protoregistry.Files, error) {
+	stream, err := rpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.CloseSend()
+
+	fetch := func(request *rpb.ServerReflectionRequest) ([]*descriptorpb.FileDescriptorProto, error) {
+		if err := stream.Send(request); err != nil {
+			return nil, err
+		}
+		response, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		if errResp := response.GetErrorResponse(); errResp != nil {
+			return nil, status.Error(codes.Code(errResp.ErrorCode), errResp.ErrorMessage)
+		}
+
+		var files []*descriptorpb.FileDescriptorProto
+		for _, raw := range response.GetFileDescriptorResponse().GetFileDescriptorProto() {
+			file := &descriptorpb.FileDescriptorProto{}
+			if err := proto.Unmarshal(raw, file); err != nil {
+				return nil, err
+			}
+			files = append(files, file)
+		}
+		return files, nil
+	}
+
+	received, err := fetch(&rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: service},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*descriptorpb.FileDescriptorProto)
+	for len(received) > 0 {
+		file := received[0]
+		received = received[1:]
+		if _, seen := byName[file.GetName()]; seen {
+			continue
+		}
+		byName[file.GetName()] = file
+
+		for _, dependency := range file.GetDependency() {
+			if _, seen := byName[dependency]; seen {
+				continue
+			}
+			more, err := fetch(&rpb.ServerReflectionRequest{
+				MessageRequest: &rpb.ServerReflectionRequest_FileByFilename{FileByFilename: dependency},
+			})
+			if err != nil {
+				return nil, err
+			}
+			received = append(received, more...)
+		}
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	for _, file := range byName {
+		set.File = append(set.File, file)
+	}
+	return protodesc.NewFiles(set)
+}
+
+func filesFromDescriptorSet(raw []byte) (*protoregistry.Files, error) {
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(raw, set); err != nil {
+		return nil, fmt.Errorf("invalid descriptor set: %w", err)
+	}
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("invalid descriptor set: %w", err)
+	}
+	return files, nil
+}
+
+func findMethod(files *protoregistry.Files, service, method string) (protoreflect.MethodDescriptor, error) {
+	descriptor, err := files.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, status.Errorf(codes.Unimplemented, "service %s not found", service)
+	}
+	serviceDescriptor, ok := descriptor.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, status.Errorf(codes.Unimplemented, "%s is not a service", service)
+	}
+	methodDescriptor := serviceDescriptor.Methods().ByName(protoreflect.Name(method))
+	if methodDescriptor == nil {
+		return nil, status.Errorf(codes.Unimplemented, "method %s/%s not found", service, method)
+	}
+	if methodDescriptor.IsStreamingClient() || methodDescriptor.IsStreamingServer() {
+		return nil, status.Errorf(codes.Unimplemented, "method %s/%s is not unary", service, method)
+	}
+	return methodDescriptor, nil
+}
+
+// splitGRPCMethod accepts "pkg.Service/Method", with or without a leading slash
+func splitGRPCMethod(fullMethod string) (string, string, error) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok || service == "" || method == "" || strings.Contains(method, "/") {
+		return "", "", fmt.Errorf("grpc method must look like package.Service/Method")
+	}
+	return service, method, nil
+}
+
+// grpcStatusName returns the canonical upper-case name of a status code
+func grpcStatusName(code codes.Code) string {
+	switch code {
+	case codes.OK:
+		return "OK"
+	case codes.Canceled:
+		return "CANCELLED"
+	case codes.DeadlineExceeded:
+		return "DEADLINE_EXCEEDED"
+	case codes.NotFound:
+		return "NOT_FOUND"
+	case codes.AlreadyExists:
+		return "ALREADY_EXISTS"
+	case codes.PermissionDenied:
+		return "PERMISSION_DENIED"
+	case codes.ResourceExhausted:
+		return "RESOURCE_EXHAUSTED"
+	case codes.FailedPrecondition:
+		return "FAILED_PRECONDITION"
+	case codes.OutOfRange:
+		return "OUT_OF_RANGE"
+	case codes.InvalidArgument:
+		return "INVALID_ARGUMENT"
+	case codes.DataLoss:
+		return "DATA_LOSS"
+	default:
+		// The remaining codes are single words, e.g. "Unavailable"
+		return strings.ToUpper(code.String())
+	}
+}
+
+func decodeGRPCAction(task *models.Task) (*models.GRPCAction, error) {
+	var action models.GRPCAction
+	if err := task.DecodeActionConfig(&action); err != nil {
+		return nil, fmt.Errorf("invalid grpc configuration: %w", err)
+	}
+	if action.Target == "" {
+		return nil, fmt.Errorf("grpc target is required")
+	}
+	if _, _, err := net.SplitHostPort(action.Target); err != nil {
+		return nil, fmt.Errorf("grpc target must be host:port")
+	}
+	if _, _, err := splitGRPCMethod(action.Method); err != nil {
+		return nil, err
+	}
+	return &action, nil
+}
@@ -1,36 +1,181 @@
 package executor
 
 import (
+	"container/list"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 
+	"task-scheduler/internal/blobstore"
 	"task-scheduler/internal/models"
+	"task-scheduler/internal/templating"
 )
 
 type HTTPExecutor struct {
-	client *http.Client
+	timeout          time.Duration
+	tlsProfiles      *TLSProfiles
+	egress           *EgressPolicy
+	blobs            *blobstore.Store
+	cookies          CookieStore
+	maxResponseBytes int64
+
+	// transports are pooled per TLS profile and proxy so connections that
+	// carry a client certificate are never reused for another profile. The
+	// pool keeps the most recently used maxPooledTransports; see transportFor.
+	mu             sync.Mutex
+	transports     map[string]*list.Element
+	transportOrder *list.List
+}
+
+// maxPooledTransports caps the pooled transports. Proxies are free-form per
+// task, so without a cap every proxy ever used would keep its connections.
+const maxPooledTransports = 64
+
+// pooledTransport is a transportOrder entry
+type pooledTransport struct {
+	key       string
+	transport *http.Transport
+}
+
+// HTTPExecutorOption configures optional HTTPExecutor behaviour
+type HTTPExecutorOption func(*HTTPExecutor)
+
+// WithTLSProfiles makes the given TLS profiles available to tasks
+func WithTLSProfiles(profiles *TLSProfiles) HTTPExecutorOption {
+	return func(e *HTTPExecutor) {
+		e.tlsProfiles = profiles
+	}
+}
+
+// WithEgressPolicy replaces the default egress policy
+func WithEgressPolicy(policy *EgressPolicy) HTTPExecutorOption {
+	return func(e *HTTPExecutor) {
+		e.egress = policy
+	}
+}
+
+// WithBlobStore enables out-of-band storage of full response bodies
+func WithBlobStore(store *blobstore.Store) HTTPExecutorOption {
+	return func(e *HTTPExecutor) {
+		e.blobs = store
+	}
+}
+
+// WithCookieStore persists cookie jars for tasks that enable them
+func WithCookieStore(store CookieStore) HTTPExecutorOption {
+	return func(e *HTTPExecutor) {
+		e.cookies = store
+	}
+}
+
+// WithMaxResponseBytes caps how much of a response body is ever read
+func WithMaxResponseBytes(limit int64) HTTPExecutorOption {
+	return func(e *HTTPExecutor) {
+		if limit > 0 {
+			e.maxResponseBytes = limit
+		}
+	}
+}
+
+func NewHTTPExecutor(opts ...HTTPExecutorOption) *HTTPExecutor {
+	e := &HTTPExecutor{
+		timeout:          30 * time.Second,
+		egress:           DefaultEgressPolicy(),
+		maxResponseBytes: 10 << 20,
+		transports:       make(map[string]*list.Element),
+		transportOrder:   list.New(),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// ValidateTask checks that the task only references configured TLS profiles
+// and that its destination and proxy are permitted by the egress policy
+func (e *HTTPExecutor) ValidateTask(task *models.Task) error {
+	if !task.IsHTTP() {
+		return nil
+	}
+	if task.TLSProfile != nil && *task.TLSProfile != "" && !e.tlsProfiles.Has(*task.TLSProfile) {
+		return fmt.Errorf("unknown TLS profile %q", *task.TLSProfile)
+	}
+	if err := task.BodyCapture.Validate(); err != nil {
+		return err
+	}
+	if err := task.Redirects.Validate(); err != nil {
+		return err
+	}
+	if task.TriggerType == models.TriggerTypeWebhook {
+		for _, text := range requestTemplates(task) {
+			if err := templating.Validate(text); err != nil {
+				return err
+			}
+		}
+	}
+	return e.egress.ValidateTarget(task.URL, task.Proxy)
+}
+
+// requestTemplates lists the payload and header values of a webhook task,
+// which are rendered against the delivery before the request is sent, and
+// of a task run as a follow-up, rendered against the parent's result
+func requestTemplates(task *models.Task) []string {
+	var templates []string
+	if task.Payload != nil {
+		templates = append(templates, *task.Payload)
+	}
+	for _, value := range task.Headers {
+		templates = append(templates, value)
+	}
+	return templates
 }
 
-func NewHTTPExecutor() *HTTPExecutor {
-	return &HTTPExecutor{
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-			Transport: &http.Transport{
-				MaxIdleConns:        100,
-				MaxIdleConnsPerHost: 10,
-				IdleConnTimeout:     90 * time.Second,
-			},
-		},
+// renderRequest returns a copy of a task with its payload and headers
+// rendered against the webhook delivery or parent result it was run for
+func renderRequest(task *models.Task) (*models.Task, error) {
+	data := templating.NewData(task, time.Now())
+	rendered := *task
+
+	if task.Payload != nil {
+		payload, err := templating.Render(*task.Payload, data)
+		if err != nil {
+			return nil, err
+		}
+		rendered.Payload = &payload
 	}
+	rendered.Headers = make(models.Headers, len(task.Headers))
+	for key, value := range task.Headers {
+		text, err := templating.Render(value, data)
+		if err != nil {
+			return nil, err
+		}
+		rendered.Headers[key] = text
+	}
+	return &rendered, nil
 }
 
 func (e *HTTPExecutor) Execute(task *models.Task) *models.TaskResult {
+	return e.execute(task, e.timeout)
+}
+
+func (e *HTTPExecutor) execute(task *models.Task, timeout time.Duration) *models.TaskResult {
+	return e.executeWithJar(task, timeout, nil)
+}
+
+// executeWithJar runs the request with the given cookie jar. A nil jar means
+// the task's own persisted jar is used, if it has one.
+func (e *HTTPExecutor) executeWithJar(task *models.Task, timeout time.Duration, jar http.CookieJar) *models.TaskResult {
 	startTime := time.Now()
 
 	result := &models.TaskResult{
@@ -38,7 +183,7 @@ func (e *HTTPExecutor) Execute(task *models.Task) *models.TaskResult {
 		TaskID:          task.ID,
 		RunAt:           startTime,
 		Success:         false,
-		ResponseHeaders: make(models.Headers),
+		ResponseHeaders: make(models.MultiHeaders),
 		CreatedAt:       time.Now(),
 	}
 
@@ -50,45 +195,76 @@ func (e *HTTPExecutor) Execute(task *models.Task) *models.TaskResult {
 		return result
 	}
 
+	transport, err := e.transportFor(task)
+	if err != nil {
+		result.ErrorMessage = stringPtr(fmt.Sprintf("Failed to prepare request: %v", err))
+		result.DurationMs = int(time.Since(startTime).Milliseconds())
+		return result
+	}
+
+	if err := e.checkEgress(task, req); err != nil {
+		result.ErrorMessage = stringPtr(err.Error())
+		result.DurationMs = int(time.Since(startTime).Milliseconds())
+		return result
+	}
+
+	client := &http.Client{
+		Timeout:       timeout,
+		Transport:     transport,
+		CheckRedirect: e.checkRedirect(task, result),
+	}
+
+	if jar != nil {
+		client.Jar = jar
+	} else if task.CookieJar && e.cookies != nil {
+		persisted, err := e.loadJar(task.ID)
+		if err != nil {
+			result.ErrorMessage = stringPtr(fmt.Sprintf("Failed to prepare request: %v", err))
+			result.DurationMs = int(time.Since(startTime).Milliseconds())
+			return result
+		}
+		client.Jar = persisted
+		defer e.saveJar(task.ID, persisted)
+	}
+
 	// Execute request
-	resp, err := e.client.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
+		result.Request = snapshotRequest(req)
 		result.ErrorMessage = stringPtr(fmt.Sprintf("HTTP request failed: %v", err))
+		result.TransportFailed = !errors.Is(err, ErrEgressDenied) && !errors.As(err, new(*redirectRefused))
 		result.DurationMs = int(time.Since(startTime).Milliseconds())
 		return result
 	}
 	defer resp.Body.Close()
 
+	// resp.Request is the last request in any redirect chain
+	result.Request = snapshotRequest(resp.Request)
+
 	// Calculate duration
 	result.DurationMs = int(time.Since(startTime).Milliseconds())
 
 	// Set status code
 	result.StatusCode = &resp.StatusCode
 
-	// Determine success (2xx status codes are considered successful)
+	// Determine success (2xx status codes are considered successful, as are
+	// redirects the task asked not to follow)
 	result.Success = resp.StatusCode >= 200 && resp.StatusCode < 300
+	if task.Redirects != nil && task.Redirects.Mode == models.RedirectNone {
+		result.Success = result.Success || (resp.StatusCode >= 300 && resp.StatusCode < 400)
+	}
 
 	// Extract response headers
 	for key, values := range resp.Header {
-		if len(values) > 0 {
-			result.ResponseHeaders[key] = values[0] // Take first value if multiple
-		}
+		result.ResponseHeaders[key] = append([]string(nil), values...)
 	}
 
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
+	// Capture response body according to the task's policy
+	if err := e.captureBody(task, resp.Body, result); err != nil {
 		result.ErrorMessage = stringPtr(fmt.Sprintf("Failed to read response body: %v", err))
 		return result
 	}
 
-	// Store response body (limit size to prevent database issues)
-	bodyStr := string(body)
-	if len(bodyStr) > 10000 { // Limit to 10KB
-		bodyStr = bodyStr[:10000] + "... (truncated)"
-	}
-	result.ResponseBody = &bodyStr
-
 	// If request failed, add status code to error message
 	if !result.Success {
 		if result.ErrorMessage == nil {
@@ -101,7 +277,95 @@ func (e *HTTPExecutor) Execute(task *models.Task) *models.TaskResult {
 	return result
 }
 
+// captureBody reads at most maxResponseBytes of the body. The inline copy
+// is cut at the policy's head size on a UTF-8 boundary; in full mode the
+// body is also streamed to the blob store when one is configured.
+func (e *HTTPExecutor) captureBody(task *models.Task, body io.Reader, result *models.TaskResult) error {
+	mode := models.BodyCaptureHead
+	if task.BodyCapture != nil {
+		mode = task.BodyCapture.Mode
+	}
+	if mode == models.BodyCaptureNone {
+		return nil
+	}
+
+	limit := task.BodyCapture.Limit()
+	if mode == models.BodyCaptureFull && e.blobs == nil {
+		limit = int(e.maxResponseBytes)
+	}
+	head := &headBuffer{limit: limit}
+	limited := io.LimitReader(body, e.maxResponseBytes)
+
+	var err error
+	if mode == models.BodyCaptureFull && e.blobs != nil {
+		var digest string
+		digest, result.ResponseBodySize, err = e.blobs.Put(io.TeeReader(limited, head))
+		if err == nil {
+			result.ResponseBodyRef = &digest
+		}
+	} else {
+		result.ResponseBodySize, err = io.Copy(head, limited)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Anything left past the read guard means the body was cut short
+	if n, _ := body.Read(make([]byte, 1)); n > 0 {
+		result.ResponseBodyTruncated = true
+	}
+
+	inline := head.String()
+	if int64(len(inline)) < result.ResponseBodySize {
+		result.ResponseBodyTruncated = true
+	}
+	result.ResponseBody = &inline
+	return nil
+}
+
+// headBuffer keeps the first limit bytes written to it and discards the rest
+type headBuffer struct {
+	limit int
+	buf   []byte
+}
+
+func (h *headBuffer) Write(p []byte) (int, error) {
+	if room := h.limit - len(h.buf); room > 0 {
+		if len(p) < room {
+			room = len(p)
+		}
+		h.buf = append(h.buf, p[:room]...)
+	}
+	return len(p), nil
+}
+
+// String returns the kept bytes without a trailing partial UTF-8 sequence
+func (h *headBuffer) String() string {
+	end := len(h.buf)
+	for i := 1; i <= utf8.UTFMax && i <= len(h.buf); i++ {
+		b := h.buf[len(h.buf)-i]
+		if b < utf8.RuneSelf {
+			break
+		}
+		if utf8.RuneStart(b) {
+			if !utf8.FullRune(h.buf[len(h.buf)-i:]) {
+				end = len(h.buf) - i
+			}
+			break
+		}
+	}
+	return string(h.buf[:end])
+}
+
 func (e *HTTPExecutor) prepareRequest(task *models.Task) (*http.Request, error) {
+	if task.TriggerType == models.TriggerTypeWebhook || task.Parent != nil {
+		rendered, err := renderRequest(task)
+		if err != nil {
+			return nil, err
+		}
+		task = rendered
+	}
+
 	var body io.Reader
 
 	// Prepare request body if payload exists
@@ -133,6 +397,12 @@ func (e *HTTPExecutor) prepareRequest(task *models.Task) (*http.Request, error)
 		}
 	}
 
+	// Retries and re-fires of one occurrence share a key the destination
+	// can deduplicate on, unless the task sets its own
+	if task.Occurrence != nil && req.Header.Get(models.IdempotencyKeyHeader) == "" {
+		req.Header.Set(models.IdempotencyKeyHeader, models.OccurrenceKey(task.ID, *task.Occurrence))
+	}
+
 	// Set User-Agent if not provided
 	if req.Header.Get("User-Agent") == "" {
 		req.Header.Set("User-Agent", "TaskScheduler/1.0")
@@ -142,21 +412,139 @@ func (e *HTTPExecutor) prepareRequest(task *models.Task) (*http.Request, error)
 }
 
 func (e *HTTPExecutor) ExecuteWithTimeout(task *models.Task, timeout time.Duration) *models.TaskResult {
-	// Create a new client with custom timeout
-	client := &http.Client{
-		Timeout:   timeout,
-		Transport: e.client.Transport,
+	return e.execute(task, timeout)
+}
+
+// checkRedirect enforces the task's redirect policy and the egress policy on
+// every hop, recording each hop in the result
+func (e *HTTPExecutor) checkRedirect(task *models.Task, result *models.TaskResult) func(*http.Request, []*http.Request) error {
+	policy := models.RedirectPolicy{Mode: models.RedirectFollow}
+	if task.Redirects != nil {
+		policy = *task.Redirects
+	}
+	if policy.Max <= 0 {
+		policy.Max = models.DefaultMaxRedirects
 	}
 
-	originalClient := e.client
-	e.client = client
+	return func(req *http.Request, via []*http.Request) error {
+		previous := via[len(via)-1]
+		hop := models.RedirectHop{
+			URL:      redactURL(previous.URL),
+			Location: redactURL(req.URL),
+		}
+		if req.Response != nil {
+			hop.StatusCode = req.Response.StatusCode
+		}
+		result.RedirectChain = append(result.RedirectChain, hop)
 
-	result := e.Execute(task)
+		switch policy.Mode {
+		case models.RedirectNone:
+			return http.ErrUseLastResponse
+		case models.RedirectSameHost:
+			if !strings.EqualFold(req.URL.Host, via[0].URL.Host) {
+				return &redirectRefused{fmt.Errorf("redirect to another host (%s) is not allowed", req.URL.Host)}
+			}
+		}
 
-	// Restore original client
-	e.client = originalClient
+		if len(via) > policy.Max {
+			return &redirectRefused{fmt.Errorf("stopped after %d redirects", policy.Max)}
+		}
+		return e.checkEgress(task, req)
+	}
+}
 
-	return result
+// redirectRefused is a redirect the task's redirect policy stopped, which
+// says nothing about the destination's health
+type redirectRefused struct {
+	err error
+}
+
+func (r *redirectRefused) Error() string { return r.err.Error() }
+func (r *redirectRefused) Unwrap() error { return r.err }
+
+// checkEgress applies the egress policy to an outgoing request or redirect
+// hop. Without a proxy the dialer checks resolved addresses itself; with a
+// proxy the target is resolved here so the proxy cannot be used to reach a
+// denied range.
+func (e *HTTPExecutor) checkEgress(task *models.Task, req *http.Request) error {
+	if err := e.egress.CheckURL(req.URL); err != nil {
+		return err
+	}
+	if task.Proxy == nil || *task.Proxy == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), 5*time.Second)
+	defer cancel()
+	if err := e.egress.CheckResolved(ctx, req.URL.Hostname()); errors.Is(err, ErrEgressDenied) {
+		return err
+	}
+	return nil
+}
+
+// transportFor returns the pooled transport for the task's TLS profile and
+// proxy, creating it on first use. Creating one past the cap evicts the
+// least recently used transport and closes its idle connections.
+func (e *HTTPExecutor) transportFor(task *models.Task) (*http.Transport, error) {
+	profile := ""
+	if task.TLSProfile != nil {
+		profile = *task.TLSProfile
+	}
+	proxy := ""
+	if task.Proxy != nil {
+		proxy = *task.Proxy
+	}
+	key := profile + "|" + proxy
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if elem, exists := e.transports[key]; exists {
+		e.transportOrder.MoveToFront(elem)
+		return elem.Value.(*pooledTransport).transport, nil
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+
+	transport := &http.Transport{
+		DialContext:         e.egress.DialContext(dialer),
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
+	}
+
+	if proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		if err := e.egress.CheckProxy(proxyURL); err != nil {
+			return nil, err
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+		transport.DialContext = e.egress.ProxyDialContext(dialer, proxyURL)
+	}
+
+	if profile != "" {
+		tlsConfig, err := e.tlsProfiles.Config(profile)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	e.transports[key] = e.transportOrder.PushFront(&pooledTransport{key: key, transport: transport})
+	for e.transportOrder.Len() > maxPooledTransports {
+		oldest := e.transportOrder.Remove(e.transportOrder.Back()).(*pooledTransport)
+		delete(e.transports, oldest.key)
+		// Requests still using it finish normally; connections they hand
+		// back are closed by IdleConnTimeout
+		oldest.transport.CloseIdleConnections()
+	}
+	return transport, nil
 }
 
 // Helper function to create string pointer
@@ -0,0 +1,445 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"task-scheduler/internal/models"
+	"task-scheduler/internal/ratelimit"
+	"task-scheduler/internal/templating"
+)
+
+const (
+	maxHTTPSteps = 20
+
+	// stepBodyLimit is how much of each step's body is kept for extractors
+	// and conditions; the recorded copy is cut to the default head size
+	stepBodyLimit = 1 << 20
+)
+
+// HTTPStepsExecutor runs http_steps actions, sending each step through the
+// HTTPExecutor so steps get the same TLS, egress, redirect and capture
+// handling as single-request tasks. Steps share one cookie jar.
+type HTTPStepsExecutor struct {
+	http *HTTPExecutor
+
+	// The task-level limiter and breaker see no URL on http_steps tasks,
+	// so they are applied here to each step's host instead
+	ctx      context.Context
+	limiter  *ratelimit.HostLimiter
+	breakers *CircuitBreakers
+}
+
+func NewHTTPStepsExecutor(httpExecutor *HTTPExecutor) *HTTPStepsExecutor {
+	return &HTTPStepsExecutor{http: httpExecutor}
+}
+
+// EnableHostLimits applies the per-host rate limits and circuit breakers to
+// every step, keyed on the step's own URL. Either may be nil. Rate limit
+// waits end early when ctx is done.
+func (e *HTTPStepsExecutor) EnableHostLimits(ctx context.Context, limiter *ratelimit.HostLimiter, breakers *CircuitBreakers) {
+	e.ctx = ctx
+	e.limiter = limiter
+	e.breakers = breakers
+}
+
+// ValidateTask checks every step. Steps with a templated URL only get their
+// egress check when they run.
+func (e *HTTPStepsExecutor) ValidateTask(task *models.Task) error {
+	if task.ActionType != models.ActionTypeHTTPSteps {
+		return nil
+	}
+
+	action, err := decodeHTTPStepsAction(task)
+	if err != nil {
+		return err
+	}
+
+	names := make(map[string]bool, len(action.Steps))
+	for i, step := range action.Steps {
+		name := stepName(step, i)
+		if names[name] {
+			return fmt.Errorf("duplicate step name %q", name)
+		}
+		names[name] = true
+
+		if step.Method == "" || step.URL == "" {
+			return fmt.Errorf("step %s: method and url are required", name)
+		}
+
+		templates := []string{step.If, step.URL, step.Payload}
+		for _, value := range step.Headers {
+			templates = append(templates, value)
+		}
+		for _, text := range templates {
+			if err := templating.Validate(text); err != nil {
+				return fmt.Errorf("step %s: %w", name, err)
+			}
+		}
+
+		for variable, spec := range step.Extract {
+			if variable == "" {
+				return fmt.Errorf("step %s: extract variable names must not be empty", name)
+			}
+			if _, err := parseExtractor(spec); err != nil {
+				return fmt.Errorf("step %s: %w", name, err)
+			}
+		}
+
+		stepTask := newStepTask(task, name, step, step.URL, step.Headers, step.Payload)
+		if !strings.Contains(step.URL, "{{") {
+			if err := e.http.ValidateTask(stepTask); err != nil {
+				return fmt.Errorf("step %s: %w", name, err)
+			}
+			continue
+		}
+		if err := step.Redirects.Validate(); err != nil {
+			return fmt.Errorf("step %s: %w", name, err)
+		}
+		if step.TLSProfile != nil && *step.TLSProfile != "" && !e.http.tlsProfiles.Has(*step.TLSProfile) {
+			return fmt.Errorf("step %s: unknown TLS profile %q", name, *step.TLSProfile)
+		}
+	}
+	return nil
+}
+
+func (e *HTTPStepsExecutor) Execute(task *models.Task) *models.TaskResult {
+	return e.ExecuteWithTimeout(task, e.http.timeout)
+}
+
+// ExecuteWithTimeout runs the steps in order, stopping at the first failed
+// step unless it has continue_on_failure. The timeout covers the whole
+// sequence.
+func (e *HTTPStepsExecutor) ExecuteWithTimeout(task *models.Task, timeout time.Duration) *models.TaskResult {
+	startTime := time.Now()
+
+	result := &models.TaskResult{
+		ID:        uuid.New(),
+		TaskID:    task.ID,
+		RunAt:     startTime,
+		CreatedAt: startTime,
+	}
+
+	action, err := decodeHTTPStepsAction(task)
+	if err != nil {
+		result.ErrorMessage = stringPtr(fmt.Sprintf("Failed to prepare steps: %v", err))
+		return result
+	}
+	if timeout <= 0 {
+		timeout = e.http.timeout
+	}
+	deadline := startTime.Add(timeout)
+
+	jar, err := newTaskJar(nil)
+	if task.CookieJar && e.http.cookies != nil {
+		jar, err = e.http.loadJar(task.ID)
+		if err == nil {
+			defer e.http.saveJar(task.ID, jar)
+		}
+	}
+	if err != nil {
+		result.ErrorMessage = stringPtr(fmt.Sprintf("Failed to prepare steps: %v", err))
+		return result
+	}
+
+	data := templating.NewData(task, startTime)
+	result.Success = true
+	sent := false
+
+	for i, step := range action.Steps {
+		name := stepName(step, i)
+		stepResult := e.runStep(task, result, name, step, data, deadline, jar)
+		result.Steps = append(result.Steps, stepResult)
+
+		if stepResult.Skipped {
+			continue
+		}
+
+		// Kept back before anything went out, the whole run is treated like
+		// a single request kept back: deferred or short-circuited
+		if stepResult.Outcome != "" && !sent {
+			result.Success = false
+			result.Outcome = stepResult.Outcome
+			result.ErrorMessage = stringPtr(fmt.Sprintf("Step %s: %s", name, *stepResult.ErrorMessage))
+			break
+		}
+		if stepResult.Outcome == "" {
+			sent = true
+		}
+
+		// The combined result mirrors the last step that ran
+		result.StatusCode = stepResult.StatusCode
+		result.ResponseHeaders = stepResult.ResponseHeaders
+		result.ResponseBody = stepResult.ResponseBody
+		result.Request = stepResult.Request
+
+		if !stepResult.Success && !step.ContinueOnFailure {
+			result.Success = false
+			message := "unknown error"
+			if stepResult.ErrorMessage != nil {
+				message = *stepResult.ErrorMessage
+			}
+			result.ErrorMessage = stringPtr(fmt.Sprintf("Step %s failed: %s", name, message))
+			break
+		}
+	}
+
+	result.DurationMs = int(time.Since(startTime).Milliseconds())
+	return result
+}
+
+// runStep evaluates the step's condition, renders and sends its request and
+// runs its extractors, updating data for the steps that follow. Rate limit
+// waits and deferrals are added to result.
+func (e *HTTPStepsExecutor) runStep(task *models.Task, result *models.TaskResult, name string, step models.HTTPStep, data *templating.Data, deadline time.Time, jar http.CookieJar) models.StepResult {
+	stepResult := models.StepResult{Name: name}
+	fail := func(format string, args ...interface{}) models.StepResult {
+		stepResult.ErrorMessage = stringPtr(fmt.Sprintf(format, args...))
+		return stepResult
+	}
+
+	if step.If != "" {
+		condition, err := templating.Render(step.If, data)
+		if err != nil {
+			return fail("%v", err)
+		}
+		if strings.TrimSpace(condition) != "true" {
+			stepResult.Skipped = true
+			return stepResult
+		}
+	}
+
+	url, err := templating.Render(step.URL, data)
+	if err != nil {
+		return fail("%v", err)
+	}
+	headers := make(map[string]string, len(step.Headers))
+	for key, value := range step.Headers {
+		if headers[key], err = templating.Render(value, data); err != nil {
+			return fail("%v", err)
+		}
+	}
+	payload, err := templating.Render(step.Payload, data)
+	if err != nil {
+		return fail("%v", err)
+	}
+
+	remaining := time.Until(deadline)
+	if remaining <= 0 {
+		return fail("no time left before the task timeout")
+	}
+
+	host := stepHost(url)
+	if e.breakers != nil && host != "" && !e.breakers.Allow(host) {
+		stepResult.Outcome = models.OutcomeCircuitOpen
+		return fail("circuit for %s is open", host)
+	}
+	if e.limiter != nil && host != "" {
+		wait, ok := e.limiter.Reserve(host)
+		if !ok || wait >= remaining {
+			if e.breakers != nil {
+				e.breakers.Release(host)
+			}
+			stepResult.Outcome = models.OutcomeRateLimited
+			result.DeferFor = wait
+			return fail("rate limit for %s exceeded, deferred by %s", host, wait.Round(time.Millisecond))
+		}
+		if !sleepCtx(e.ctx, wait) {
+			if e.breakers != nil {
+				e.breakers.Release(host)
+			}
+			stepResult.Outcome = models.OutcomeRateLimited
+			result.DeferFor = wait
+			return fail("stopped while waiting on the rate limit for %s", host)
+		}
+		result.RateLimitWaitMs += int(wait.Milliseconds())
+		remaining -= wait
+	}
+
+	stepTask := newStepTask(task, name, step, url, headers, payload)
+	httpResult := e.http.executeWithJar(stepTask, remaining, jar)
+	if e.breakers != nil && host != "" {
+		e.breakers.Record(host, !isDestinationFailure(httpResult))
+	}
+
+	stepResult.Success = httpResult.Success
+	stepResult.StatusCode = httpResult.StatusCode
+	stepResult.DurationMs = httpResult.DurationMs
+	stepResult.Request = httpResult.Request
+	stepResult.ResponseHeaders = httpResult.ResponseHeaders
+	stepResult.ErrorMessage = httpResult.ErrorMessage
+
+	response := &templating.Response{Headers: httpResult.ResponseHeaders}
+	if httpResult.StatusCode != nil {
+		response.StatusCode = *httpResult.StatusCode
+	}
+	if httpResult.ResponseBody != nil {
+		response.Body = *httpResult.ResponseBody
+		recorded := &headBuffer{limit: models.DefaultBodyCaptureHeadBytes}
+		recorded.Write([]byte(response.Body))
+		stepResult.ResponseBody = stringPtr(recorded.String())
+	}
+	data.Prev = response
+
+	if !stepResult.Success {
+		return stepResult
+	}
+
+	for variable, spec := range step.Extract {
+		x, _ := parseExtractor(spec)
+		value, err := x.extract(response)
+		if err != nil {
+			stepResult.Success = false
+			return fail("extract %s: %v", variable, err)
+		}
+		data.Vars[variable] = value
+
+		if stepResult.Extracted == nil {
+			stepResult.Extracted = make(map[string]string)
+		}
+		if isSensitive(variable) {
+			value = redacted
+		}
+		stepResult.Extracted[variable] = value
+	}
+
+	return stepResult
+}
+
+func stepHost(rawURL string) string {
+	target, err := neturl.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return target.Hostname()
+}
+
+func newStepTask(task *models.Task, name string, step models.HTTPStep, url string, headers map[string]string, payload string) *models.Task {
+	stepTask := &models.Task{
+		ID:          task.ID,
+		Name:        task.Name + "/" + name,
+		ActionType:  models.ActionTypeHTTP,
+		Method:      step.Method,
+		URL:         url,
+		Headers:     headers,
+		TLSProfile:  step.TLSProfile,
+		Proxy:       step.Proxy,
+		Redirects:   step.Redirects,
+		BodyCapture: &models.BodyCapture{Mode: models.BodyCaptureHead, HeadBytes: stepBodyLimit},
+	}
+	if payload != "" {
+		stepTask.Payload = &payload
+	}
+	return stepTask
+}
+
+func stepName(step models.HTTPStep, index int) string {
+	if step.Name != "" {
+		return step.Name
+	}
+	return "step" + strconv.Itoa(index+1)
+}
+
+// extractor pulls one value out of a step's response
+type extractor struct {
+	kind string
+	arg  string
+	re   *regexp.Regexp
+}
+
+func parseExtractor(spec string) (extractor, error) {
+	kind, arg, _ := strings.Cut(spec, ":")
+	x := extractor{kind: kind, arg: arg}
+
+	switch kind {
+	case "status":
+		return x, nil
+	case "header", "json":
+		if arg == "" {
+			return x, fmt.Errorf("extractor %q needs an argument", spec)
+		}
+		return x, nil
+	case "regex":
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			return x, fmt.Errorf("extractor %q: %w", spec, err)
+		}
+		if re.NumSubexp() < 1 {
+			return x, fmt.Errorf("extractor %q needs a capture group", spec)
+		}
+		x.re = re
+		return x, nil
+	default:
+		return x, fmt.Errorf("unknown extractor %q; use status, header:, json: or regex:", spec)
+	}
+}
+
+func (x extractor) extract(response *templating.Response) (string, error) {
+	switch x.kind {
+	case "status":
+		return strconv.Itoa(response.StatusCode), nil
+
+	case "header":
+		value := response.Header(x.arg)
+		if value == "" {
+			return "", fmt.Errorf("header %s not present", x.arg)
+		}
+		return value, nil
+
+	case "regex":
+		match := x.re.FindStringSubmatch(response.Body)
+		if match == nil {
+			return "", fmt.Errorf("pattern did not match")
+		}
+		return match[1], nil
+
+	default:
+		var doc interface{}
+		if err := json.Unmarshal([]byte(response.Body), &doc); err != nil {
+			return "", fmt.Errorf("response is not JSON")
+		}
+		for _, key := range strings.Split(x.arg, ".") {
+			switch node := doc.(type) {
+			case map[string]interface{}:
+				value, ok := node[key]
+				if !ok {
+					return "", fmt.Errorf("%s not found", x.arg)
+				}
+				doc = value
+			case []interface{}:
+				index, err := strconv.Atoi(key)
+				if err != nil || index < 0 || index >= len(node) {
+					return "", fmt.Errorf("%s not found", x.arg)
+				}
+				doc = node[index]
+			default:
+				return "", fmt.Errorf("%s not found", x.arg)
+			}
+		}
+		if text, ok := doc.(string); ok {
+			return text, nil
+		}
+		encoded, err := json.Marshal(doc)
+		return string(encoded), err
+	}
+}
+
+func decodeHTTPStepsAction(task *models.Task) (*models.HTTPStepsAction, error) {
+	var action models.HTTPStepsAction
+	if err := task.DecodeActionConfig(&action); err != nil {
+		return nil, fmt.Errorf("invalid http_steps configuration: %w", err)
+	}
+	if len(action.Steps) == 0 || len(action.Steps) > maxHTTPSteps {
+		return nil, fmt.Errorf("http_steps needs between 1 and %d steps", maxHTTPSteps)
+	}
+	return &action, nil
+}
@@ -0,0 +1,107 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+
+	"task-scheduler/internal/models"
+	"task-scheduler/internal/ratelimit"
+)
+
+// RateLimitedExecutor throttles outbound requests per destination host
+// before handing them to the wrapped executor. Short waits are absorbed
+// in-line; runs that would wait longer than the limiter allows are returned
+// as deferred so the scheduler can re-run them later. In-line waits end
+// early when ctx is done, so shutdown does not wait them out.
+type RateLimitedExecutor struct {
+	ctx      context.Context
+	executor ExecutorInterface
+	limiter  *ratelimit.HostLimiter
+}
+
+func NewRateLimitedExecutor(ctx context.Context, executor ExecutorInterface, limiter *ratelimit.HostLimiter) *RateLimitedExecutor {
+	return &RateLimitedExecutor{
+		ctx:      ctx,
+		executor: executor,
+		limiter:  limiter,
+	}
+}
+
+func (r *RateLimitedExecutor) Execute(task *models.Task) *models.TaskResult {
+	return r.execute(task, func() *models.TaskResult {
+		return r.executor.Execute(task)
+	})
+}
+
+func (r *RateLimitedExecutor) ExecuteWithTimeout(task *models.Task, timeout time.Duration) *models.TaskResult {
+	return r.execute(task, func() *models.TaskResult {
+		return r.executor.ExecuteWithTimeout(task, timeout)
+	})
+}
+
+func (r *RateLimitedExecutor) execute(task *models.Task, run func() *models.TaskResult) *models.TaskResult {
+	host := destinationHost(task)
+	if r.limiter == nil || host == "" {
+		return run()
+	}
+
+	wait, ok := r.limiter.Reserve(host)
+	if !ok {
+		log.Printf("Rate limit for %s exceeded, deferring task %s by %s", host, task.ID, wait)
+		return deferredResult(task, wait, fmt.Sprintf("rate limit for %s exceeded, deferred by %s", host, wait.Round(time.Millisecond)))
+	}
+
+	if !sleepCtx(r.ctx, wait) {
+		return deferredResult(task, wait, fmt.Sprintf("stopped while waiting on the rate limit for %s", host))
+	}
+
+	result := run()
+	result.RateLimitWaitMs += int(wait.Milliseconds())
+	return result
+}
+
+// deferredResult is the result of a run the limiter kept from starting
+func deferredResult(task *models.Task, wait time.Duration, message string) *models.TaskResult {
+	now := time.Now()
+	return &models.TaskResult{
+		ID:           uuid.New(),
+		TaskID:       task.ID,
+		RunAt:        now,
+		Outcome:      models.OutcomeRateLimited,
+		ErrorMessage: &message,
+		DeferFor:     wait,
+		CreatedAt:    now,
+	}
+}
+
+// sleepCtx waits for d and reports whether it did; false means ctx was
+// done first
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func destinationHost(task *models.Task) string {
+	if task.URL == "" {
+		return ""
+	}
+	target, err := url.Parse(task.URL)
+	if err != nil {
+		return ""
+	}
+	return target.Hostname()
+}
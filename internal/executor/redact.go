@@ -0,0 +1,107 @@
+package executor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"task-scheduler/internal/models"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveNames are matched as substrings of lower-cased header and query
+// parameter names
+var sensitiveNames = []string{
+	"authorization",
+	"cookie",
+	"token",
+	"secret",
+	"password",
+	"passwd",
+	"api-key",
+	"apikey",
+	"api_key",
+	"signature",
+}
+
+func isSensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, sensitive := range sensitiveNames {
+		if strings.Contains(name, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshotRequest records the request as it went out on the wire, with
+// credentials replaced by a placeholder
+func snapshotRequest(req *http.Request) *models.RequestSnapshot {
+	body := requestBody(req)
+	snapshot := &models.RequestSnapshot{
+		Method:   req.Method,
+		URL:      redactURL(req.URL),
+		Headers:  make(models.MultiHeaders, len(req.Header)),
+		BodySize: len(body),
+	}
+
+	for key, values := range req.Header {
+		if isSensitive(key) {
+			snapshot.Headers[key] = []string{redacted}
+			continue
+		}
+		snapshot.Headers[key] = append([]string(nil), values...)
+	}
+
+	if len(body) > 0 {
+		sum := sha256.Sum256(body)
+		snapshot.BodySHA256 = hex.EncodeToString(sum[:])
+	}
+
+	return snapshot
+}
+
+// requestBody re-reads the body req was sent with. A redirect that turns
+// the request into a GET leaves it without one.
+func requestBody(req *http.Request) []byte {
+	if req.GetBody == nil || req.ContentLength == 0 {
+		return nil
+	}
+	rc, err := req.GetBody()
+	if err != nil {
+		return nil
+	}
+	defer rc.Close()
+	body, err := io.ReadAll(rc)
+	if err != nil {
+		return nil
+	}
+	return body
+}
+
+func redactURL(u *url.URL) string {
+	clean := *u
+	if clean.User != nil {
+		if _, hasPassword := clean.User.Password(); hasPassword {
+			clean.User = url.UserPassword(clean.User.Username(), redacted)
+		}
+	}
+
+	query := clean.Query()
+	changed := false
+	for key := range query {
+		if isSensitive(key) {
+			query[key] = []string{redacted}
+			changed = true
+		}
+	}
+	if changed {
+		clean.RawQuery = query.Encode()
+	}
+
+	return clean.String()
+}
@@ -0,0 +1,105 @@
+package executor
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"task-scheduler/internal/models"
+)
+
+// ValidateFunc checks a task's action configuration before it is saved
+type ValidateFunc func(task *models.Task) error
+
+type actionKind struct {
+	executor ExecutorInterface
+	validate ValidateFunc
+}
+
+// Registry maps each action type to the executor that runs it and the
+// validation hook CreateTask applies to it. Action kinds are registered once
+// at startup; the registry itself is an ExecutorInterface so it can sit at
+// the bottom of the execution pipeline.
+type Registry struct {
+	kinds map[models.ActionType]actionKind
+}
+
+func NewRegistry() *Registry {
+	return &Registry{kinds: make(map[models.ActionType]actionKind)}
+}
+
+// Register adds an action kind. validate may be nil when the kind needs no
+// checks beyond the request binding.
+func (r *Registry) Register(actionType models.ActionType, executor ExecutorInterface, validate ValidateFunc) error {
+	if actionType == "" {
+		return fmt.Errorf("action type is required")
+	}
+	if _, exists := r.kinds[actionType]; exists {
+		return fmt.Errorf("action type %q is already registered", actionType)
+	}
+	r.kinds[actionType] = actionKind{executor: executor, validate: validate}
+	return nil
+}
+
+// Types returns the registered action types in sorted order
+func (r *Registry) Types() []models.ActionType {
+	types := make([]models.ActionType, 0, len(r.kinds))
+	for actionType := range r.kinds {
+		types = append(types, actionType)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// ValidateTask rejects unregistered action types and runs the kind's hook
+func (r *Registry) ValidateTask(task *models.Task) error {
+	kind, err := r.kindFor(task)
+	if err != nil {
+		return err
+	}
+	if kind.validate == nil {
+		return nil
+	}
+	return kind.validate(task)
+}
+
+func (r *Registry) Execute(task *models.Task) *models.TaskResult {
+	kind, err := r.kindFor(task)
+	if err != nil {
+		return unsupportedActionResult(task, err)
+	}
+	return kind.executor.Execute(task)
+}
+
+func (r *Registry) ExecuteWithTimeout(task *models.Task, timeout time.Duration) *models.TaskResult {
+	kind, err := r.kindFor(task)
+	if err != nil {
+		return unsupportedActionResult(task, err)
+	}
+	return kind.executor.ExecuteWithTimeout(task, timeout)
+}
+
+func (r *Registry) kindFor(task *models.Task) (actionKind, error) {
+	actionType := task.ActionType
+	if actionType == "" {
+		actionType = models.ActionTypeHTTP
+	}
+	kind, ok := r.kinds[actionType]
+	if !ok {
+		return actionKind{}, fmt.Errorf("unsupported action type %q", actionType)
+	}
+	return kind, nil
+}
+
+func unsupportedActionResult(task *models.Task, err error) *models.TaskResult {
+	now := time.Now()
+	return &models.TaskResult{
+		ID:           uuid.New(),
+		TaskID:       task.ID,
+		RunAt:        now,
+		ErrorMessage: stringPtr(err.Error()),
+		CreatedAt:    now,
+	}
+}
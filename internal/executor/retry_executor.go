@@ -21,20 +21,33 @@ func NewRetryExecutor(executor ExecutorInterface, maxRetries int, retryDelay tim
     }
 }
 
+// retriesFor is how often a failed run of task is tried again. Commands
+// get one attempt: a maintenance script need not be safe to run twice.
+func (r *RetryExecutor) retriesFor(task *models.Task) int {
+    if task.ActionType == models.ActionTypeCommand {
+        return 0
+    }
+    return r.maxRetries
+}
+
 func (r *RetryExecutor) Execute(task *models.Task) *models.TaskResult {
     var lastResult *models.TaskResult
+    rateLimitWaitMs := 0
     
-    for attempt := 0; attempt <= r.maxRetries; attempt++ {
+    maxRetries := r.retriesFor(task)
+    for attempt := 0; attempt <= maxRetries; attempt++ {
         if attempt > 0 {
-            log.Printf("Retrying task %s (attempt %d/%d)", task.ID, attempt+1, r.maxRetries+1)
+            log.Printf("Retrying task %s (attempt %d/%d)", task.ID, attempt+1, maxRetries+1)
             time.Sleep(r.retryDelay)
         }
         
         result := r.executor.Execute(task)
+        rateLimitWaitMs += result.RateLimitWaitMs
+        result.RateLimitWaitMs = rateLimitWaitMs
         lastResult = result
         
-        // If successful, return immediately
-        if result.Success {
+        // If successful or short-circuited, return immediately
+        if result.Success || result.NotAttempted() {
             if attempt > 0 {
                 log.Printf("Task %s succeeded on attempt %d", task.ID, attempt+1)
             }
@@ -50,24 +63,28 @@ func (r *RetryExecutor) Execute(task *models.Task) *models.TaskResult {
     }
     
     // All attempts failed
-    log.Printf("Task %s failed after %d attempts", task.ID, r.maxRetries+1)
+    log.Printf("Task %s failed after %d attempts", task.ID, maxRetries+1)
     return lastResult
 }
 
 func (r *RetryExecutor) ExecuteWithTimeout(task *models.Task, timeout time.Duration) *models.TaskResult {
     var lastResult *models.TaskResult
+    rateLimitWaitMs := 0
     
-    for attempt := 0; attempt <= r.maxRetries; attempt++ {
+    maxRetries := r.retriesFor(task)
+    for attempt := 0; attempt <= maxRetries; attempt++ {
         if attempt > 0 {
-            log.Printf("Retrying task %s with timeout (attempt %d/%d)", task.ID, attempt+1, r.maxRetries+1)
+            log.Printf("Retrying task %s with timeout (attempt %d/%d)", task.ID, attempt+1, maxRetries+1)
             time.Sleep(r.retryDelay)
         }
         
         result := r.executor.ExecuteWithTimeout(task, timeout)
+        rateLimitWaitMs += result.RateLimitWaitMs
+        result.RateLimitWaitMs = rateLimitWaitMs
         lastResult = result
         
-        // If successful, return immediately
-        if result.Success {
+        // If successful or short-circuited, return immediately
+        if result.Success || result.NotAttempted() {
             if attempt > 0 {
                 log.Printf("Task %s succeeded on attempt %d", task.ID, attempt+1)
             }
@@ -83,6 +100,6 @@ func (r *RetryExecutor) ExecuteWithTimeout(task *models.Task, timeout time.Durat
     }
     
     // All attempts failed
-    log.Printf("Task %s failed after %d attempts", task.ID, r.maxRetries+1)
+    log.Printf("Task %s failed after %d attempts", task.ID, maxRetries+1)
     return lastResult
 }
@@ -0,0 +1,322 @@
+package executor
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	"task-scheduler/internal/models"
+	"task-scheduler/internal/templating"
+)
+
+const (
+	// SchedulerConnection names the scheduler's own database. sql actions may
+	// only use it when the connections file sets allow_scheduler_db.
+	SchedulerConnection = "scheduler"
+
+	defaultSQLTimeout = time.Minute
+	defaultSQLMaxRows = 20
+	maxSQLMaxRows     = 1000
+)
+
+// SQLConnection is an admin-configured database sql actions can target
+type SQLConnection struct {
+	Name string `json:"name"`
+	// Driver is a database/sql driver name; it defaults to pgx (Postgres)
+	Driver       string `json:"driver,omitempty"`
+	DSN          string `json:"dsn"`
+	MaxOpenConns int    `json:"max_open_conns,omitempty"`
+}
+
+// SQLConnectionsConfig is the on-disk format of SQL_CONNECTIONS_FILE
+type SQLConnectionsConfig struct {
+	Connections      []SQLConnection `json:"connections"`
+	AllowSchedulerDB bool            `json:"allow_scheduler_db"`
+}
+
+type sqlConnection struct {
+	db     *sql.DB
+	driver string
+}
+
+// SQLExecutor runs sql actions against named connections
+type SQLExecutor struct {
+	connections map[string]sqlConnection
+}
+
+// NewSQLExecutor opens the configured connections. schedulerDB is only
+// exposed as the "scheduler" connection when config.AllowSchedulerDB is set.
+func NewSQLExecutor(config SQLConnectionsConfig, schedulerDB *sql.DB) (*SQLExecutor, error) {
+	e := &SQLExecutor{connections: make(map[string]sqlConnection)}
+
+	for _, conn := range config.Connections {
+		if conn.Name == "" || conn.DSN == "" {
+			return nil, fmt.Errorf("sql connections need a name and a dsn")
+		}
+		if conn.Name == SchedulerConnection {
+			return nil, fmt.Errorf("sql connection name %q is reserved", SchedulerConnection)
+		}
+		if _, exists := e.connections[conn.Name]; exists {
+			return nil, fmt.Errorf("duplicate sql connection %q", conn.Name)
+		}
+
+		driver := conn.Driver
+		if driver == "" {
+			driver = "pgx"
+		}
+		db, err := sql.Open(driver, conn.DSN)
+		if err != nil {
+			return nil, fmt.Errorf("sql connection %q: %w", conn.Name, err)
+		}
+		if conn.MaxOpenConns > 0 {
+			db.SetMaxOpenConns(conn.MaxOpenConns)
+		}
+		e.connections[conn.Name] = sqlConnection{db: db, driver: driver}
+	}
+
+	if config.AllowSchedulerDB && schedulerDB != nil {
+		e.connections[SchedulerConnection] = sqlConnection{db: schedulerDB, driver: "pgx"}
+	}
+
+	return e, nil
+}
+
+// LoadSQLExecutor reads a SQLConnectionsConfig from a JSON file
+func LoadSQLExecutor(path string, schedulerDB *sql.DB) (*SQLExecutor, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sql connections: %w", err)
+	}
+
+	var config SQLConnectionsConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse sql connections: %w", err)
+	}
+
+	return NewSQLExecutor(config, schedulerDB)
+}
+
+// ValidateTask checks a sql action's configuration and connection
+func (e *SQLExecutor) ValidateTask(task *models.Task) error {
+	if task.ActionType != models.ActionTypeSQL {
+		return nil
+	}
+
+	action, err := decodeSQLAction(task)
+	if err != nil {
+		return err
+	}
+	if _, ok := e.connections[action.Connection]; !ok {
+		return fmt.Errorf("unknown sql connection %q", action.Connection)
+	}
+	if action.TimeoutSeconds < 0 || action.MaxRows < 0 || action.MaxRows > maxSQLMaxRows {
+		return fmt.Errorf("sql timeout_seconds must not be negative and max_rows must be between 0 and %d", maxSQLMaxRows)
+	}
+	// Rendering once catches unknown template fields as well as syntax errors
+	_, err = bindParams(action.Params, templating.NewData(task, time.Now()))
+	return err
+}
+
+func (e *SQLExecutor) Execute(task *models.Task) *models.TaskResult {
+	return e.ExecuteWithTimeout(task, 0)
+}
+
+// ExecuteWithTimeout runs the statement in its own transaction, bounded by
+// the timeout (or the action's own timeout, when shorter)
+func (e *SQLExecutor) ExecuteWithTimeout(task *models.Task, timeout time.Duration) *models.TaskResult {
+	startTime := time.Now()
+
+	result := &models.TaskResult{
+		ID:        uuid.New(),
+		TaskID:    task.ID,
+		RunAt:     startTime,
+		CreatedAt: startTime,
+	}
+
+	action, err := decodeSQLAction(task)
+	if err != nil {
+		result.ErrorMessage = stringPtr(fmt.Sprintf("Failed to prepare statement: %v", err))
+		return result
+	}
+	conn, ok := e.connections[action.Connection]
+	if !ok {
+		result.ErrorMessage = stringPtr(fmt.Sprintf("Unknown sql connection %q", action.Connection))
+		return result
+	}
+	args, err := bindParams(action.Params, templating.NewData(task, startTime))
+	if err != nil {
+		result.ErrorMessage = stringPtr(fmt.Sprintf("Failed to prepare statement: %v", err))
+		return result
+	}
+
+	if action.TimeoutSeconds > 0 {
+		actionTimeout := time.Duration(action.TimeoutSeconds) * time.Second
+		if timeout <= 0 || actionTimeout < timeout {
+			timeout = actionTimeout
+		}
+	}
+	if timeout <= 0 {
+		timeout = defaultSQLTimeout
+	}
+
+	maxRows := action.MaxRows
+	if maxRows <= 0 {
+		maxRows = defaultSQLMaxRows
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	rowCount, rows, err := runStatement(ctx, conn, action, args, timeout, maxRows)
+	result.DurationMs = int(time.Since(startTime).Milliseconds())
+
+	switch {
+	case err == nil:
+		result.Success = true
+		result.RowCount = &rowCount
+		result.Rows = rows
+	case ctx.Err() == context.DeadlineExceeded:
+		result.ErrorMessage = stringPtr(fmt.Sprintf("Statement timed out after %s", timeout))
+	default:
+		result.ErrorMessage = stringPtr(fmt.Sprintf("Statement failed: %v", err))
+	}
+
+	return result
+}
+
+func runStatement(ctx context.Context, conn sqlConnection, action *models.SQLAction, args []interface{}, timeout time.Duration, maxRows int) (int64, models.ResultRows, error) {
+	tx, err := conn.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: action.ReadOnly})
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tx.Rollback()
+
+	// The context deadline cancels the client side; statement_timeout also
+	// stops the server from carrying on with the work
+	if isPostgres(conn.driver) {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", timeout.Milliseconds())); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	var rowCount int64
+	var rows models.ResultRows
+	if returnsRows(action.Statement) {
+		rowCount, rows, err = queryRows(ctx, tx, action.Statement, args, maxRows)
+	} else {
+		var res sql.Result
+		res, err = tx.ExecContext(ctx, action.Statement, args...)
+		if err == nil {
+			rowCount, err = res.RowsAffected()
+		}
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return rowCount, rows, tx.Commit()
+}
+
+// queryRows counts every returned row but keeps only the first maxRows
+func queryRows(ctx context.Context, tx *sql.Tx, statement string, args []interface{}, maxRows int) (int64, models.ResultRows, error) {
+	rows, err := tx.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var count int64
+	kept := models.ResultRows{}
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+
+	for rows.Next() {
+		count++
+		if len(kept) >= maxRows {
+			continue
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return 0, nil, err
+		}
+		row := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			if b, ok := values[i].([]byte); ok {
+				row[column] = string(b)
+			} else {
+				row[column] = values[i]
+			}
+		}
+		kept = append(kept, row)
+	}
+	return count, kept, rows.Err()
+}
+
+var (
+	rowReturningStatement = regexp.MustCompile(`(?i)^\s*(select|with|values|table|show|explain)\b`)
+	returningClause       = regexp.MustCompile(`(?i)\breturning\b`)
+)
+
+// returnsRows reports whether statement produces a result set, in which case
+// it is run as a query; anything else reports its affected row count
+func returnsRows(statement string) bool {
+	return rowReturningStatement.MatchString(statement) || returningClause.MatchString(statement)
+}
+
+func isPostgres(driver string) bool {
+	return driver == "pgx" || driver == "postgres"
+}
+
+// bindParams renders string params as templates; JSON numbers are bound as
+// int64 when integral and other values are passed through
+func bindParams(params []interface{}, data *templating.Data) ([]interface{}, error) {
+	args := make([]interface{}, len(params))
+	for i, param := range params {
+		switch value := param.(type) {
+		case string:
+			rendered, err := templating.Render(value, data)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = rendered
+		case float64:
+			if value == math.Trunc(value) && math.Abs(value) < 1<<53 {
+				args[i] = int64(value)
+			} else {
+				args[i] = value
+			}
+		case nil, bool:
+			args[i] = value
+		default:
+			return nil, fmt.Errorf("param %d must be a string, number, boolean or null", i+1)
+		}
+	}
+	return args, nil
+}
+
+func decodeSQLAction(task *models.Task) (*models.SQLAction, error) {
+	var action models.SQLAction
+	if err := task.DecodeActionConfig(&action); err != nil {
+		return nil, fmt.Errorf("invalid sql configuration: %w", err)
+	}
+	if action.Connection == "" || strings.TrimSpace(action.Statement) == "" {
+		return nil, fmt.Errorf("sql connection and statement are required")
+	}
+	return &action, nil
+}
@@ -0,0 +1,142 @@
+package executor
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// TLSProfile describes the client certificate and trust settings a task can
+// reference by name when calling services that require mutual TLS or a
+// private certificate authority.
+type TLSProfile struct {
+	Name               string `json:"name"`
+	CertFile           string `json:"cert_file,omitempty"`
+	KeyFile            string `json:"key_file,omitempty"`
+	CAFile             string `json:"ca_file,omitempty"`
+	ServerName         string `json:"server_name,omitempty"`
+	MinVersion         string `json:"min_version,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+}
+
+// TLSProfiles holds the admin-configured set of TLS profiles
+type TLSProfiles struct {
+	profiles      map[string]TLSProfile
+	allowInsecure bool
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// NewTLSProfiles builds a profile set. Profiles asking for
+// insecure_skip_verify are only honoured when allowInsecure is true.
+func NewTLSProfiles(profiles []TLSProfile, allowInsecure bool) (*TLSProfiles, error) {
+	set := &TLSProfiles{
+		profiles:      make(map[string]TLSProfile, len(profiles)),
+		allowInsecure: allowInsecure,
+	}
+
+	for _, profile := range profiles {
+		if profile.Name == "" {
+			return nil, fmt.Errorf("TLS profile name is required")
+		}
+		if _, exists := set.profiles[profile.Name]; exists {
+			return nil, fmt.Errorf("duplicate TLS profile %q", profile.Name)
+		}
+		if _, err := set.build(profile); err != nil {
+			return nil, fmt.Errorf("TLS profile %q: %w", profile.Name, err)
+		}
+		set.profiles[profile.Name] = profile
+	}
+
+	return set, nil
+}
+
+// LoadTLSProfiles reads a JSON array of profiles from path
+func LoadTLSProfiles(path string, allowInsecure bool) (*TLSProfiles, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TLS profiles: %w", err)
+	}
+
+	var profiles []TLSProfile
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("failed to parse TLS profiles: %w", err)
+	}
+
+	return NewTLSProfiles(profiles, allowInsecure)
+}
+
+// Has reports whether a profile with the given name is configured
+func (p *TLSProfiles) Has(name string) bool {
+	if p == nil {
+		return false
+	}
+	_, ok := p.profiles[name]
+	return ok
+}
+
+// Config returns a fresh tls.Config for the named profile
+func (p *TLSProfiles) Config(name string) (*tls.Config, error) {
+	if p == nil {
+		return nil, fmt.Errorf("unknown TLS profile %q", name)
+	}
+	profile, ok := p.profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown TLS profile %q", name)
+	}
+	return p.build(profile)
+}
+
+func (p *TLSProfiles) build(profile TLSProfile) (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName: profile.ServerName,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if profile.MinVersion != "" {
+		version, ok := tlsVersions[profile.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("unsupported min_version %q", profile.MinVersion)
+		}
+		config.MinVersion = version
+	}
+
+	if profile.InsecureSkipVerify {
+		if !p.allowInsecure {
+			return nil, fmt.Errorf("insecure_skip_verify is disabled by the administrator")
+		}
+		config.InsecureSkipVerify = true
+	}
+
+	if profile.CertFile != "" || profile.KeyFile != "" {
+		if profile.CertFile == "" || profile.KeyFile == "" {
+			return nil, fmt.Errorf("cert_file and key_file must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(profile.CertFile, profile.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	if profile.CAFile != "" {
+		pem, err := os.ReadFile(profile.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", profile.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	return config, nil
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"task-scheduler/internal/models"
+)
+
+// MaintenanceController is the part of the scheduler the admin endpoints use
+type MaintenanceController interface {
+	Status() models.SchedulerStatus
+	Pause(onFire models.PausedFireAction, reason *string) (models.SchedulerStatus, error)
+	Drain(reason *string) (models.SchedulerStatus, error)
+	Resume() (models.SchedulerStatus, error)
+}
+
+type AdminHandler struct {
+	scheduler MaintenanceController
+}
+
+func NewAdminHandler(scheduler MaintenanceController) *AdminHandler {
+	return &AdminHandler{scheduler: scheduler}
+}
+
+// GetSchedulerMode godoc
+// @Summary Get the scheduler's maintenance mode
+// @Description Get the maintenance mode with the number of runs in flight and queued
+// @Tags admin
+// @Produce json
+// @Success 200 {object} models.SchedulerStatus
+// @Router /admin/scheduler [get]
+func (h *AdminHandler) GetSchedulerMode(c *gin.Context) {
+	c.JSON(http.StatusOK, h.scheduler.Status())
+}
+
+// PauseScheduler godoc
+// @Summary Pause the scheduler
+// @Description Stop every run from starting; fires are recorded and skipped, or queued to run on resume
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body models.PauseSchedulerRequest false "Pause options"
+// @Success 200 {object} models.SchedulerStatus
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/scheduler/pause [post]
+func (h *AdminHandler) PauseScheduler(c *gin.Context) {
+	var req models.PauseSchedulerRequest
+	if !bindOptionalJSON(c, &req) {
+		return
+	}
+
+	status, err := h.scheduler.Pause(req.OnFire, req.Reason)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to pause scheduler"})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// DrainScheduler godoc
+// @Summary Drain the scheduler
+// @Description Let runs in flight finish and skip new ones; the status reports drained once none are left
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body models.DrainSchedulerRequest false "Drain options"
+// @Success 200 {object} models.SchedulerStatus
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/scheduler/drain [post]
+func (h *AdminHandler) DrainScheduler(c *gin.Context) {
+	var req models.DrainSchedulerRequest
+	if !bindOptionalJSON(c, &req) {
+		return
+	}
+
+	status, err := h.scheduler.Drain(req.Reason)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to drain scheduler"})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// ResumeScheduler godoc
+// @Summary Resume the scheduler
+// @Description Return to normal operation and run the fires queued while paused
+// @Tags admin
+// @Produce json
+// @Success 200 {object} models.SchedulerStatus
+// @Failure 500 {object} map[string]string
+// @Router /admin/scheduler/resume [post]
+func (h *AdminHandler) ResumeScheduler(c *gin.Context) {
+	status, err := h.scheduler.Resume()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resume scheduler"})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// bindOptionalJSON binds a request body that may be left out entirely
+func bindOptionalJSON(c *gin.Context, req interface{}) bool {
+	err := c.ShouldBindJSON(req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"task-scheduler/internal/calendar"
+	"task-scheduler/internal/models"
+	"task-scheduler/internal/repository"
+)
+
+// maxICSBytes caps the size of an imported iCalendar file
+const maxICSBytes = 5 << 20
+
+type CalendarHandler struct {
+	calendarRepo *repository.CalendarRepository
+	taskRepo     *repository.TaskRepository
+}
+
+func NewCalendarHandler(calendarRepo *repository.CalendarRepository, taskRepo *repository.TaskRepository) *CalendarHandler {
+	return &CalendarHandler{
+		calendarRepo: calendarRepo,
+		taskRepo:     taskRepo,
+	}
+}
+
+// CreateCalendar godoc
+// @Summary Create a calendar
+// @Description Create a calendar of recurring blackout windows, dates and periods
+// @Tags calendars
+// @Accept json
+// @Produce json
+// @Param calendar body models.CalendarRequest true "Calendar creation request"
+// @Success 201 {object} models.Calendar
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /calendars [post]
+func (h *CalendarHandler) CreateCalendar(c *gin.Context) {
+	var req models.CalendarRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cal := &models.Calendar{
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	applyCalendarRequest(cal, &req)
+	if err := calendar.Validate(cal); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.calendarRepo.Create(cal); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create calendar"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, cal)
+}
+
+// GetCalendars godoc
+// @Summary List calendars
+// @Description Get a paginated list of calendars
+// @Tags calendars
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]string
+// @Router /calendars [get]
+func (h *CalendarHandler) GetCalendars(c *gin.Context) {
+	page, limit, offset := pagination(c)
+
+	calendars, total, err := h.calendarRepo.List(limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch calendars"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"calendars": calendars,
+		"pagination": gin.H{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// GetCalendar godoc
+// @Summary Get calendar by ID
+// @Description Get a calendar's blackout windows, dates and periods
+// @Tags calendars
+// @Produce json
+// @Param id path string true "Calendar ID"
+// @Success 200 {object} models.Calendar
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /calendars/{id} [get]
+func (h *CalendarHandler) GetCalendar(c *gin.Context) {
+	cal, ok := h.lookupCalendar(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, cal)
+}
+
+// UpdateCalendar godoc
+// @Summary Replace a calendar
+// @Description Replace a calendar's name, time zone and blackouts
+// @Tags calendars
+// @Accept json
+// @Produce json
+// @Param id path string true "Calendar ID"
+// @Param calendar body models.CalendarRequest true "Calendar"
+// @Success 200 {object} models.Calendar
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /calendars/{id} [put]
+func (h *CalendarHandler) UpdateCalendar(c *gin.Context) {
+	cal, ok := h.lookupCalendar(c)
+	if !ok {
+		return
+	}
+
+	var req models.CalendarRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	applyCalendarRequest(cal, &req)
+	if err := calendar.Validate(cal); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.calendarRepo.Update(cal); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update calendar"})
+		return
+	}
+
+	c.JSON(http.StatusOK, cal)
+}
+
+// DeleteCalendar godoc
+// @Summary Delete a calendar
+// @Description Delete a calendar that no task is attached to
+// @Tags calendars
+// @Produce json
+// @Param id path string true "Calendar ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /calendars/{id} [delete]
+func (h *CalendarHandler) DeleteCalendar(c *gin.Context) {
+	cal, ok := h.lookupCalendar(c)
+	if !ok {
+		return
+	}
+
+	inUse, err := h.taskRepo.CountByCalendar(cal.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete calendar"})
+		return
+	}
+	if inUse > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Calendar is attached to tasks", "tasks": inUse})
+		return
+	}
+
+	if err := h.calendarRepo.Delete(cal.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete calendar"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Calendar deleted successfully"})
+}
+
+// ImportCalendar godoc
+// @Summary Import an iCalendar file
+// @Description Add the events in an .ics file to a calendar: all-day events become blackout dates and timed events become periods. Recurring events are skipped.
+// @Tags calendars
+// @Accept text/calendar
+// @Produce json
+// @Param id path string true "Calendar ID"
+// @Param replace query bool false "Replace the calendar's dates and periods instead of adding to them"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /calendars/{id}/import [post]
+func (h *CalendarHandler) ImportCalendar(c *gin.Context) {
+	cal, ok := h.lookupCalendar(c)
+	if !ok {
+		return
+	}
+
+	imported, err := calendar.ParseICS(http.MaxBytesReader(c.Writer, c.Request.Body, maxICSBytes))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.Query("replace") == "true" {
+		cal.Dates = nil
+		cal.Periods = nil
+	}
+	cal.Dates = append(cal.Dates, imported.Dates...)
+	cal.Periods = append(cal.Periods, imported.Periods...)
+
+	if err := h.calendarRepo.Update(cal); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update calendar"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"calendar": cal,
+		"imported": gin.H{
+			"dates":   len(imported.Dates),
+			"periods": len(imported.Periods),
+			"skipped": imported.Skipped,
+		},
+	})
+}
+
+// CheckCalendar godoc
+// @Summary Check a time against a calendar
+// @Description Report whether a time falls inside one of the calendar's blackouts and when it ends
+// @Tags calendars
+// @Produce json
+// @Param id path string true "Calendar ID"
+// @Param at query string false "RFC 3339 time, defaults to now"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /calendars/{id}/check [get]
+func (h *CalendarHandler) CheckCalendar(c *gin.Context) {
+	cal, ok := h.lookupCalendar(c)
+	if !ok {
+		return
+	}
+
+	at := time.Now()
+	if value := c.Query("at"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "at must be an RFC 3339 time"})
+			return
+		}
+		at = parsed
+	}
+
+	blackout, blocked := calendar.Check(cal, at)
+	if !blocked {
+		c.JSON(http.StatusOK, gin.H{"at": at, "blackout": false})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"at":       at,
+		"blackout": true,
+		"reason":   blackout.Reason,
+		"until":    blackout.Until,
+	})
+}
+
+func (h *CalendarHandler) lookupCalendar(c *gin.Context) (*models.Calendar, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid calendar ID"})
+		return nil, false
+	}
+
+	cal, err := h.calendarRepo.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar not found"})
+		return nil, false
+	}
+	return cal, true
+}
+
+func applyCalendarRequest(cal *models.Calendar, req *models.CalendarRequest) {
+	cal.Name = req.Name
+	cal.Timezone = req.Timezone
+	if cal.Timezone == "" {
+		cal.Timezone = "UTC"
+	}
+	cal.Windows = req.Windows
+	cal.Dates = req.Dates
+	cal.Periods = req.Periods
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"task-scheduler/internal/executor"
+)
+
+type CircuitHandler struct {
+	breakers *executor.CircuitBreakers
+}
+
+func NewCircuitHandler(breakers *executor.CircuitBreakers) *CircuitHandler {
+	return &CircuitHandler{breakers: breakers}
+}
+
+// GetCircuits godoc
+// @Summary Get circuit breaker states
+// @Description Get the circuit breaker state for every destination host seen so far
+// @Tags circuits
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /circuits [get]
+func (h *CircuitHandler) GetCircuits(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"circuits": h.breakers.Snapshot()})
+}
@@ -1,21 +1,25 @@
 package handlers
 
 import (
+    "errors"
+    "io"
     "net/http"
     "strconv"
 
     "github.com/gin-gonic/gin"
     "github.com/google/uuid"
 
+    "task-scheduler/internal/blobstore"
     "task-scheduler/internal/repository"
 )
 
 type ResultHandler struct {
     resultRepo *repository.ResultRepository
+    blobs      *blobstore.Store
 }
 
-func NewResultHandler(resultRepo *repository.ResultRepository) *ResultHandler {
-    return &ResultHandler{resultRepo: resultRepo}
+func NewResultHandler(resultRepo *repository.ResultRepository, blobs *blobstore.Store) *ResultHandler {
+    return &ResultHandler{resultRepo: resultRepo, blobs: blobs}
 }
 
 // GetResults godoc
@@ -73,3 +77,60 @@ func (h *ResultHandler) GetResults(c *gin.Context) {
         },
     })
 }
+
+// GetResultBody godoc
+// @Summary Download a result's response body
+// @Description Stream the original response body captured for a result. Bodies captured in full mode come from the blob store; otherwise the inline copy is returned.
+// @Tags results
+// @Produce octet-stream
+// @Param id path string true "Result ID"
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /results/{id}/body [get]
+func (h *ResultHandler) GetResultBody(c *gin.Context) {
+    id, err := uuid.Parse(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid result ID"})
+        return
+    }
+
+    result, err := h.resultRepo.GetByID(id)
+    if err != nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "Result not found"})
+        return
+    }
+
+    contentType := result.ResponseHeaders.Get("Content-Type")
+    if contentType == "" {
+        contentType = "application/octet-stream"
+    }
+
+    if result.ResponseBodyRef != nil && h.blobs != nil {
+        body, err := h.blobs.Open(*result.ResponseBodyRef)
+        if errors.Is(err, blobstore.ErrNotFound) {
+            c.JSON(http.StatusNotFound, gin.H{"error": "Response body not found"})
+            return
+        }
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read response body"})
+            return
+        }
+        defer body.Close()
+
+        c.Header("Content-Type", contentType)
+        c.Header("Content-Length", strconv.FormatInt(result.ResponseBodySize, 10))
+        c.Status(http.StatusOK)
+        io.Copy(c.Writer, body)
+        return
+    }
+
+    if result.ResponseBody == nil {
+        c.JSON(http.StatusNotFound, gin.H{"error": "No response body was captured"})
+        return
+    }
+
+    c.Header("X-Body-Truncated", strconv.FormatBool(result.ResponseBodyTruncated))
+    c.Data(http.StatusOK, contentType, []byte(*result.ResponseBody))
+}
@@ -1,32 +1,67 @@
 package handlers
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
 
+	"task-scheduler/internal/cronspec"
 	"task-scheduler/internal/models"
 	"task-scheduler/internal/repository"
+	"task-scheduler/internal/templating"
 )
 
+// TaskValidator checks a task's action configuration before it is stored
+type TaskValidator interface {
+	ValidateTask(task *models.Task) error
+}
+
+// TaskControl is the part of the scheduler the pause, resume, cancel and
+// execute endpoints use
+type TaskControl interface {
+	ScheduleTask(task *models.Task) error
+	UnscheduleTask(taskID uuid.UUID)
+	RunTaskNow(task *models.Task)
+}
+
 type TaskHandler struct {
-	taskRepo   *repository.TaskRepository
-	resultRepo *repository.ResultRepository
+	taskRepo     *repository.TaskRepository
+	resultRepo   *repository.ResultRepository
+	calendarRepo *repository.CalendarRepository
+	scheduler    TaskControl
+	validators   []TaskValidator
 }
 
-func NewTaskHandler(taskRepo *repository.TaskRepository, resultRepo *repository.ResultRepository) *TaskHandler {
+func NewTaskHandler(taskRepo *repository.TaskRepository, resultRepo *repository.ResultRepository, validators ...TaskValidator) *TaskHandler {
 	return &TaskHandler{
 		taskRepo:   taskRepo,
 		resultRepo: resultRepo,
+		validators: validators,
 	}
 }
 
+// EnableCalendars lets tasks be attached to calendars stored in repo
+func (h *TaskHandler) EnableCalendars(repo *repository.CalendarRepository) {
+	h.calendarRepo = repo
+}
+
+// EnableScheduling lets pause, resume and cancel tell the running
+// scheduler about the task straight away rather than at its next poll, and
+// lets execute run tasks
+func (h *TaskHandler) EnableScheduling(scheduler TaskControl) {
+	h.scheduler = scheduler
+}
+
 // CreateTask godoc
 // @Summary Create a new task
 // @Description Create a new scheduled task with trigger and action configuration
@@ -51,8 +86,16 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 		return
 	}
 
+	if err := req.Action.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Get trigger value based on type
 	triggerValue := req.Trigger.GetTriggerValue()
+	if req.Trigger.Type == models.TriggerTypeWebhook {
+		triggerValue = newWebhookToken()
+	}
 	if triggerValue == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trigger configuration"})
 		return
@@ -62,19 +105,31 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 		Name:         req.Name,
 		TriggerType:  req.Trigger.Type,
 		TriggerValue: triggerValue,
+		ActionType:   req.Action.GetActionType(),
 		Method:       req.Action.Method,
 		URL:          req.Action.URL,
 		Headers:      req.Action.Headers,
+		TLSProfile:   req.Action.TLSProfile,
+		Proxy:        req.Action.Proxy,
+		BodyCapture:  req.Action.BodyCapture,
+		Redirects:    req.Action.Redirects,
+		CookieJar:    req.Action.CookieJar,
+		Webhook:      req.Trigger.Webhook,
+		Coalesce:     req.Trigger.Coalesce,
 		Status:       models.TaskStatusScheduled,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}
 
+	// The ID is assigned up front because H tokens and jitter hash it
+	task.ID = uuid.New()
+	task.JitterSeconds = req.Trigger.JitterSeconds
+
 	// Calculate next run time
 	if req.Trigger.Type == models.TriggerTypeOneOff && req.Trigger.DateTime != nil {
 		task.NextRun = req.Trigger.DateTime
 	} else if req.Trigger.Type == models.TriggerTypeCron && req.Trigger.Cron != nil {
-		if nextRun, err := h.calculateNextCronRun(*req.Trigger.Cron); err == nil {
+		if nextRun, err := h.calculateNextCronRun(task); err == nil {
 			task.NextRun = &nextRun
 		}
 	}
@@ -86,6 +141,29 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 		task.Payload = &payloadStr
 	}
 
+	if req.Action.GetActionType() != models.ActionTypeHTTP {
+		task.ActionConfig = req.Action.Config
+	}
+
+	for _, followUps := range [][]models.FollowUp{req.OnSuccess, req.OnFailure} {
+		if err := h.validateFollowUps(followUps); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	task.OnSuccess = req.OnSuccess
+	task.OnFailure = req.OnFailure
+
+	if err := h.applyCalendar(task, req.Calendar); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.validateTask(task); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	if err := h.taskRepo.Create(task); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create task"})
 		return
@@ -174,6 +252,7 @@ func (h *TaskHandler) GetTask(c *gin.Context) {
 // @Success 200 {object} models.Task
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /tasks/{id} [put]
 func (h *TaskHandler) UpdateTask(c *gin.Context) {
@@ -189,6 +268,10 @@ func (h *TaskHandler) UpdateTask(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
 		return
 	}
+	if task.Status.Terminal() {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("cannot edit a task that is %s", task.Status)})
+		return
+	}
 
 	var req models.UpdateTaskRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -207,22 +290,48 @@ func (h *TaskHandler) UpdateTask(c *gin.Context) {
 			return
 		}
 
+		// A webhook task keeps its token when its webhook settings change
+		triggerValue := req.Trigger.GetTriggerValue()
+		if req.Trigger.Type == models.TriggerTypeWebhook {
+			triggerValue = task.TriggerValue
+			if task.TriggerType != models.TriggerTypeWebhook {
+				triggerValue = newWebhookToken()
+			}
+		}
 		task.TriggerType = req.Trigger.Type
-		task.TriggerValue = req.Trigger.GetTriggerValue()
+		task.TriggerValue = triggerValue
+		task.Webhook = req.Trigger.Webhook
+		task.Coalesce = req.Trigger.Coalesce
+		task.JitterSeconds = req.Trigger.JitterSeconds
 
 		if req.Trigger.Type == models.TriggerTypeOneOff && req.Trigger.DateTime != nil {
 			task.NextRun = req.Trigger.DateTime
 		} else if req.Trigger.Type == models.TriggerTypeCron && req.Trigger.Cron != nil {
-			if nextRun, err := h.calculateNextCronRun(*req.Trigger.Cron); err == nil {
+			if nextRun, err := h.calculateNextCronRun(task); err == nil {
 				task.NextRun = &nextRun
 			}
 		}
 	}
 
 	if req.Action != nil {
+		if err := req.Action.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		task.ActionType = req.Action.GetActionType()
+		task.ActionConfig = nil
+		if req.Action.GetActionType() != models.ActionTypeHTTP {
+			task.ActionConfig = req.Action.Config
+		}
 		task.Method = req.Action.Method
 		task.URL = req.Action.URL
 		task.Headers = req.Action.Headers
+		task.TLSProfile = req.Action.TLSProfile
+		task.Proxy = req.Action.Proxy
+		task.BodyCapture = req.Action.BodyCapture
+		task.Redirects = req.Action.Redirects
+		task.CookieJar = req.Action.CookieJar
 
 		if req.Action.Payload != nil {
 			payloadBytes, _ := json.Marshal(req.Action.Payload)
@@ -233,11 +342,46 @@ func (h *TaskHandler) UpdateTask(c *gin.Context) {
 		}
 	}
 
-	if err := h.taskRepo.Update(task); err != nil {
+	if req.OnSuccess != nil {
+		if err := h.validateFollowUps(*req.OnSuccess); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		task.OnSuccess = *req.OnSuccess
+	}
+	if req.OnFailure != nil {
+		if err := h.validateFollowUps(*req.OnFailure); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		task.OnFailure = *req.OnFailure
+	}
+
+	if err := h.applyCalendar(task, req.Calendar); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.validateTask(task); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Status only moves through the state machine, so it is left as is
+	updated, err := h.taskRepo.UpdateDefinition(task, req.Trigger != nil)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update task"})
 		return
 	}
+	if !updated {
+		c.JSON(http.StatusConflict, gin.H{"error": "task was completed or cancelled while being edited"})
+		return
+	}
 
+	// Return the status and next_run as they are now
+	if current, err := h.taskRepo.GetByID(id); err == nil {
+		task = current
+	}
 	c.JSON(http.StatusOK, task)
 }
 
@@ -250,6 +394,7 @@ func (h *TaskHandler) UpdateTask(c *gin.Context) {
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /tasks/{id} [delete]
 func (h *TaskHandler) DeleteTask(c *gin.Context) {
@@ -260,16 +405,11 @@ func (h *TaskHandler) DeleteTask(c *gin.Context) {
 		return
 	}
 
-	// Check if task exists
-	_, err = h.taskRepo.GetByID(id)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+	if _, ok := h.transitionTask(c, id, models.TaskEventCancel); !ok {
 		return
 	}
-
-	if err := h.taskRepo.Delete(id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel task"})
-		return
+	if h.scheduler != nil {
+		h.scheduler.UnscheduleTask(id)
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Task cancelled successfully"})
@@ -333,6 +473,15 @@ func (h *TaskHandler) GetTaskResults(c *gin.Context) {
 }
 
 func (h *TaskHandler) validateTrigger(trigger *models.CreateTaskTrigger) error {
+	if err := trigger.Coalesce.Validate(); err != nil {
+		return err
+	}
+	if trigger.JitterSeconds < 0 || time.Duration(trigger.JitterSeconds)*time.Second > cronspec.MaxJitter {
+		return fmt.Errorf("jitter_seconds must be between 0 and %d", int(cronspec.MaxJitter.Seconds()))
+	}
+	if trigger.JitterSeconds > 0 && trigger.Type != models.TriggerTypeCron {
+		return fmt.Errorf("jitter_seconds only applies to cron triggers")
+	}
 	if trigger.Type == models.TriggerTypeOneOff {
 		if trigger.DateTime == nil {
 			return fmt.Errorf("datetime is required for one-off triggers")
@@ -344,32 +493,197 @@ func (h *TaskHandler) validateTrigger(trigger *models.CreateTaskTrigger) error {
 		if trigger.Cron == nil || *trigger.Cron == "" {
 			return fmt.Errorf("cron expression is required for cron triggers")
 		}
-		if _, err := cron.ParseStandard(*trigger.Cron); err != nil {
+		if err := cronspec.Validate(*trigger.Cron); err != nil {
 			return fmt.Errorf("invalid cron expression: %v", err)
 		}
 	}
 	return nil
 }
 
-func (h *TaskHandler) calculateNextCronRun(cronExpr string) (time.Time, error) {
-	parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-	schedule, err := parser.Parse(cronExpr)
+// newWebhookToken returns the secret path segment of a webhook task's URL
+func newWebhookToken() string {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		panic(fmt.Sprintf("crypto/rand failed: %v", err))
+	}
+	return hex.EncodeToString(token)
+}
+
+func (h *TaskHandler) validateTask(task *models.Task) error {
+	for _, validator := range h.validators {
+		if err := validator.ValidateTask(task); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateFollowUps checks that follow-up tasks exist and that their
+// templates and callbacks parse. Callbacks with a templated URL only get
+// their egress check when they run.
+func (h *TaskHandler) validateFollowUps(followUps []models.FollowUp) error {
+	for _, followUp := range followUps {
+		if err := followUp.Validate(); err != nil {
+			return err
+		}
+
+		if followUp.TaskID != nil {
+			target, err := h.taskRepo.GetByID(*followUp.TaskID)
+			if err != nil {
+				return fmt.Errorf("follow-up task %s not found", *followUp.TaskID)
+			}
+			// An HTTP follow-up's payload and headers are rendered against
+			// the parent's result
+			if target.IsHTTP() {
+				if err := validateRequestTemplates(target); err != nil {
+					return fmt.Errorf("follow-up task %s: %w", target.ID, err)
+				}
+			}
+			continue
+		}
+
+		callback := followUp.Callback
+		templates := []string{callback.URL, callback.Payload}
+		for _, value := range callback.Headers {
+			templates = append(templates, value)
+		}
+		for _, text := range templates {
+			if err := templating.Validate(text); err != nil {
+				return fmt.Errorf("follow-up callback: %w", err)
+			}
+		}
+
+		if !strings.Contains(callback.URL, "{{") {
+			callbackTask := &models.Task{ActionType: models.ActionTypeHTTP, Method: callback.Method, URL: callback.URL}
+			if err := h.validateTask(callbackTask); err != nil {
+				return fmt.Errorf("follow-up callback: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+func validateRequestTemplates(task *models.Task) error {
+	templates := make([]string, 0, len(task.Headers)+1)
+	if task.Payload != nil {
+		templates = append(templates, *task.Payload)
+	}
+	for _, value := range task.Headers {
+		templates = append(templates, value)
+	}
+	for _, text := range templates {
+		if err := templating.Validate(text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyCalendar attaches the requested calendar to task; a nil request
+// leaves the task's calendar alone and a null ID detaches it
+func (h *TaskHandler) applyCalendar(task *models.Task, req *models.TaskCalendar) error {
+	if req == nil {
+		return nil
+	}
+	if req.ID == nil {
+		task.CalendarID = nil
+		task.OnBlackout = ""
+		return nil
+	}
+
+	switch req.OnBlackout {
+	case "", models.BlackoutSkip, models.BlackoutDefer:
+	default:
+		return fmt.Errorf("on_blackout must be %q or %q", models.BlackoutSkip, models.BlackoutDefer)
+	}
+	if h.calendarRepo == nil {
+		return fmt.Errorf("calendars are not enabled")
+	}
+	if _, err := h.calendarRepo.GetByID(*req.ID); err != nil {
+		return fmt.Errorf("calendar %s not found", *req.ID)
+	}
+
+	task.CalendarID = req.ID
+	task.OnBlackout = req.OnBlackout
+	if task.OnBlackout == "" {
+		task.OnBlackout = models.BlackoutSkip
+	}
+	return nil
+}
+
+func (h *TaskHandler) calculateNextCronRun(task *models.Task) (time.Time, error) {
+	schedule, err := cronspec.Parse(task.TriggerValue, task.ID, task.Jitter())
 	if err != nil {
 		return time.Time{}, err
 	}
 	return schedule.Next(time.Now()), nil
 }
 
+// PreviewTaskSchedule godoc
+// @Summary Preview a cron task's schedule
+// @Description Show a cron task's expression with H tokens expanded, its jitter and its next fire times
+// @Tags tasks
+// @Produce json
+// @Param id path string true "Task ID"
+// @Param count query int false "Number of fire times" default(5)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /tasks/{id}/schedule [get]
+func (h *TaskHandler) PreviewTaskSchedule(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID"})
+		return
+	}
+
+	task, err := h.taskRepo.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+	if task.TriggerType != models.TriggerTypeCron {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only cron tasks have a schedule to preview"})
+		return
+	}
+
+	count, _ := strconv.Atoi(c.DefaultQuery("count", "5"))
+	if count < 1 || count > 100 {
+		count = 5
+	}
+
+	expanded, err := cronspec.Expand(task.TriggerValue, task.ID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid cron expression: %v", err)})
+		return
+	}
+	schedule, err := cronspec.Parse(task.TriggerValue, task.ID, task.Jitter())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid cron expression: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"task_id":        task.ID,
+		"cron":           task.TriggerValue,
+		"expanded":       expanded,
+		"jitter_seconds": task.JitterSeconds,
+		"jitter_offset":  cronspec.Jitter(task.ID, task.Jitter()).String(),
+		"next_runs":      cronspec.Preview(schedule, time.Now(), count),
+	})
+}
+
 // ExecuteTask godoc
 // @Summary Execute a task immediately
-// @Description Trigger immediate execution of a task
+// @Description Trigger immediate execution of a scheduled task, subject to its debounce or throttle
 // @Tags tasks
 // @Produce json
 // @Param id path string true "Task ID"
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 503 {object} map[string]string
 // @Router /tasks/{id}/execute [post]
 func (h *TaskHandler) ExecuteTask(c *gin.Context) {
 	taskID := c.Param("id")
@@ -386,62 +700,54 @@ func (h *TaskHandler) ExecuteTask(c *gin.Context) {
 	}
 
 	// Get task
-	_, err = h.taskRepo.GetByID(id)
+	task, err := h.taskRepo.GetByID(id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
 		return
 	}
+	if task.Status != models.TaskStatusScheduled {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("Task is %s", task.Status), "status": task.Status})
+		return
+	}
+	if h.scheduler == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Scheduler is not available"})
+		return
+	}
 
-	// TODO: Trigger immediate execution via scheduler/executor
-	// For now, just return success message
+	// Manual runs pass through the task's debounce or throttle like any
+	// other trigger
+	h.scheduler.RunTaskNow(task)
 	c.JSON(http.StatusOK, gin.H{"message": "Task execution triggered successfully"})
 }
 
 // PauseTask godoc
-// @Summary Pause a recurring task
-// @Description Pause a cron-based task to stop future executions
+// @Summary Pause a task
+// @Description Pause a scheduled task to stop future executions; a one-off task whose time passes while paused runs on resume
 // @Tags tasks
 // @Produce json
 // @Param id path string true "Task ID"
 // @Success 200 {object} models.Task
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /tasks/{id}/pause [post]
 func (h *TaskHandler) PauseTask(c *gin.Context) {
-	taskID := c.Param("id")
-	if taskID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Task ID is required"})
-		return
-	}
-
-	// Parse UUID
-	id, err := uuid.Parse(taskID)
+	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID format"})
 		return
 	}
 
-	// Get task
-	task, err := h.taskRepo.GetByID(id)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+	task, ok := h.transitionTask(c, id, models.TaskEventPause)
+	if !ok {
 		return
 	}
 
-	// Only pause cron tasks
-	if task.TriggerType != models.TriggerTypeCron {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Only cron tasks can be paused"})
-		return
-	}
-
-	// Update status to paused
-	task.Status = models.TaskStatusPaused
-	task.UpdatedAt = time.Now()
-
-	if err := h.taskRepo.Update(task); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to pause task"})
-		return
+	// The scheduler only fires scheduled tasks; unscheduling drops the task
+	// from its cache of upcoming fires
+	if h.scheduler != nil {
+		h.scheduler.UnscheduleTask(task.ID)
 	}
 
 	c.JSON(http.StatusOK, task)
@@ -449,60 +755,76 @@ func (h *TaskHandler) PauseTask(c *gin.Context) {
 
 // ResumeTask godoc
 // @Summary Resume a paused task
-// @Description Resume a previously paused cron-based task
+// @Description Resume a paused task; a one-off task whose time passed while paused runs straight away
 // @Tags tasks
 // @Produce json
 // @Param id path string true "Task ID"
 // @Success 200 {object} models.Task
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /tasks/{id}/resume [post]
 func (h *TaskHandler) ResumeTask(c *gin.Context) {
-	taskID := c.Param("id")
-	if taskID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Task ID is required"})
-		return
-	}
-
-	// Parse UUID
-	id, err := uuid.Parse(taskID)
+	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid task ID format"})
 		return
 	}
 
-	// Get task
-	task, err := h.taskRepo.GetByID(id)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
-		return
-	}
-
-	// Only resume paused cron tasks
-	if task.TriggerType != models.TriggerTypeCron {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Only cron tasks can be resumed"})
+	// A cron task's next_run is moved on in the same update that resumes
+	// it, so the due loop never fires the one from before the pause. Fires
+	// missed while paused are not made up.
+	task, ok := h.transitionTaskWith(c, id, models.TaskEventResume, func(task *models.Task) map[string]interface{} {
+		if task.TriggerType != models.TriggerTypeCron {
+			return map[string]interface{}{}
+		}
+		nextRun, err := h.calculateNextCronRun(task)
+		if err != nil {
+			return map[string]interface{}{}
+		}
+		task.NextRun = &nextRun
+		task.DeferredTriggers = 0
+		return map[string]interface{}{"next_run": nextRun, "deferred_triggers": 0}
+	})
+	if !ok {
 		return
 	}
 
-	if task.Status != models.TaskStatusPaused {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Task is not paused"})
-		return
+	switch task.TriggerType {
+	case models.TriggerTypeOneOff:
+		if h.scheduler != nil {
+			if err := h.scheduler.ScheduleTask(task); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to re-arm task"})
+				return
+			}
+		}
 	}
 
-	// Update status to active and recalculate next run
-	task.Status = models.TaskStatusActive
-	task.UpdatedAt = time.Now()
+	c.JSON(http.StatusOK, task)
+}
 
-	// Recalculate next run time
-	if nextRun, err := h.calculateNextCronRun(task.TriggerValue); err == nil {
-		task.NextRun = &nextRun
-	}
+// transitionTask applies a state machine event to a task, answering 409
+// when the task's status does not allow it
+func (h *TaskHandler) transitionTask(c *gin.Context, id uuid.UUID, event models.TaskEvent) (*models.Task, bool) {
+	return h.transitionTaskWith(c, id, event, nil)
+}
 
-	if err := h.taskRepo.Update(task); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resume task"})
-		return
+// transitionTaskWith is transitionTask that also writes the columns changes
+// returns in the same update; see TaskRepository.TransitionWith
+func (h *TaskHandler) transitionTaskWith(c *gin.Context, id uuid.UUID, event models.TaskEvent, changes func(*models.Task) map[string]interface{}) (*models.Task, bool) {
+	task, err := h.taskRepo.TransitionWith(id, event, changes)
+	var transitionErr *models.TransitionError
+	switch {
+	case errors.As(err, &transitionErr):
+		c.JSON(http.StatusConflict, gin.H{"error": transitionErr.Error(), "status": transitionErr.From})
+		return nil, false
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return nil, false
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to %s task", event)})
+		return nil, false
 	}
-
-	c.JSON(http.StatusOK, task)
+	return task, true
 }
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"task-scheduler/internal/models"
+	"task-scheduler/internal/repository"
+	"task-scheduler/internal/secrets"
+)
+
+const maxWebhookBodyBytes = 1 << 20
+
+// WebhookRunner starts a webhook task's run for an accepted delivery
+type WebhookRunner interface {
+	RunWebhookTask(task *models.Task, inbound *models.InboundRequest)
+}
+
+type WebhookHandler struct {
+	taskRepo    *repository.TaskRepository
+	webhookRepo *repository.WebhookRepository
+	secrets     *secrets.Resolver
+	runner      WebhookRunner
+}
+
+func NewWebhookHandler(taskRepo *repository.TaskRepository, webhookRepo *repository.WebhookRepository, resolver *secrets.Resolver, runner WebhookRunner) *WebhookHandler {
+	return &WebhookHandler{
+		taskRepo:    taskRepo,
+		webhookRepo: webhookRepo,
+		secrets:     resolver,
+		runner:      runner,
+	}
+}
+
+// ReceiveWebhook godoc
+// @Summary Deliver a webhook
+// @Description Run the webhook task the token belongs to, with the request body available to its templates as .Webhook
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param token path string true "Webhook token"
+// @Success 200 {object} map[string]interface{} "Duplicate delivery"
+// @Success 202 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 413 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /hooks/{token} [post]
+func (h *WebhookHandler) ReceiveWebhook(c *gin.Context) {
+	task, err := h.taskRepo.GetByWebhookToken(c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+	if task.Status != models.TaskStatusScheduled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Task is " + string(task.Status)})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBodyBytes+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read body"})
+		return
+	}
+	if len(body) > maxWebhookBodyBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Body too large"})
+		return
+	}
+
+	// Signatures are checked before the idempotency key is recorded so a
+	// forged delivery cannot use up a key
+	if task.Webhook != nil && task.Webhook.HMACSecret != "" {
+		secret, err := h.secrets.Resolve(task.Webhook.HMACSecret)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Webhook secret unavailable"})
+			return
+		}
+		if !VerifyWebhookSignature(secret, body, c.GetHeader(task.Webhook.GetSignatureHeader())) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid signature"})
+			return
+		}
+	}
+
+	if key := c.GetHeader(task.Webhook.GetIdempotencyHeader()); key != "" {
+		isNew, err := h.webhookRepo.RecordDelivery(task.ID, key)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record delivery"})
+			return
+		}
+		if !isNew {
+			c.JSON(http.StatusOK, gin.H{"message": "Duplicate delivery ignored", "duplicate": true})
+			return
+		}
+	}
+
+	inbound := &models.InboundRequest{Body: string(body), Headers: c.Request.Header.Clone()}
+	if json.Unmarshal(body, &inbound.JSON) != nil {
+		inbound.JSON = nil
+	}
+	h.runner.RunWebhookTask(task, inbound)
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Webhook accepted", "task_id": task.ID})
+}
+
+// VerifyWebhookSignature checks a hex HMAC-SHA256 of body, with or without
+// a "sha256=" prefix
+func VerifyWebhookSignature(secret string, body []byte, signature string) bool {
+	signature = strings.TrimPrefix(strings.TrimSpace(signature), "sha256=")
+	given, err := hex.DecodeString(signature)
+	if err != nil || len(given) == 0 {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(given, mac.Sum(nil))
+}
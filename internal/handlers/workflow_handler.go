@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"task-scheduler/internal/cronspec"
+	"task-scheduler/internal/models"
+	"task-scheduler/internal/repository"
+	"task-scheduler/internal/workflow"
+)
+
+// WorkflowScheduler is the part of the scheduler the workflow endpoints use
+type WorkflowScheduler interface {
+	ScheduleWorkflow(wf *models.Workflow) error
+	UnscheduleWorkflow(workflowID uuid.UUID)
+	StartWorkflowRun(workflowID uuid.UUID) (*models.WorkflowRun, error)
+}
+
+type WorkflowHandler struct {
+	workflowRepo *repository.WorkflowRepository
+	taskRepo     *repository.TaskRepository
+	scheduler    WorkflowScheduler
+}
+
+func NewWorkflowHandler(workflowRepo *repository.WorkflowRepository, taskRepo *repository.TaskRepository, scheduler WorkflowScheduler) *WorkflowHandler {
+	return &WorkflowHandler{
+		workflowRepo: workflowRepo,
+		taskRepo:     taskRepo,
+		scheduler:    scheduler,
+	}
+}
+
+// CreateWorkflow godoc
+// @Summary Create a workflow
+// @Description Create a DAG of tasks whose edges run on success, failure or always
+// @Tags workflows
+// @Accept json
+// @Produce json
+// @Param workflow body models.CreateWorkflowRequest true "Workflow creation request"
+// @Success 201 {object} models.Workflow
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /workflows [post]
+func (h *WorkflowHandler) CreateWorkflow(c *gin.Context) {
+	var req models.CreateWorkflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := workflow.Validate(req.Nodes, req.Edges); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, node := range req.Nodes {
+		task, err := h.taskRepo.GetByID(node.TaskID)
+		if err != nil || task.Status == models.TaskStatusCancelled {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("node %s: task %s not found", node.ID, node.TaskID)})
+			return
+		}
+	}
+
+	wf := &models.Workflow{
+		Name:      req.Name,
+		Nodes:     req.Nodes,
+		Edges:     req.Edges,
+		Status:    models.WorkflowStatusActive,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	// The ID is assigned up front because H tokens hash it
+	wf.ID = uuid.New()
+	if req.Cron != nil && *req.Cron != "" {
+		schedule, err := cronspec.Parse(*req.Cron, wf.ID, 0)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid cron expression: %v", err)})
+			return
+		}
+		nextRun := schedule.Next(time.Now())
+		wf.Cron = req.Cron
+		wf.NextRun = &nextRun
+	}
+
+	if err := h.workflowRepo.Create(wf); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create workflow"})
+		return
+	}
+
+	if err := h.scheduler.ScheduleWorkflow(wf); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule workflow"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, wf)
+}
+
+// GetWorkflows godoc
+// @Summary List workflows
+// @Description Get a paginated list of workflows
+// @Tags workflows
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]string
+// @Router /workflows [get]
+func (h *WorkflowHandler) GetWorkflows(c *gin.Context) {
+	page, limit, offset := pagination(c)
+
+	workflows, total, err := h.workflowRepo.List(limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch workflows"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"workflows": workflows,
+		"pagination": gin.H{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// GetWorkflow godoc
+// @Summary Get workflow by ID
+// @Description Get a workflow's nodes and edges
+// @Tags workflows
+// @Produce json
+// @Param id path string true "Workflow ID"
+// @Success 200 {object} models.Workflow
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /workflows/{id} [get]
+func (h *WorkflowHandler) GetWorkflow(c *gin.Context) {
+	wf, ok := h.lookupWorkflow(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, wf)
+}
+
+// DeleteWorkflow godoc
+// @Summary Cancel a workflow
+// @Description Cancel a workflow so it no longer runs; runs already started carry on
+// @Tags workflows
+// @Produce json
+// @Param id path string true "Workflow ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /workflows/{id} [delete]
+func (h *WorkflowHandler) DeleteWorkflow(c *gin.Context) {
+	wf, ok := h.lookupWorkflow(c)
+	if !ok {
+		return
+	}
+
+	if err := h.workflowRepo.Delete(wf.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel workflow"})
+		return
+	}
+	h.scheduler.UnscheduleWorkflow(wf.ID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Workflow cancelled successfully"})
+}
+
+// RunWorkflow godoc
+// @Summary Run a workflow now
+// @Description Start a workflow run immediately, regardless of its cron
+// @Tags workflows
+// @Produce json
+// @Param id path string true "Workflow ID"
+// @Success 202 {object} models.WorkflowRun
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /workflows/{id}/run [post]
+func (h *WorkflowHandler) RunWorkflow(c *gin.Context) {
+	wf, ok := h.lookupWorkflow(c)
+	if !ok {
+		return
+	}
+	if wf.Status != models.WorkflowStatusActive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Workflow is cancelled"})
+		return
+	}
+
+	run, err := h.scheduler.StartWorkflowRun(wf.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start workflow run"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, run)
+}
+
+// GetWorkflowRuns godoc
+// @Summary List workflow runs
+// @Description Get a paginated list of a workflow's runs with their node states
+// @Tags workflows
+// @Produce json
+// @Param id path string true "Workflow ID"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(10)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /workflows/{id}/runs [get]
+func (h *WorkflowHandler) GetWorkflowRuns(c *gin.Context) {
+	wf, ok := h.lookupWorkflow(c)
+	if !ok {
+		return
+	}
+
+	page, limit, offset := pagination(c)
+
+	runs, total, err := h.workflowRepo.ListRuns(wf.ID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch workflow runs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"runs": runs,
+		"pagination": gin.H{
+			"page":        page,
+			"limit":       limit,
+			"total":       total,
+			"total_pages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// GetWorkflowRun godoc
+// @Summary Get a workflow run
+// @Description Get one workflow run with the state of every node
+// @Tags workflows
+// @Produce json
+// @Param id path string true "Workflow ID"
+// @Param run_id path string true "Run ID"
+// @Success 200 {object} models.WorkflowRun
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /workflows/{id}/runs/{run_id} [get]
+func (h *WorkflowHandler) GetWorkflowRun(c *gin.Context) {
+	wf, ok := h.lookupWorkflow(c)
+	if !ok {
+		return
+	}
+
+	runID, err := uuid.Parse(c.Param("run_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid run ID"})
+		return
+	}
+
+	run, err := h.workflowRepo.GetRun(runID)
+	if err != nil || run.WorkflowID != wf.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workflow run not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+func (h *WorkflowHandler) lookupWorkflow(c *gin.Context) (*models.Workflow, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workflow ID"})
+		return nil, false
+	}
+
+	wf, err := h.workflowRepo.GetByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workflow not found"})
+		return nil, false
+	}
+	return wf, true
+}
+
+func pagination(c *gin.Context) (page, limit, offset int) {
+	page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ = strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	return page, limit, (page - 1) * limit
+}
@@ -13,6 +13,12 @@ type Metrics struct {
     TotalExecutionTime    time.Duration
     AverageExecutionTime  time.Duration
     TasksPerMinute        float64
+    RateLimitWaits        int64
+    TotalRateLimitWait    time.Duration
+    RateLimitDeferrals    int64
+    CircuitOpenRuns       int64
+    BlackoutSkips         int64
+    BlackoutDeferrals     int64
     lastMinuteExecutions  []time.Time
 }
 
@@ -53,6 +59,44 @@ func (m *Metrics) RecordTaskExecution(duration time.Duration, success bool) {
     m.TasksPerMinute = float64(len(m.lastMinuteExecutions))
 }
 
+// RecordRateLimitWait records time a run spent waiting on a per-host limiter
+func (m *Metrics) RecordRateLimitWait(wait time.Duration) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    
+    m.RateLimitWaits++
+    m.TotalRateLimitWait += wait
+}
+
+// RecordRateLimitDeferral records a run pushed back by a per-host limiter
+func (m *Metrics) RecordRateLimitDeferral() {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    
+    m.RateLimitDeferrals++
+}
+
+// RecordCircuitOpen records a run short-circuited by an open circuit
+func (m *Metrics) RecordCircuitOpen() {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    
+    m.CircuitOpenRuns++
+}
+
+// RecordBlackout records a run that fell inside a calendar blackout and
+// was skipped or deferred
+func (m *Metrics) RecordBlackout(deferred bool) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    
+    if deferred {
+        m.BlackoutDeferrals++
+    } else {
+        m.BlackoutSkips++
+    }
+}
+
 func (m *Metrics) GetMetrics() map[string]interface{} {
     m.mu.RLock()
     defer m.mu.RUnlock()
@@ -69,6 +113,12 @@ func (m *Metrics) GetMetrics() map[string]interface{} {
         "success_rate_percent":   successRate,
         "average_execution_ms":   m.AverageExecutionTime.Milliseconds(),
         "tasks_per_minute":       m.TasksPerMinute,
+        "rate_limit_waits":       m.RateLimitWaits,
+        "rate_limit_wait_ms":     m.TotalRateLimitWait.Milliseconds(),
+        "rate_limit_deferrals":   m.RateLimitDeferrals,
+        "circuit_open_runs":      m.CircuitOpenRuns,
+        "blackout_skips":         m.BlackoutSkips,
+        "blackout_deferrals":     m.BlackoutDeferrals,
     }
 }
 
@@ -82,5 +132,11 @@ func (m *Metrics) Reset() {
     m.TotalExecutionTime = 0
     m.AverageExecutionTime = 0
     m.TasksPerMinute = 0
+    m.RateLimitWaits = 0
+    m.TotalRateLimitWait = 0
+    m.RateLimitDeferrals = 0
+    m.CircuitOpenRuns = 0
+    m.BlackoutSkips = 0
+    m.BlackoutDeferrals = 0
     m.lastMinuteExecutions = make([]time.Time, 0)
 }
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+    "crypto/subtle"
+    "net/http"
+    "strings"
+
+    "github.com/gin-gonic/gin"
+)
+
+// AdminToken guards admin routes with a bearer token. An empty token
+// rejects every request rather than leave them open.
+func AdminToken(token string) gin.HandlerFunc {
+    return gin.HandlerFunc(func(c *gin.Context) {
+        if token == "" {
+            c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin endpoints are disabled"})
+            return
+        }
+
+        given := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+        if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid admin token"})
+            return
+        }
+
+        c.Next()
+    })
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BlackoutAction says what happens to a run that lands in a blackout
+type BlackoutAction string
+
+const (
+	// BlackoutSkip drops the run
+	BlackoutSkip BlackoutAction = "skip"
+	// BlackoutDefer runs it once the blackout ends
+	BlackoutDefer BlackoutAction = "defer"
+)
+
+// BlackoutWindow is a recurring daily window, e.g. a partner's weekly
+// maintenance. Start and End are "15:04" times in the calendar's time zone;
+// an End before Start runs past midnight. Days limits the window to the
+// given weekdays ("mon".."sun"), counted from the day the window starts.
+type BlackoutWindow struct {
+	Name  string   `json:"name,omitempty"`
+	Days  []string `json:"days,omitempty"`
+	Start string   `json:"start"`
+	End   string   `json:"end"`
+}
+
+type BlackoutWindows []BlackoutWindow
+
+func (w BlackoutWindows) Value() (driver.Value, error) {
+	return json.Marshal(w)
+}
+
+func (w *BlackoutWindows) Scan(value interface{}) error {
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, w)
+}
+
+// CalendarDate blacks out a whole day, such as a bank holiday
+type CalendarDate struct {
+	Date string `json:"date"` // 2006-01-02
+	Name string `json:"name,omitempty"`
+}
+
+type CalendarDates []CalendarDate
+
+func (d CalendarDates) Value() (driver.Value, error) {
+	return json.Marshal(d)
+}
+
+func (d *CalendarDates) Scan(value interface{}) error {
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, d)
+}
+
+// BlackoutPeriod blacks out a fixed span of time, e.g. a timed event
+// imported from an iCalendar file
+type BlackoutPeriod struct {
+	Name  string    `json:"name,omitempty"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+type BlackoutPeriods []BlackoutPeriod
+
+func (p BlackoutPeriods) Value() (driver.Value, error) {
+	return json.Marshal(p)
+}
+
+func (p *BlackoutPeriods) Scan(value interface{}) error {
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, p)
+}
+
+// Calendar groups the blackouts tasks can be attached to
+type Calendar struct {
+	ID   uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Name string    `json:"name" gorm:"not null"`
+	// Timezone is an IANA zone name used for windows and dates; it
+	// defaults to UTC
+	Timezone  string          `json:"timezone" gorm:"not null;default:UTC"`
+	Windows   BlackoutWindows `json:"windows" gorm:"type:jsonb"`
+	Dates     CalendarDates   `json:"dates" gorm:"type:jsonb"`
+	Periods   BlackoutPeriods `json:"periods" gorm:"type:jsonb"`
+	CreatedAt time.Time       `json:"created_at" gorm:"default:now()"`
+	UpdatedAt time.Time       `json:"updated_at" gorm:"default:now()"`
+}
+
+// CalendarRequest is the payload for creating or replacing a calendar
+type CalendarRequest struct {
+	Name     string           `json:"name" binding:"required"`
+	Timezone string           `json:"timezone,omitempty"`
+	Windows  []BlackoutWindow `json:"windows,omitempty"`
+	Dates    []CalendarDate   `json:"dates,omitempty"`
+	Periods  []BlackoutPeriod `json:"periods,omitempty"`
+}
+
+// TaskCalendar attaches a calendar to a task
+type TaskCalendar struct {
+	// ID is the calendar to follow; null detaches the task's calendar
+	ID *uuid.UUID `json:"id"`
+	// OnBlackout defaults to skip
+	OnBlackout BlackoutAction `json:"on_blackout,omitempty"`
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FollowUp is run after a task finishes: either another task, or an inline
+// HTTP callback whose fields are templates rendered with the parent result
+type FollowUp struct {
+	TaskID   *uuid.UUID    `json:"task_id,omitempty"`
+	Callback *HTTPCallback `json:"callback,omitempty"`
+}
+
+type HTTPCallback struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Payload string            `json:"payload,omitempty"`
+}
+
+// Validate checks that exactly one of task_id and callback is set
+func (f FollowUp) Validate() error {
+	if (f.TaskID == nil) == (f.Callback == nil) {
+		return fmt.Errorf("follow-ups need exactly one of task_id and callback")
+	}
+	if f.Callback != nil && (f.Callback.Method == "" || f.Callback.URL == "") {
+		return fmt.Errorf("follow-up callbacks need a method and url")
+	}
+	return nil
+}
+
+type FollowUps []FollowUp
+
+func (f FollowUps) Value() (driver.Value, error) {
+	if f == nil {
+		return nil, nil
+	}
+	return json.Marshal(f)
+}
+
+func (f *FollowUps) Scan(value interface{}) error {
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, f)
+}
+
+// ParentResult describes the run that triggered a follow-up. Templates see
+// it as .Parent.
+type ParentResult struct {
+	TaskID     uuid.UUID
+	TaskName   string
+	ResultID   uuid.UUID
+	Success    bool
+	StatusCode int
+	Body       string
+	Error      string
+	RunAt      time.Time
+	DurationMs int
+
+	// Chain lists the tasks that led to this follow-up, oldest first
+	Chain []uuid.UUID
+}
+
+// NewParentResult describes task's finished run for its follow-ups,
+// extending the chain the task itself was started by
+func NewParentResult(task *Task, result *TaskResult) *ParentResult {
+	parent := &ParentResult{
+		TaskID:     task.ID,
+		TaskName:   task.Name,
+		ResultID:   result.ID,
+		Success:    result.Success,
+		RunAt:      result.RunAt,
+		DurationMs: result.DurationMs,
+	}
+	if result.StatusCode != nil {
+		parent.StatusCode = *result.StatusCode
+	}
+	if result.ResponseBody != nil {
+		parent.Body = *result.ResponseBody
+	}
+	if result.ErrorMessage != nil {
+		parent.Error = *result.ErrorMessage
+	}
+	if task.Parent != nil {
+		parent.Chain = append(parent.Chain, task.Parent.Chain...)
+	}
+	parent.Chain = append(parent.Chain, task.ID)
+	return parent
+}
+
+// InChain reports whether taskID already ran earlier in this chain
+func (p *ParentResult) InChain(taskID uuid.UUID) bool {
+	for _, id := range p.Chain {
+		if id == taskID {
+			return true
+		}
+	}
+	return false
+}
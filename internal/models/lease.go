@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// SchedulerLease is a leadership lease shared by the instances of a
+// cluster; whoever holds an unexpired lease is the leader
+type SchedulerLease struct {
+	Name      string    `json:"name" gorm:"primaryKey"`
+	Holder    string    `json:"holder" gorm:"not null"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
+	RenewedAt time.Time `json:"renewed_at" gorm:"not null"`
+}
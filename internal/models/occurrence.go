@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyKeyHeader carries an occurrence's key to HTTP destinations
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// TaskOccurrence records that a planned fire of a task was dispatched. The
+// primary key lets only one instance, or one pass after a restart, claim
+// each occurrence.
+type TaskOccurrence struct {
+	TaskID       uuid.UUID `json:"task_id" gorm:"type:uuid;primaryKey"`
+	ScheduledFor time.Time `json:"scheduled_for" gorm:"primaryKey"`
+	CreatedAt    time.Time `json:"created_at" gorm:"default:now()"`
+}
+
+// OccurrenceTime normalises a planned fire time so every instance records
+// the same occurrence for it
+func OccurrenceTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Second)
+}
+
+// OccurrenceKey is the stable key of a task's occurrence, the same on every
+// retry and every instance that might fire it
+func OccurrenceKey(taskID uuid.UUID, scheduledFor time.Time) string {
+	return taskID.String() + ":" + OccurrenceTime(scheduledFor).Format(time.RFC3339)
+}
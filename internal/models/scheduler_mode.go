@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SchedulerMode is the scheduler-wide maintenance mode
+type SchedulerMode string
+
+const (
+	// SchedulerRunning is normal operation
+	SchedulerRunning SchedulerMode = "running"
+	// SchedulerPaused stops every run from starting; fires are skipped or
+	// queued according to the pause's OnFire
+	SchedulerPaused SchedulerMode = "paused"
+	// SchedulerDraining lets runs already in flight finish and skips new ones
+	SchedulerDraining SchedulerMode = "draining"
+)
+
+// PausedFireAction says what happens to a fire while the scheduler is paused
+type PausedFireAction string
+
+const (
+	// PausedFireSkip drops the run
+	PausedFireSkip PausedFireAction = "skip"
+	// PausedFireQueue runs it once the scheduler is resumed
+	PausedFireQueue PausedFireAction = "queue"
+)
+
+// SchedulerState is the persisted maintenance mode. There is a single row.
+type SchedulerState struct {
+	ID        int              `json:"-" gorm:"primaryKey"`
+	Mode      SchedulerMode    `json:"mode" gorm:"not null;default:running"`
+	OnFire    PausedFireAction `json:"on_fire,omitempty"`
+	Reason    *string          `json:"reason,omitempty"`
+	UpdatedAt time.Time        `json:"since"`
+}
+
+// QueuedFire is a run held back while the scheduler was paused
+type QueuedFire struct {
+	ID       uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TaskID   uuid.UUID `json:"task_id" gorm:"type:uuid;not null;index"`
+	QueuedAt time.Time `json:"queued_at" gorm:"not null"`
+}
+
+// SchedulerStatus reports the maintenance mode and the work around it
+type SchedulerStatus struct {
+	SchedulerState
+	// InFlight counts runs currently executing
+	InFlight int64 `json:"in_flight"`
+	// Queued counts fires waiting for the scheduler to resume
+	Queued int64 `json:"queued"`
+	// Drained is true once a draining scheduler has no runs left in flight
+	Drained bool `json:"drained,omitempty"`
+}
+
+// PauseSchedulerRequest is the payload for pausing the scheduler
+type PauseSchedulerRequest struct {
+	// OnFire defaults to skip
+	OnFire PausedFireAction `json:"on_fire,omitempty" binding:"omitempty,oneof=skip queue"`
+	Reason *string          `json:"reason,omitempty"`
+}
+
+// DrainSchedulerRequest is the payload for draining the scheduler
+type DrainSchedulerRequest struct {
+	Reason *string `json:"reason,omitempty"`
+}
@@ -3,6 +3,9 @@ package models
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,6 +16,20 @@ type TriggerType string
 const (
 	TriggerTypeOneOff TriggerType = "one-off"
 	TriggerTypeCron   TriggerType = "cron"
+	// TriggerTypeWebhook tasks run when POST /hooks/:token is called; the
+	// token is kept in TriggerValue
+	TriggerTypeWebhook TriggerType = "webhook"
+)
+
+type ActionType string
+
+const (
+	ActionTypeHTTP      ActionType = "http"
+	ActionTypeCommand   ActionType = "command"
+	ActionTypeSQL       ActionType = "sql"
+	ActionTypeGRPC      ActionType = "grpc"
+	ActionTypeEmail     ActionType = "email"
+	ActionTypeHTTPSteps ActionType = "http_steps"
 )
 
 type TaskStatus string
@@ -24,6 +41,7 @@ const (
 	TaskStatusCancelled TaskStatus = "cancelled"
 	TaskStatusCompleted TaskStatus = "completed"
 	TaskStatusPending   TaskStatus = "pending"
+	TaskStatusFailed    TaskStatus = "failed"
 )
 
 type Headers map[string]string
@@ -46,49 +64,697 @@ func (h *Headers) Scan(value interface{}) error {
 	return json.Unmarshal(bytes, h)
 }
 
+// MultiHeaders keeps every value of every header, as net/http does
+type MultiHeaders map[string][]string
+
+func (h MultiHeaders) Value() (driver.Value, error) {
+	return json.Marshal(h)
+}
+
+// Scan also accepts rows written before headers were multi-valued, where
+// each header maps to a single string
+func (h *MultiHeaders) Scan(value interface{}) error {
+	if value == nil {
+		*h = make(MultiHeaders)
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+
+	if err := json.Unmarshal(bytes, (*map[string][]string)(h)); err == nil {
+		return nil
+	}
+
+	var legacy map[string]string
+	if err := json.Unmarshal(bytes, &legacy); err != nil {
+		return err
+	}
+	*h = make(MultiHeaders, len(legacy))
+	for key, value := range legacy {
+		(*h)[key] = []string{value}
+	}
+	return nil
+}
+
+// Get returns the first value of a header, matching the key case-insensitively
+func (h MultiHeaders) Get(key string) string {
+	if values := h[http.CanonicalHeaderKey(key)]; len(values) > 0 {
+		return values[0]
+	}
+	for k, values := range h {
+		if strings.EqualFold(k, key) && len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// RequestSnapshot records what the scheduler actually sent. URL is the
+// final URL after redirects; sensitive headers and query values are redacted.
+type RequestSnapshot struct {
+	Method     string       `json:"method"`
+	URL        string       `json:"url"`
+	Headers    MultiHeaders `json:"headers,omitempty"`
+	BodySHA256 string       `json:"body_sha256,omitempty"`
+	BodySize   int          `json:"body_size"`
+}
+
+func (r RequestSnapshot) Value() (driver.Value, error) {
+	return json.Marshal(r)
+}
+
+func (r *RequestSnapshot) Scan(value interface{}) error {
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, r)
+}
+
+// RawConfig is an action's JSON configuration, stored as jsonb
+type RawConfig json.RawMessage
+
+func (c RawConfig) Value() (driver.Value, error) {
+	if len(c) == 0 {
+		return nil, nil
+	}
+	return []byte(c), nil
+}
+
+func (c *RawConfig) Scan(value interface{}) error {
+	bytes, ok := value.([]byte)
+	if !ok {
+		*c = nil
+		return nil
+	}
+	*c = append((*c)[:0], bytes...)
+	return nil
+}
+
+func (c RawConfig) MarshalJSON() ([]byte, error) {
+	if len(c) == 0 {
+		return []byte("null"), nil
+	}
+	return c, nil
+}
+
+func (c *RawConfig) UnmarshalJSON(data []byte) error {
+	*c = append((*c)[:0], data...)
+	return nil
+}
+
+// CommandAction runs a program directly (no shell) with the given argv
+type CommandAction struct {
+	Argv           []string          `json:"argv"`
+	Dir            string            `json:"dir,omitempty"`
+	Env            map[string]string `json:"env,omitempty"`
+	TimeoutSeconds int               `json:"timeout_seconds,omitempty"`
+	MaxOutputBytes int               `json:"max_output_bytes,omitempty"`
+}
+
+// SQLAction runs one statement against an admin-configured connection.
+// String params are rendered as templates before being bound as $1, $2, ...
+type SQLAction struct {
+	Connection     string        `json:"connection"`
+	Statement      string        `json:"statement"`
+	Params         []interface{} `json:"params,omitempty"`
+	ReadOnly       bool          `json:"read_only,omitempty"`
+	TimeoutSeconds int           `json:"timeout_seconds,omitempty"`
+	// MaxRows is how many returned rows are kept on the result
+	MaxRows int `json:"max_rows,omitempty"`
+}
+
+// GRPCAction invokes a unary gRPC method. The method's types come from
+// DescriptorSet (a serialized FileDescriptorSet, base64 in JSON) when given,
+// otherwise from the server's reflection service.
+type GRPCAction struct {
+	// Target is the server's host:port
+	Target string `json:"target"`
+	// Method is the full method name, e.g. "grpc.health.v1.Health/Check"
+	Method         string            `json:"method"`
+	Request        json.RawMessage   `json:"request,omitempty"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+	DescriptorSet  []byte            `json:"descriptor_set,omitempty"`
+	Plaintext      bool              `json:"plaintext,omitempty"`
+	TLSProfile     string            `json:"tls_profile,omitempty"`
+	TimeoutSeconds int               `json:"timeout_seconds,omitempty"`
+}
+
+// EmailAction sends one message over SMTP. Subject and Body are templates.
+type EmailAction struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	StartTLS bool   `json:"starttls,omitempty"`
+	// TLSProfile supplies the trust settings used for STARTTLS
+	TLSProfile string `json:"tls_profile,omitempty"`
+	Username   string `json:"username,omitempty"`
+	// PasswordSecret is a secret reference such as "env:SECRET_SMTP_PASSWORD"
+	PasswordSecret string `json:"password_secret,omitempty"`
+
+	From        string            `json:"from"`
+	To          []string          `json:"to"`
+	Cc          []string          `json:"cc,omitempty"`
+	Subject     string            `json:"subject"`
+	Body        string            `json:"body"`
+	HTML        bool              `json:"html,omitempty"`
+	Attachments []EmailAttachment `json:"attachments,omitempty"`
+
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+}
+
+// EmailAttachment is fetched from URL when the email is sent
+type EmailAttachment struct {
+	URL string `json:"url"`
+	// Filename defaults to the last element of the URL path
+	Filename string `json:"filename,omitempty"`
+}
+
+// SMTPReply is the server's reply to one SMTP command
+type SMTPReply struct {
+	Command string `json:"command"`
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// SMTPReplies records an email action's SMTP conversation
+type SMTPReplies []SMTPReply
+
+func (r SMTPReplies) Value() (driver.Value, error) {
+	if r == nil {
+		return nil, nil
+	}
+	return json.Marshal(r)
+}
+
+func (r *SMTPReplies) Scan(value interface{}) error {
+	if value == nil {
+		*r = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into SMTPReplies", value)
+	}
+	return json.Unmarshal(bytes, r)
+}
+
+// HTTPStepsAction runs HTTP requests in order, e.g. login, call, confirm
+type HTTPStepsAction struct {
+	Steps []HTTPStep `json:"steps"`
+}
+
+// HTTPStep is one request of an http_steps action. URL, header values and
+// Payload are templates that can use .Vars (values extracted by earlier
+// steps) and .Prev (the previous step's response).
+type HTTPStep struct {
+	// Name defaults to "step1", "step2", ...
+	Name string `json:"name,omitempty"`
+	// If is a template; the step runs only when it renders "true", e.g.
+	// {{eq .Prev.StatusCode 200}}
+	If      string            `json:"if,omitempty"`
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Payload string            `json:"payload,omitempty"`
+
+	TLSProfile *string         `json:"tls_profile,omitempty"`
+	Proxy      *string         `json:"proxy,omitempty"`
+	Redirects  *RedirectPolicy `json:"redirects,omitempty"`
+
+	// ContinueOnFailure keeps the sequence going when this step fails, so a
+	// later step can react to it with an If condition
+	ContinueOnFailure bool `json:"continue_on_failure,omitempty"`
+
+	// Extract maps variable names to extractors: "json:data.token",
+	// "header:X-Request-Id", "status" or "regex:id=(\d+)"
+	Extract map[string]string `json:"extract,omitempty"`
+}
+
+// StepResult records one step of an http_steps action
+type StepResult struct {
+	Name            string           `json:"name"`
+	Skipped         bool             `json:"skipped,omitempty"`
+	Success         bool             `json:"success"`
+	StatusCode      *int             `json:"status_code,omitempty"`
+	DurationMs      int              `json:"duration_ms"`
+	Request         *RequestSnapshot `json:"request,omitempty"`
+	ResponseHeaders MultiHeaders     `json:"response_headers,omitempty"`
+	ResponseBody    *string          `json:"response_body,omitempty"`
+	// Extracted holds the extracted variables; sensitive ones are redacted
+	Extracted    map[string]string `json:"extracted,omitempty"`
+	ErrorMessage *string           `json:"error_message,omitempty"`
+	// Outcome is set when the step's host limiter or breaker kept it back
+	Outcome ResultOutcome `json:"outcome,omitempty"`
+}
+
+// StepResults records every step of an http_steps action
+type StepResults []StepResult
+
+func (r StepResults) Value() (driver.Value, error) {
+	if r == nil {
+		return nil, nil
+	}
+	return json.Marshal(r)
+}
+
+func (r *StepResults) Scan(value interface{}) error {
+	if value == nil {
+		*r = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into StepResults", value)
+	}
+	return json.Unmarshal(bytes, r)
+}
+
+// ResultRows holds the first rows returned by a sql action
+type ResultRows []map[string]interface{}
+
+func (r ResultRows) Value() (driver.Value, error) {
+	if r == nil {
+		return nil, nil
+	}
+	return json.Marshal(r)
+}
+
+func (r *ResultRows) Scan(value interface{}) error {
+	if value == nil {
+		*r = nil
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into ResultRows", value)
+	}
+	return json.Unmarshal(bytes, r)
+}
+
+type RedirectMode string
+
+const (
+	RedirectFollow   RedirectMode = "follow"
+	RedirectNone     RedirectMode = "none"
+	RedirectSameHost RedirectMode = "same_host"
+)
+
+// DefaultMaxRedirects matches net/http's own limit
+const DefaultMaxRedirects = 10
+
+// RedirectPolicy controls whether and how far redirects are followed
+type RedirectPolicy struct {
+	Mode RedirectMode `json:"mode"`
+	Max  int          `json:"max,omitempty"`
+}
+
+func (r RedirectPolicy) Value() (driver.Value, error) {
+	return json.Marshal(r)
+}
+
+func (r *RedirectPolicy) Scan(value interface{}) error {
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, r)
+}
+
+// Validate checks the redirect mode and limit
+func (r *RedirectPolicy) Validate() error {
+	if r == nil {
+		return nil
+	}
+	switch r.Mode {
+	case RedirectFollow, RedirectNone, RedirectSameHost:
+	default:
+		return fmt.Errorf("redirects.mode must be one of follow, none, same_host")
+	}
+	if r.Max < 0 {
+		return fmt.Errorf("redirects.max must not be negative")
+	}
+	return nil
+}
+
+// RedirectHop is one redirect response followed during a run
+type RedirectHop struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+	Location   string `json:"location"`
+}
+
+type RedirectChain []RedirectHop
+
+func (r RedirectChain) Value() (driver.Value, error) {
+	return json.Marshal(r)
+}
+
+func (r *RedirectChain) Scan(value interface{}) error {
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, r)
+}
+
+// StoredCookie is a cookie saved from a task's isolated cookie jar along
+// with the URL that set it
+type StoredCookie struct {
+	URL      string     `json:"url"`
+	Name     string     `json:"name"`
+	Value    string     `json:"value"`
+	Domain   string     `json:"domain,omitempty"`
+	Path     string     `json:"path,omitempty"`
+	Expires  *time.Time `json:"expires,omitempty"`
+	Secure   bool       `json:"secure,omitempty"`
+	HttpOnly bool       `json:"http_only,omitempty"`
+}
+
+type StoredCookies []StoredCookie
+
+func (c StoredCookies) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+func (c *StoredCookies) Scan(value interface{}) error {
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, c)
+}
+
+// TaskCookieJar persists a task's cookies across runs
+type TaskCookieJar struct {
+	TaskID    uuid.UUID     `json:"task_id" gorm:"type:uuid;primary_key"`
+	Cookies   StoredCookies `json:"cookies" gorm:"type:jsonb"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+type BodyCaptureMode string
+
+const (
+	BodyCaptureNone BodyCaptureMode = "none"
+	BodyCaptureHead BodyCaptureMode = "head"
+	BodyCaptureFull BodyCaptureMode = "full"
+)
+
+// DefaultBodyCaptureHeadBytes is how much of a response body is kept inline
+// when a task does not say otherwise
+const DefaultBodyCaptureHeadBytes = 10000
+
+// BodyCapture controls how much of a response body is kept. In full mode the
+// whole body is written to the blob store and only the head is kept inline.
+type BodyCapture struct {
+	Mode      BodyCaptureMode `json:"mode"`
+	HeadBytes int             `json:"head_bytes,omitempty"`
+}
+
+func (b BodyCapture) Value() (driver.Value, error) {
+	return json.Marshal(b)
+}
+
+func (b *BodyCapture) Scan(value interface{}) error {
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, b)
+}
+
+// Limit returns the number of bytes kept inline
+func (b *BodyCapture) Limit() int {
+	if b == nil || b.HeadBytes <= 0 {
+		return DefaultBodyCaptureHeadBytes
+	}
+	return b.HeadBytes
+}
+
+// Validate checks the capture mode and head size
+func (b *BodyCapture) Validate() error {
+	if b == nil {
+		return nil
+	}
+	switch b.Mode {
+	case BodyCaptureNone, BodyCaptureHead, BodyCaptureFull:
+	default:
+		return fmt.Errorf("body_capture.mode must be one of none, head, full")
+	}
+	if b.HeadBytes < 0 {
+		return fmt.Errorf("body_capture.head_bytes must not be negative")
+	}
+	return nil
+}
+
 type Task struct {
-	ID           uuid.UUID   `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	Name         string      `json:"name" gorm:"not null"`
-	TriggerType  TriggerType `json:"trigger_type" gorm:"not null"`
-	TriggerValue string      `json:"trigger_value" gorm:"not null"`
-	Method       string      `json:"method" gorm:"not null;default:GET"`
-	URL          string      `json:"url" gorm:"not null"`
-	Headers      Headers     `json:"headers,omitempty" gorm:"type:jsonb;default:'{}'"`
-	Payload      *string     `json:"payload,omitempty" gorm:"type:jsonb"`
-	Status       TaskStatus  `json:"status" gorm:"default:scheduled"`
-	CreatedAt    time.Time   `json:"created_at" gorm:"default:now()"`
-	UpdatedAt    time.Time   `json:"updated_at" gorm:"default:now()"`
-	NextRun      *time.Time  `json:"next_run,omitempty"`
-	LastRun      *time.Time  `json:"last_run,omitempty"`
+	ID           uuid.UUID       `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Name         string          `json:"name" gorm:"not null"`
+	TriggerType  TriggerType     `json:"trigger_type" gorm:"not null"`
+	TriggerValue string          `json:"trigger_value" gorm:"not null"`
+	ActionType   ActionType      `json:"action_type" gorm:"not null;default:http"`
+	ActionConfig RawConfig       `json:"action_config,omitempty" gorm:"type:jsonb"`
+	Method       string          `json:"method" gorm:"not null;default:GET"`
+	URL          string          `json:"url" gorm:"not null"`
+	Headers      Headers         `json:"headers,omitempty" gorm:"type:jsonb;default:'{}'"`
+	Payload      *string         `json:"payload,omitempty" gorm:"type:jsonb"`
+	TLSProfile   *string         `json:"tls_profile,omitempty"`
+	Proxy        *string         `json:"proxy,omitempty"`
+	BodyCapture  *BodyCapture    `json:"body_capture,omitempty" gorm:"type:jsonb"`
+	Redirects    *RedirectPolicy `json:"redirects,omitempty" gorm:"type:jsonb"`
+	CookieJar    bool            `json:"cookie_jar" gorm:"default:false"`
+	Status       TaskStatus      `json:"status" gorm:"default:scheduled"`
+	CreatedAt    time.Time       `json:"created_at" gorm:"default:now()"`
+	UpdatedAt    time.Time       `json:"updated_at" gorm:"default:now()"`
+	NextRun      *time.Time      `json:"next_run,omitempty" gorm:"index"`
+	LastRun      *time.Time      `json:"last_run,omitempty"`
+
+	// OnSuccess and OnFailure run after the task finishes, depending on
+	// whether the run succeeded
+	OnSuccess FollowUps `json:"on_success,omitempty" gorm:"type:jsonb"`
+	OnFailure FollowUps `json:"on_failure,omitempty" gorm:"type:jsonb"`
+
+	Webhook  *WebhookConfig  `json:"webhook,omitempty" gorm:"type:jsonb"`
+	Coalesce *CoalescePolicy `json:"coalesce,omitempty" gorm:"type:jsonb"`
+
+	// JitterSeconds delays each cron fire by a stable, per-task amount
+	// below this many seconds
+	JitterSeconds int `json:"jitter_seconds,omitempty" gorm:"default:0"`
+
+	// CalendarID attaches blackouts; runs inside one are skipped or
+	// deferred according to OnBlackout
+	CalendarID *uuid.UUID     `json:"calendar_id,omitempty" gorm:"type:uuid"`
+	OnBlackout BlackoutAction `json:"on_blackout,omitempty"`
+	// DeferredTriggers counts the scheduled fires folded into the run
+	// deferred to the end of a blackout, which fires from next_run
+	DeferredTriggers int `json:"-" gorm:"not null;default:0"`
+
+	// Parent is set on runs started as a follow-up of another task
+	Parent *ParentResult `json:"-" gorm:"-"`
+	// Inbound is set on runs started by a webhook delivery
+	Inbound *InboundRequest `json:"-" gorm:"-"`
+	// Coalesced is the number of extra triggers folded into this run
+	Coalesced int `json:"-" gorm:"-"`
+	// Occurrence is the planned fire time this run is for; nil for runs
+	// without one, such as webhook deliveries
+	Occurrence *time.Time `json:"-" gorm:"-"`
+	// Deferrals is how many times this run has been pushed back
+	Deferrals int `json:"-" gorm:"-"`
+}
+
+// maxCoalesceSeconds bounds debounce and throttle windows to a day
+const maxCoalesceSeconds = 86400
+
+// CoalescePolicy folds bursts of triggers into fewer runs. With a debounce
+// the task runs once the triggers have been quiet for DebounceSeconds; with
+// a throttle it runs at most once per ThrottleSeconds, and triggers inside
+// the window are folded into one run at the window's end. The folded run
+// uses the most recent trigger, e.g. the latest webhook delivery.
+type CoalescePolicy struct {
+	DebounceSeconds int `json:"debounce_seconds,omitempty"`
+	ThrottleSeconds int `json:"throttle_seconds,omitempty"`
+}
+
+func (c CoalescePolicy) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+func (c *CoalescePolicy) Scan(value interface{}) error {
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, c)
+}
+
+// Validate checks the windows; a policy may debounce or throttle, not both
+func (c *CoalescePolicy) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.DebounceSeconds < 0 || c.DebounceSeconds > maxCoalesceSeconds ||
+		c.ThrottleSeconds < 0 || c.ThrottleSeconds > maxCoalesceSeconds {
+		return fmt.Errorf("coalesce windows must be between 0 and %d seconds", maxCoalesceSeconds)
+	}
+	if c.DebounceSeconds > 0 && c.ThrottleSeconds > 0 {
+		return fmt.Errorf("coalesce takes debounce_seconds or throttle_seconds, not both")
+	}
+	return nil
 }
 
+// Debounce returns the debounce window, zero when there is none
+func (c *CoalescePolicy) Debounce() time.Duration {
+	if c == nil {
+		return 0
+	}
+	return time.Duration(c.DebounceSeconds) * time.Second
+}
+
+// Throttle returns the throttle window, zero when there is none
+func (c *CoalescePolicy) Throttle() time.Duration {
+	if c == nil {
+		return 0
+	}
+	return time.Duration(c.ThrottleSeconds) * time.Second
+}
+
+// ResultOutcome marks results for runs the pipeline did not carry out
+type ResultOutcome string
+
+const (
+	// OutcomeRateLimited means the run was deferred by the per-host limiter
+	OutcomeRateLimited ResultOutcome = "rate_limited"
+	// OutcomeCircuitOpen means the destination's circuit breaker was open
+	OutcomeCircuitOpen ResultOutcome = "circuit_open"
+	// OutcomeBlackoutSkipped and OutcomeBlackoutDeferred mean the run fell
+	// inside a blackout on the task's calendar; SkipReason says which
+	OutcomeBlackoutSkipped  ResultOutcome = "blackout_skipped"
+	OutcomeBlackoutDeferred ResultOutcome = "blackout_deferred"
+	// OutcomePausedSkipped, OutcomePausedQueued and OutcomeDrainingSkipped
+	// mean the run fell while the whole scheduler was paused or draining
+	OutcomePausedSkipped   ResultOutcome = "paused_skipped"
+	OutcomePausedQueued    ResultOutcome = "paused_queued"
+	OutcomeDrainingSkipped ResultOutcome = "draining_skipped"
+	// OutcomeDeferralDropped means a deferred run was not re-run because the
+	// task was paused or cancelled meanwhile, or it was deferred too often
+	OutcomeDeferralDropped ResultOutcome = "deferral_dropped"
+)
+
 type TaskResult struct {
-	ID              uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
-	TaskID          uuid.UUID `json:"task_id" gorm:"not null"`
-	RunAt           time.Time `json:"run_at" gorm:"not null"`
-	StatusCode      *int      `json:"status_code"`
-	Success         bool      `json:"success"`
-	ResponseHeaders Headers   `json:"response_headers,omitempty" gorm:"type:jsonb"`
-	ResponseBody    *string   `json:"response_body,omitempty"`
-	ErrorMessage    *string   `json:"error_message,omitempty"`
-	DurationMs      int       `json:"duration_ms"`
-	CreatedAt       time.Time `json:"created_at"`
+	ID              uuid.UUID     `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	TaskID          uuid.UUID     `json:"task_id" gorm:"not null"`
+	RunAt           time.Time     `json:"run_at" gorm:"not null"`
+	StatusCode      *int          `json:"status_code"`
+	Success         bool          `json:"success"`
+	Outcome         ResultOutcome `json:"outcome,omitempty"`
+	ResponseHeaders MultiHeaders  `json:"response_headers,omitempty" gorm:"type:jsonb"`
+	ResponseBody    *string       `json:"response_body,omitempty"`
+	ErrorMessage    *string       `json:"error_message,omitempty"`
+	DurationMs      int           `json:"duration_ms"`
+	RateLimitWaitMs int           `json:"rate_limit_wait_ms,omitempty"`
+	CreatedAt       time.Time     `json:"created_at"`
+
+	// CoalescedTriggers counts the triggers folded into this run by the
+	// task's debounce or throttle, besides the one that started it
+	CoalescedTriggers int `json:"coalesced_triggers,omitempty"`
+
+	// SkipReason explains why a run was not carried out, e.g. the blackout
+	// it fell in
+	SkipReason *string `json:"skip_reason,omitempty"`
+
+	// ResponseBodySize is the number of body bytes read; the body is
+	// truncated when more was sent than the inline head or read limit
+	ResponseBodySize      int64   `json:"response_body_size"`
+	ResponseBodyTruncated bool    `json:"response_body_truncated,omitempty"`
+	ResponseBodyRef       *string `json:"response_body_ref,omitempty"`
+
+	// ExitCode, Stdout and Stderr are set for command actions
+	ExitCode *int    `json:"exit_code,omitempty"`
+	Stdout   *string `json:"stdout,omitempty"`
+	Stderr   *string `json:"stderr,omitempty"`
+
+	// GRPCStatus is the status code name of a grpc action, e.g. "OK" or
+	// "UNAVAILABLE"
+	GRPCStatus *string `json:"grpc_status,omitempty"`
+
+	// Steps is set for http_steps actions
+	Steps StepResults `json:"steps,omitempty" gorm:"type:jsonb"`
+
+	// SMTPReplies is set for email actions
+	SMTPReplies SMTPReplies `json:"smtp_replies,omitempty" gorm:"type:jsonb"`
+
+	// RowCount and Rows are set for sql actions. RowCount is the number of
+	// rows returned or affected; Rows keeps only the first few.
+	RowCount *int64     `json:"row_count,omitempty"`
+	Rows     ResultRows `json:"rows,omitempty" gorm:"type:jsonb"`
+
+	// Request is a redacted snapshot of the request that was sent
+	Request       *RequestSnapshot `json:"request,omitempty" gorm:"type:jsonb"`
+	RedirectChain RedirectChain    `json:"redirect_chain,omitempty" gorm:"type:jsonb"`
+
+	// DeferFor is how long to wait before re-running a deferred task
+	DeferFor time.Duration `json:"-" gorm:"-"`
+
+	// TransportFailed is set when a request went out but got no response,
+	// e.g. on a refused connection or a timeout. Requests that never left,
+	// such as ones refused by the egress policy, do not set it.
+	TransportFailed bool `json:"-" gorm:"-"`
 
 	// Relationship
 	Task Task `json:"task,omitempty" gorm:"foreignKey:TaskID"`
 }
 
+// IsHTTP reports whether the task performs an HTTP request; tasks created
+// before action types existed are HTTP tasks
+func (t *Task) IsHTTP() bool {
+	return t.ActionType == "" || t.ActionType == ActionTypeHTTP
+}
+
+// Jitter returns the task's maximum cron jitter
+func (t *Task) Jitter() time.Duration {
+	return time.Duration(t.JitterSeconds) * time.Second
+}
+
+// DecodeActionConfig unmarshals the task's action configuration into v
+func (t *Task) DecodeActionConfig(v interface{}) error {
+	if len(t.ActionConfig) == 0 {
+		return fmt.Errorf("%s action has no configuration", t.ActionType)
+	}
+	return json.Unmarshal(t.ActionConfig, v)
+}
+
+// NotAttempted reports whether the pipeline short-circuited the run
+// instead of performing it
+func (r *TaskResult) NotAttempted() bool {
+	return r.Outcome != ""
+}
+
 // CreateTaskRequest represents the request payload for creating a task
 type CreateTaskRequest struct {
-	Name    string            `json:"name" binding:"required"`
-	Trigger CreateTaskTrigger `json:"trigger" binding:"required"`
-	Action  CreateTaskAction  `json:"action" binding:"required"`
+	Name      string            `json:"name" binding:"required"`
+	Trigger   CreateTaskTrigger `json:"trigger" binding:"required"`
+	Action    CreateTaskAction  `json:"action" binding:"required"`
+	OnSuccess []FollowUp        `json:"on_success,omitempty"`
+	OnFailure []FollowUp        `json:"on_failure,omitempty"`
+	Calendar  *TaskCalendar     `json:"calendar,omitempty"`
 }
 
 type CreateTaskTrigger struct {
-	Type     TriggerType `json:"type" binding:"required,oneof=one-off cron"`
-	DateTime *time.Time  `json:"datetime,omitempty"`
-	Cron     *string     `json:"cron,omitempty"`
+	Type     TriggerType    `json:"type" binding:"required,oneof=one-off cron webhook"`
+	DateTime *time.Time     `json:"datetime,omitempty"`
+	Cron     *string        `json:"cron,omitempty"`
+	Webhook  *WebhookConfig `json:"webhook,omitempty"`
+
+	// Coalesce debounces or throttles bursts of triggers
+	Coalesce *CoalescePolicy `json:"coalesce,omitempty"`
+
+	// JitterSeconds spreads cron fires; see Task.JitterSeconds
+	JitterSeconds int `json:"jitter_seconds,omitempty"`
 }
 
 // GetTriggerValue returns the appropriate trigger value based on the trigger type
@@ -107,10 +773,54 @@ func (t *CreateTaskTrigger) GetTriggerValue() string {
 }
 
 type CreateTaskAction struct {
-	Method  string            `json:"method" binding:"required"`
-	URL     string            `json:"url" binding:"required,url"`
+	// Type selects the action kind; it defaults to http
+	Type    ActionType        `json:"type,omitempty"`
+	Method  string            `json:"method"`
+	URL     string            `json:"url" binding:"omitempty,url"`
 	Headers map[string]string `json:"headers,omitempty"`
 	Payload interface{}       `json:"payload,omitempty"`
+
+	// TLSProfile names an admin-configured client certificate / CA bundle
+	TLSProfile *string `json:"tls_profile,omitempty"`
+
+	// Proxy is an optional http://, https:// or socks5:// proxy URL
+	Proxy *string `json:"proxy,omitempty"`
+
+	BodyCapture *BodyCapture `json:"body_capture,omitempty"`
+
+	Redirects *RedirectPolicy `json:"redirects,omitempty"`
+
+	// CookieJar keeps an isolated cookie jar for the task, persisted
+	// between runs
+	CookieJar bool `json:"cookie_jar,omitempty"`
+
+	// Config is the typed configuration for non-HTTP action kinds, e.g.
+	// {"argv": [...]} for command actions. Its shape is checked by the
+	// validation hook registered for the action type.
+	Config RawConfig `json:"config,omitempty"`
+}
+
+// GetActionType returns the requested action type, defaulting to http
+func (a *CreateTaskAction) GetActionType() ActionType {
+	if a.Type == "" {
+		return ActionTypeHTTP
+	}
+	return a.Type
+}
+
+// Validate checks that the fields required by the action type are present
+func (a *CreateTaskAction) Validate() error {
+	switch a.GetActionType() {
+	case ActionTypeHTTP:
+		if a.Method == "" || a.URL == "" {
+			return fmt.Errorf("method and url are required for http actions")
+		}
+	default:
+		if len(a.Config) == 0 {
+			return fmt.Errorf("config is required for %s actions", a.Type)
+		}
+	}
+	return nil
 }
 
 // UpdateTaskRequest represents the request payload for updating a task
@@ -118,4 +828,12 @@ type UpdateTaskRequest struct {
 	Name    *string            `json:"name,omitempty"`
 	Trigger *CreateTaskTrigger `json:"trigger,omitempty"`
 	Action  *CreateTaskAction  `json:"action,omitempty"`
+
+	// OnSuccess and OnFailure replace the task's follow-ups when present;
+	// an empty list clears them
+	OnSuccess *[]FollowUp `json:"on_success,omitempty"`
+	OnFailure *[]FollowUp `json:"on_failure,omitempty"`
+
+	// Calendar replaces the task's calendar when present
+	Calendar *TaskCalendar `json:"calendar,omitempty"`
 }
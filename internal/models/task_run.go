@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TaskRunStatus is where a queued run is in its life
+type TaskRunStatus string
+
+const (
+	TaskRunQueued    TaskRunStatus = "queued"
+	TaskRunRunning   TaskRunStatus = "running"
+	TaskRunSucceeded TaskRunStatus = "succeeded"
+	TaskRunFailed    TaskRunStatus = "failed"
+	// TaskRunSkipped means the run was not carried out, e.g. it fell in a
+	// blackout or the task was paused after it was queued
+	TaskRunSkipped TaskRunStatus = "skipped"
+)
+
+// TaskRun is a due run waiting in the queue for a worker, or being worked.
+// A running run whose VisibleAt has passed belongs to a worker that
+// stopped renewing its claim and may be claimed again.
+type TaskRun struct {
+	ID     uuid.UUID     `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TaskID uuid.UUID     `json:"task_id" gorm:"type:uuid;not null;index"`
+	Status TaskRunStatus `json:"status" gorm:"not null;default:queued"`
+	// VisibleAt is when the run may next be claimed
+	VisibleAt time.Time `json:"visible_at" gorm:"not null;default:now();index"`
+	Attempts  int       `json:"attempts" gorm:"not null;default:0"`
+	ClaimedBy *string   `json:"claimed_by,omitempty"`
+
+	CoalescedTriggers int `json:"coalesced_triggers,omitempty"`
+	// ScheduledFor is the occurrence the run is for
+	ScheduledFor *time.Time `json:"scheduled_for,omitempty"`
+
+	ResultID   *uuid.UUID `json:"result_id,omitempty" gorm:"type:uuid"`
+	Error      *string    `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"default:now()"`
+	UpdatedAt  time.Time  `json:"updated_at" gorm:"default:now()"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
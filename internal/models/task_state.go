@@ -0,0 +1,64 @@
+package models
+
+import "fmt"
+
+// TaskEvent is something that moves a task between statuses
+type TaskEvent string
+
+const (
+	TaskEventPause    TaskEvent = "pause"
+	TaskEventResume   TaskEvent = "resume"
+	TaskEventCancel   TaskEvent = "cancel"
+	TaskEventComplete TaskEvent = "complete"
+	TaskEventFail     TaskEvent = "fail"
+)
+
+// taskTransitions is the task state machine. Active is what resume used to
+// set; it behaves like scheduled so such tasks can still be moved on.
+// Completed, failed and cancelled have no way out.
+var taskTransitions = map[TaskStatus]map[TaskEvent]TaskStatus{
+	TaskStatusPending: {
+		TaskEventCancel: TaskStatusCancelled,
+	},
+	TaskStatusScheduled: {
+		TaskEventPause:    TaskStatusPaused,
+		TaskEventCancel:   TaskStatusCancelled,
+		TaskEventComplete: TaskStatusCompleted,
+		TaskEventFail:     TaskStatusFailed,
+	},
+	TaskStatusActive: {
+		TaskEventPause:    TaskStatusPaused,
+		TaskEventResume:   TaskStatusScheduled,
+		TaskEventCancel:   TaskStatusCancelled,
+		TaskEventComplete: TaskStatusCompleted,
+		TaskEventFail:     TaskStatusFailed,
+	},
+	TaskStatusPaused: {
+		TaskEventResume: TaskStatusScheduled,
+		TaskEventCancel: TaskStatusCancelled,
+	},
+}
+
+// TransitionError is returned for an event the task's status does not allow
+type TransitionError struct {
+	From  TaskStatus
+	Event TaskEvent
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cannot %s a task that is %s", e.Event, e.From)
+}
+
+// Terminal reports whether no event can move a task out of the status
+func (s TaskStatus) Terminal() bool {
+	return len(taskTransitions[s]) == 0
+}
+
+// Next returns the status event moves a task in status s to
+func (s TaskStatus) Next(event TaskEvent) (TaskStatus, error) {
+	next, ok := taskTransitions[s][event]
+	if !ok {
+		return s, &TransitionError{From: s, Event: event}
+	}
+	return next, nil
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultWebhookSignatureHeader   = "X-Signature-256"
+	DefaultWebhookIdempotencyHeader = "Idempotency-Key"
+)
+
+// WebhookConfig controls how deliveries to a webhook task are checked
+type WebhookConfig struct {
+	// HMACSecret is a secret reference such as "env:SECRET_GITHUB_HOOK".
+	// When set, deliveries must carry a hex HMAC-SHA256 of the body,
+	// optionally prefixed with "sha256=", in SignatureHeader.
+	HMACSecret      string `json:"hmac_secret,omitempty"`
+	SignatureHeader string `json:"signature_header,omitempty"`
+
+	// IdempotencyHeader names the header used to drop repeated deliveries
+	IdempotencyHeader string `json:"idempotency_header,omitempty"`
+}
+
+// GetSignatureHeader returns the signature header, with its default
+func (w *WebhookConfig) GetSignatureHeader() string {
+	if w == nil || w.SignatureHeader == "" {
+		return DefaultWebhookSignatureHeader
+	}
+	return w.SignatureHeader
+}
+
+// GetIdempotencyHeader returns the idempotency header, with its default
+func (w *WebhookConfig) GetIdempotencyHeader() string {
+	if w == nil || w.IdempotencyHeader == "" {
+		return DefaultWebhookIdempotencyHeader
+	}
+	return w.IdempotencyHeader
+}
+
+func (w WebhookConfig) Value() (driver.Value, error) {
+	return json.Marshal(w)
+}
+
+func (w *WebhookConfig) Scan(value interface{}) error {
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, w)
+}
+
+// InboundRequest is the webhook delivery that started a run. Templates see
+// it as .Webhook; JSON holds the decoded body when it is valid JSON.
+type InboundRequest struct {
+	Body    string
+	Headers map[string][]string
+	JSON    interface{}
+}
+
+// Header returns the first value of a delivery header, ignoring case
+func (r *InboundRequest) Header(name string) string {
+	return http.Header(r.Headers).Get(name)
+}
+
+// WebhookDelivery records an idempotency key a webhook task has accepted
+type WebhookDelivery struct {
+	TaskID         uuid.UUID `json:"task_id" gorm:"type:uuid;primaryKey"`
+	IdempotencyKey string    `json:"idempotency_key" gorm:"primaryKey"`
+	ReceivedAt     time.Time `json:"received_at"`
+}
@@ -0,0 +1,159 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type WorkflowStatus string
+
+const (
+	WorkflowStatusActive    WorkflowStatus = "active"
+	WorkflowStatusCancelled WorkflowStatus = "cancelled"
+)
+
+// EdgeCondition decides whether an edge lets its target run, based on how
+// the source node finished
+type EdgeCondition string
+
+const (
+	EdgeOnSuccess EdgeCondition = "success"
+	EdgeOnFailure EdgeCondition = "failure"
+	EdgeAlways    EdgeCondition = "always"
+)
+
+// WorkflowNode is one step of a workflow; it runs the referenced task
+type WorkflowNode struct {
+	ID     string    `json:"id"`
+	TaskID uuid.UUID `json:"task_id"`
+}
+
+type WorkflowNodes []WorkflowNode
+
+func (n WorkflowNodes) Value() (driver.Value, error) {
+	return json.Marshal(n)
+}
+
+func (n *WorkflowNodes) Scan(value interface{}) error {
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, n)
+}
+
+// WorkflowEdge runs To after From finishes, if Condition holds. The
+// condition defaults to success.
+type WorkflowEdge struct {
+	From      string        `json:"from"`
+	To        string        `json:"to"`
+	Condition EdgeCondition `json:"condition,omitempty"`
+}
+
+// GetCondition returns the edge's condition, defaulting to success
+func (e WorkflowEdge) GetCondition() EdgeCondition {
+	if e.Condition == "" {
+		return EdgeOnSuccess
+	}
+	return e.Condition
+}
+
+type WorkflowEdges []WorkflowEdge
+
+func (e WorkflowEdges) Value() (driver.Value, error) {
+	return json.Marshal(e)
+}
+
+func (e *WorkflowEdges) Scan(value interface{}) error {
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, e)
+}
+
+// Workflow is a DAG of tasks. Nodes without incoming edges are roots and
+// start every run; the rest start when their parents finish.
+type Workflow struct {
+	ID        uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Name      string         `json:"name" gorm:"not null"`
+	Cron      *string        `json:"cron,omitempty"`
+	Nodes     WorkflowNodes  `json:"nodes" gorm:"type:jsonb;not null"`
+	Edges     WorkflowEdges  `json:"edges" gorm:"type:jsonb"`
+	Status    WorkflowStatus `json:"status" gorm:"default:active"`
+	NextRun   *time.Time     `json:"next_run,omitempty"`
+	CreatedAt time.Time      `json:"created_at" gorm:"default:now()"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"default:now()"`
+}
+
+type NodeState string
+
+const (
+	NodeStatePending   NodeState = "pending"
+	NodeStateRunning   NodeState = "running"
+	NodeStateSucceeded NodeState = "succeeded"
+	NodeStateFailed    NodeState = "failed"
+	NodeStateSkipped   NodeState = "skipped"
+)
+
+// Finished reports whether the node will not change state again
+func (s NodeState) Finished() bool {
+	return s == NodeStateSucceeded || s == NodeStateFailed || s == NodeStateSkipped
+}
+
+// NodeRun is one node's progress within a workflow run
+type NodeRun struct {
+	State      NodeState  `json:"state"`
+	ResultID   *uuid.UUID `json:"result_id,omitempty"`
+	Error      *string    `json:"error,omitempty"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// NodeRuns maps node IDs to their progress
+type NodeRuns map[string]NodeRun
+
+func (n NodeRuns) Value() (driver.Value, error) {
+	return json.Marshal(n)
+}
+
+func (n *NodeRuns) Scan(value interface{}) error {
+	bytes, ok := value.([]byte)
+	if !ok {
+		return nil
+	}
+	return json.Unmarshal(bytes, n)
+}
+
+type WorkflowRunStatus string
+
+const (
+	WorkflowRunRunning   WorkflowRunStatus = "running"
+	WorkflowRunSucceeded WorkflowRunStatus = "succeeded"
+	WorkflowRunFailed    WorkflowRunStatus = "failed"
+)
+
+// WorkflowRun records one execution of a workflow. A run fails if any of
+// its nodes failed, even when a failure edge handled it.
+type WorkflowRun struct {
+	ID         uuid.UUID         `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	WorkflowID uuid.UUID         `json:"workflow_id" gorm:"type:uuid;not null;index"`
+	Status     WorkflowRunStatus `json:"status" gorm:"not null"`
+	Nodes      NodeRuns          `json:"nodes" gorm:"type:jsonb"`
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt *time.Time        `json:"finished_at,omitempty"`
+	// Owner is the cluster instance running the run's nodes
+	Owner *string `json:"owner,omitempty"`
+}
+
+// CreateWorkflowRequest represents the request payload for creating a workflow
+type CreateWorkflowRequest struct {
+	Name string `json:"name" binding:"required"`
+	// Cron optionally starts the workflow on a standard 5-field schedule
+	Cron  *string        `json:"cron,omitempty"`
+	Nodes []WorkflowNode `json:"nodes" binding:"required"`
+	Edges []WorkflowEdge `json:"edges,omitempty"`
+}
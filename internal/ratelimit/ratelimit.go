@@ -0,0 +1,156 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limit describes a token bucket: Rate tokens per second, up to Burst
+// tokens banked. A zero rate means unlimited.
+type Limit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// HostLimit overrides the default limit for hosts matching Pattern
+type HostLimit struct {
+	Pattern string `json:"pattern"`
+	Limit
+}
+
+// Config is the on-disk representation of the per-host limits
+type Config struct {
+	Default        Limit       `json:"default"`
+	Hosts          []HostLimit `json:"hosts,omitempty"`
+	MaxWaitSeconds int         `json:"max_wait_seconds,omitempty"`
+}
+
+// HostLimiter hands out tokens from one bucket per destination host
+type HostLimiter struct {
+	config  Config
+	maxWait time.Duration
+	now     func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	limit  Limit
+	tokens float64
+	last   time.Time
+}
+
+// NewHostLimiter validates config and builds a limiter. Reservations that
+// would need to wait longer than max_wait_seconds (default 30) are refused
+// so the caller can defer the run instead of blocking.
+func NewHostLimiter(config Config) (*HostLimiter, error) {
+	limits := []Limit{config.Default}
+	for _, host := range config.Hosts {
+		if host.Pattern == "" {
+			return nil, fmt.Errorf("host rate limit pattern is required")
+		}
+		if _, err := path.Match(strings.ToLower(host.Pattern), ""); err != nil {
+			return nil, fmt.Errorf("invalid host pattern %q: %w", host.Pattern, err)
+		}
+		limits = append(limits, host.Limit)
+	}
+	for _, limit := range limits {
+		if limit.Rate < 0 || limit.Burst < 0 {
+			return nil, fmt.Errorf("rate and burst must not be negative")
+		}
+	}
+
+	maxWait := 30 * time.Second
+	if config.MaxWaitSeconds > 0 {
+		maxWait = time.Duration(config.MaxWaitSeconds) * time.Second
+	}
+
+	return &HostLimiter{
+		config:  config,
+		maxWait: maxWait,
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}, nil
+}
+
+// Load reads a Config JSON document from path
+func Load(path string) (*HostLimiter, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rate limits: %w", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse rate limits: %w", err)
+	}
+
+	return NewHostLimiter(config)
+}
+
+// Reserve takes a token for host. It returns how long the caller must wait
+// before using it, or ok=false (and consumes nothing) when that wait would
+// exceed the limiter's maximum.
+func (l *HostLimiter) Reserve(host string) (wait time.Duration, ok bool) {
+	host = strings.ToLower(host)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, exists := l.buckets[host]
+	if !exists {
+		limit := l.limitFor(host)
+		b = &bucket{limit: limit, tokens: float64(burstOf(limit)), last: l.now()}
+		l.buckets[host] = b
+	}
+
+	if b.limit.Rate <= 0 {
+		return 0, true
+	}
+
+	now := l.now()
+	b.tokens += now.Sub(b.last).Seconds() * b.limit.Rate
+	if max := float64(burstOf(b.limit)); b.tokens > max {
+		b.tokens = max
+	}
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0, true
+	}
+
+	wait = time.Duration(-b.tokens / b.limit.Rate * float64(time.Second))
+	if wait > l.maxWait {
+		b.tokens++
+		return wait, false
+	}
+	return wait, true
+}
+
+// MaxWait is the longest a reservation may wait before it is refused
+func (l *HostLimiter) MaxWait() time.Duration {
+	return l.maxWait
+}
+
+func (l *HostLimiter) limitFor(host string) Limit {
+	for _, override := range l.config.Hosts {
+		if ok, _ := path.Match(strings.ToLower(override.Pattern), host); ok {
+			return override.Limit
+		}
+	}
+	return l.config.Default
+}
+
+func burstOf(limit Limit) int {
+	if limit.Burst > 0 {
+		return limit.Burst
+	}
+	return 1
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"task-scheduler/internal/models"
+)
+
+type CalendarRepository struct {
+	db *gorm.DB
+}
+
+func NewCalendarRepository(db *gorm.DB) *CalendarRepository {
+	return &CalendarRepository{db: db}
+}
+
+func (r *CalendarRepository) Create(cal *models.Calendar) error {
+	return r.db.Create(cal).Error
+}
+
+func (r *CalendarRepository) GetByID(id uuid.UUID) (*models.Calendar, error) {
+	var cal models.Calendar
+	err := r.db.First(&cal, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &cal, nil
+}
+
+func (r *CalendarRepository) List(limit, offset int) ([]models.Calendar, int64, error) {
+	var calendars []models.Calendar
+	var total int64
+
+	query := r.db.Model(&models.Calendar{})
+
+	err := query.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = query.Limit(limit).Offset(offset).Order("created_at DESC").Find(&calendars).Error
+	return calendars, total, err
+}
+
+func (r *CalendarRepository) Update(cal *models.Calendar) error {
+	cal.UpdatedAt = time.Now()
+	return r.db.Save(cal).Error
+}
+
+func (r *CalendarRepository) Delete(id uuid.UUID) error {
+	return r.db.Delete(&models.Calendar{}, "id = ?", id).Error
+}
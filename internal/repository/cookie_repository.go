@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"task-scheduler/internal/models"
+)
+
+type CookieRepository struct {
+	db *gorm.DB
+}
+
+func NewCookieRepository(db *gorm.DB) *CookieRepository {
+	return &CookieRepository{db: db}
+}
+
+// LoadCookies returns the saved jar for a task, or nothing if it has none
+func (r *CookieRepository) LoadCookies(taskID uuid.UUID) (models.StoredCookies, error) {
+	var jar models.TaskCookieJar
+	err := r.db.First(&jar, "task_id = ?", taskID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return jar.Cookies, nil
+}
+
+// SaveCookies replaces the saved jar for a task
+func (r *CookieRepository) SaveCookies(taskID uuid.UUID, cookies models.StoredCookies) error {
+	jar := models.TaskCookieJar{
+		TaskID:    taskID,
+		Cookies:   cookies,
+		UpdatedAt: time.Now(),
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "task_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"cookies", "updated_at"}),
+	}).Create(&jar).Error
+}
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type LeaseRepository struct {
+	db *gorm.DB
+}
+
+func NewLeaseRepository(db *gorm.DB) *LeaseRepository {
+	return &LeaseRepository{db: db}
+}
+
+// TryAcquire takes or renews the named lease for holder. It succeeds when
+// holder already has the lease or the current one has expired. Expiry is
+// judged by the database clock so instances need not agree on the time.
+func (r *LeaseRepository) TryAcquire(name, holder string, ttl time.Duration) (bool, error) {
+	res := r.db.Exec(`INSERT INTO scheduler_leases (name, holder, expires_at, renewed_at)
+		VALUES (?, ?, now() + ? * interval '1 millisecond', now())
+		ON CONFLICT (name) DO UPDATE
+		SET holder = EXCLUDED.holder, expires_at = EXCLUDED.expires_at, renewed_at = EXCLUDED.renewed_at
+		WHERE scheduler_leases.holder = EXCLUDED.holder OR scheduler_leases.expires_at < now()`,
+		name, holder, ttl.Milliseconds())
+	return res.RowsAffected == 1, res.Error
+}
+
+// Release gives up holder's lease so another instance can take it at once
+func (r *LeaseRepository) Release(name, holder string) error {
+	return r.db.Exec(`UPDATE scheduler_leases SET expires_at = now() - interval '1 second'
+		WHERE name = ? AND holder = ?`, name, holder).Error
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"task-scheduler/internal/models"
+)
+
+type OccurrenceRepository struct {
+	db *gorm.DB
+}
+
+func NewOccurrenceRepository(db *gorm.DB) *OccurrenceRepository {
+	return &OccurrenceRepository{db: db}
+}
+
+// Record claims an occurrence of a task and reports whether it is new;
+// false means it was already dispatched, by this or another instance
+func (r *OccurrenceRepository) Record(taskID uuid.UUID, scheduledFor time.Time) (bool, error) {
+	occurrence := models.TaskOccurrence{
+		TaskID:       taskID,
+		ScheduledFor: models.OccurrenceTime(scheduledFor),
+		CreatedAt:    time.Now(),
+	}
+	res := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&occurrence)
+	return res.RowsAffected == 1, res.Error
+}
+
+// RecordWithRun claims the occurrence a queued run is for and inserts the
+// run in the same transaction, so an occurrence is never recorded without
+// its run. It reports false, inserting nothing, when the occurrence was
+// already dispatched.
+func (r *OccurrenceRepository) RecordWithRun(run *models.TaskRun) (bool, error) {
+	recorded := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		occurrence := models.TaskOccurrence{
+			TaskID:       run.TaskID,
+			ScheduledFor: models.OccurrenceTime(*run.ScheduledFor),
+			CreatedAt:    time.Now(),
+		}
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&occurrence)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		recorded = true
+		return tx.Create(run).Error
+	})
+	if err != nil {
+		return false, err
+	}
+	return recorded, nil
+}
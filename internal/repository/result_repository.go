@@ -19,6 +19,15 @@ func (r *ResultRepository) Create(result *models.TaskResult) error {
     return r.db.Create(result).Error
 }
 
+func (r *ResultRepository) GetByID(id uuid.UUID) (*models.TaskResult, error) {
+    var result models.TaskResult
+    err := r.db.First(&result, "id = ?", id).Error
+    if err != nil {
+        return nil, err
+    }
+    return &result, nil
+}
+
 func (r *ResultRepository) GetByTaskID(taskID uuid.UUID, limit, offset int) ([]models.TaskResult, int64, error) {
     var results []models.TaskResult
     var total int64
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"task-scheduler/internal/models"
+)
+
+type RunRepository struct {
+	db *gorm.DB
+}
+
+func NewRunRepository(db *gorm.DB) *RunRepository {
+	return &RunRepository{db: db}
+}
+
+func (r *RunRepository) Enqueue(run *models.TaskRun) error {
+	return r.db.Create(run).Error
+}
+
+// Claim hands workerID the oldest visible run, or nil when there is none.
+// The run stays claimed for timeout unless Extend renews it; after that
+// another worker may claim it again. Runs already claimed maxAttempts
+// times are left for AbandonExhausted.
+func (r *RunRepository) Claim(workerID string, timeout time.Duration, maxAttempts int) (*models.TaskRun, error) {
+	var runs []models.TaskRun
+	err := r.db.Raw(`UPDATE task_runs
+		SET status = ?, claimed_by = ?, attempts = attempts + 1,
+			visible_at = now() + ? * interval '1 millisecond', updated_at = now()
+		WHERE id = (
+			SELECT id FROM task_runs
+			WHERE status IN (?, ?) AND visible_at <= now() AND attempts < ?
+			ORDER BY visible_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		models.TaskRunRunning, workerID, timeout.Milliseconds(),
+		models.TaskRunQueued, models.TaskRunRunning, maxAttempts).Scan(&runs).Error
+	if err != nil || len(runs) == 0 {
+		return nil, err
+	}
+	return &runs[0], nil
+}
+
+// Extend renews workerID's claim on a run; false means the claim was lost
+func (r *RunRepository) Extend(id uuid.UUID, workerID string, timeout time.Duration) (bool, error) {
+	res := r.db.Exec(`UPDATE task_runs SET visible_at = now() + ? * interval '1 millisecond', updated_at = now()
+		WHERE id = ? AND status = ? AND claimed_by = ?`,
+		timeout.Milliseconds(), id, models.TaskRunRunning, workerID)
+	return res.RowsAffected == 1, res.Error
+}
+
+// Complete records a run's outcome; false means workerID no longer held it
+func (r *RunRepository) Complete(id uuid.UUID, workerID string, status models.TaskRunStatus, resultID *uuid.UUID, errMsg *string) (bool, error) {
+	now := time.Now()
+	res := r.db.Model(&models.TaskRun{}).
+		Where("id = ? AND status = ? AND claimed_by = ?", id, models.TaskRunRunning, workerID).
+		Updates(map[string]interface{}{
+			"status":      status,
+			"result_id":   resultID,
+			"error":       errMsg,
+			"finished_at": now,
+			"updated_at":  now,
+		})
+	return res.RowsAffected == 1, res.Error
+}
+
+// AbandonExhausted fails runs whose last claim expired after maxAttempts
+// claims, i.e. runs that keep taking their workers down with them. It
+// returns the tasks of the runs it failed.
+func (r *RunRepository) AbandonExhausted(maxAttempts int) ([]uuid.UUID, error) {
+	var taskIDs []uuid.UUID
+	err := r.db.Raw(`UPDATE task_runs SET status = ?, error = ?, finished_at = now(), updated_at = now()
+		WHERE status = ? AND visible_at <= now() AND attempts >= ?
+		RETURNING task_id`,
+		models.TaskRunFailed, "abandoned: claim expired on every attempt",
+		models.TaskRunRunning, maxAttempts).Scan(&taskIDs).Error
+	return taskIDs, err
+}
+
+func (r *RunRepository) ListByTask(taskID uuid.UUID, limit, offset int) ([]models.TaskRun, int64, error) {
+	var runs []models.TaskRun
+	var total int64
+
+	query := r.db.Model(&models.TaskRun{}).Where("task_id = ?", taskID)
+
+	err := query.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = query.Limit(limit).Offset(offset).Order("created_at DESC").Find(&runs).Error
+	return runs, total, err
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"task-scheduler/internal/models"
+)
+
+// schedulerStateID is the key of the single scheduler_states row
+const schedulerStateID = 1
+
+type SchedulerStateRepository struct {
+	db *gorm.DB
+}
+
+func NewSchedulerStateRepository(db *gorm.DB) *SchedulerStateRepository {
+	return &SchedulerStateRepository{db: db}
+}
+
+// Get returns the persisted maintenance mode, running if none was ever set
+func (r *SchedulerStateRepository) Get() (*models.SchedulerState, error) {
+	var state models.SchedulerState
+	err := r.db.First(&state, "id = ?", schedulerStateID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.SchedulerState{ID: schedulerStateID, Mode: models.SchedulerRunning}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (r *SchedulerStateRepository) Save(state *models.SchedulerState) error {
+	state.ID = schedulerStateID
+	state.UpdatedAt = time.Now()
+	return r.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(state).Error
+}
+
+func (r *SchedulerStateRepository) QueueFire(fire *models.QueuedFire) error {
+	return r.db.Create(fire).Error
+}
+
+// ListQueued returns the queued fires, oldest first
+func (r *SchedulerStateRepository) ListQueued() ([]models.QueuedFire, error) {
+	var fires []models.QueuedFire
+	err := r.db.Order("queued_at ASC").Find(&fires).Error
+	return fires, err
+}
+
+func (r *SchedulerStateRepository) CountQueued() (int64, error) {
+	var count int64
+	err := r.db.Model(&models.QueuedFire{}).Count(&count).Error
+	return count, err
+}
+
+// DeleteQueued takes a queued fire off the queue and reports whether this
+// call removed it; false means another instance already replayed it
+func (r *SchedulerStateRepository) DeleteQueued(id uuid.UUID) (bool, error) {
+	res := r.db.Delete(&models.QueuedFire{}, "id = ?", id)
+	return res.RowsAffected == 1, res.Error
+}
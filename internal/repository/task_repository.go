@@ -54,8 +54,72 @@ func (r *TaskRepository) Update(task *models.Task) error {
     return r.db.Save(task).Error
 }
 
+// UpdateDefinition saves an edited task without touching its status, which
+// only Transition changes, or its run bookkeeping. next_run and the fires
+// deferred into it are only saved when the trigger changed, so a fire
+// moving it on meanwhile is kept. It
+// reports false when the task reached a final status in the meantime.
+func (r *TaskRepository) UpdateDefinition(task *models.Task, triggerChanged bool) (bool, error) {
+    task.UpdatedAt = time.Now()
+    omit := []string{"id", "status", "created_at", "last_run"}
+    if !triggerChanged {
+        omit = append(omit, "next_run", "deferred_triggers")
+    }
+    res := r.db.Model(task).
+        Where("status NOT IN ?", []models.TaskStatus{models.TaskStatusCompleted, models.TaskStatusFailed, models.TaskStatusCancelled}).
+        Select("*").Omit(omit...).Updates(task)
+    return res.RowsAffected == 1, res.Error
+}
+
 func (r *TaskRepository) Delete(id uuid.UUID) error {
-    return r.db.Model(&models.Task{}).Where("id = ?", id).Update("status", models.TaskStatusCancelled).Error
+    _, err := r.Transition(id, models.TaskEventCancel)
+    return err
+}
+
+// Transition moves a task through the state machine in models. The update
+// only applies if the status is unchanged since it was read, so a racing
+// transition makes this one fail with a *models.TransitionError.
+func (r *TaskRepository) Transition(id uuid.UUID, event models.TaskEvent) (*models.Task, error) {
+    return r.TransitionWith(id, event, nil)
+}
+
+// TransitionWith is Transition that also writes the columns changes
+// returns for the task in the same update, so nothing reads the new status
+// without them. changes may update the task it is given to match.
+func (r *TaskRepository) TransitionWith(id uuid.UUID, event models.TaskEvent, changes func(*models.Task) map[string]interface{}) (*models.Task, error) {
+    task, err := r.GetByID(id)
+    if err != nil {
+        return nil, err
+    }
+
+    next, err := task.Status.Next(event)
+    if err != nil {
+        return task, err
+    }
+
+    now := time.Now()
+    fields := map[string]interface{}{}
+    if changes != nil {
+        fields = changes(task)
+    }
+    fields["status"] = next
+    fields["updated_at"] = now
+    res := r.db.Model(&models.Task{}).Where("id = ? AND status = ?", id, task.Status).
+        Updates(fields)
+    if res.Error != nil {
+        return nil, res.Error
+    }
+    if res.RowsAffected == 0 {
+        current, err := r.GetByID(id)
+        if err != nil {
+            return nil, err
+        }
+        return current, &models.TransitionError{From: current.Status, Event: event}
+    }
+
+    task.Status = next
+    task.UpdatedAt = now
+    return task, nil
 }
 
 func (r *TaskRepository) GetScheduledTasks() ([]models.Task, error) {
@@ -68,3 +132,61 @@ func (r *TaskRepository) GetScheduledTasks() ([]models.Task, error) {
 func (r *TaskRepository) UpdateNextRun(id uuid.UUID, nextRun *time.Time) error {
     return r.db.Model(&models.Task{}).Where("id = ?", id).Update("next_run", nextRun).Error
 }
+
+// RetryNextRun sets next_run only if the task has none, so an edit made
+// meanwhile is not overwritten. It reports whether next_run was set.
+func (r *TaskRepository) RetryNextRun(id uuid.UUID, nextRun time.Time) (bool, error) {
+    res := r.db.Model(&models.Task{}).Where("id = ? AND next_run IS NULL", id).Update("next_run", nextRun)
+    return res.RowsAffected == 1, res.Error
+}
+
+// GetDue returns the ID and next_run of scheduled one-off and cron tasks
+// whose next_run is at or before the given time, soonest first
+func (r *TaskRepository) GetDue(before time.Time, limit int) ([]models.Task, error) {
+    var tasks []models.Task
+    err := r.db.Select("id", "next_run").
+        Where("status = ? AND next_run IS NOT NULL AND next_run <= ?", models.TaskStatusScheduled, before).
+        Where("trigger_type IN ?", []models.TriggerType{models.TriggerTypeOneOff, models.TriggerTypeCron}).
+        Order("next_run").Limit(limit).Find(&tasks).Error
+    return tasks, err
+}
+
+// AdvanceNextRun moves next_run from one fire to the next, or clears it
+// when next is nil, and clears the triggers deferred into the fire. It
+// reports false when next_run was no longer from, e.g. because the task
+// was edited meanwhile.
+func (r *TaskRepository) AdvanceNextRun(id uuid.UUID, from time.Time, next *time.Time) (bool, error) {
+    res := r.db.Model(&models.Task{}).Where("id = ? AND next_run = ?", id, from).Updates(map[string]interface{}{
+        "next_run":          next,
+        "deferred_triggers": 0,
+    })
+    return res.RowsAffected == 1, res.Error
+}
+
+// DeferNextRun moves a scheduled task's next_run to until, folding fires
+// due before then into that one run: folded of them are added to the
+// count it reports
+func (r *TaskRepository) DeferNextRun(id uuid.UUID, until time.Time, folded int) error {
+    return r.db.Model(&models.Task{}).Where("id = ? AND status = ?", id, models.TaskStatusScheduled).Updates(map[string]interface{}{
+        "next_run":          until,
+        "deferred_triggers": gorm.Expr("deferred_triggers + ?", folded),
+    }).Error
+}
+
+// GetByWebhookToken finds the webhook task a delivery token belongs to
+func (r *TaskRepository) GetByWebhookToken(token string) (*models.Task, error) {
+    var task models.Task
+    err := r.db.First(&task, "trigger_type = ? AND trigger_value = ?", models.TriggerTypeWebhook, token).Error
+    if err != nil {
+        return nil, err
+    }
+    return &task, nil
+}
+
+// CountByCalendar counts the live tasks attached to a calendar
+func (r *TaskRepository) CountByCalendar(calendarID uuid.UUID) (int64, error) {
+    var count int64
+    err := r.db.Model(&models.Task{}).Where("calendar_id = ? AND status <> ?",
+        calendarID, models.TaskStatusCancelled).Count(&count).Error
+    return count, err
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"task-scheduler/internal/models"
+)
+
+type WebhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+// RecordDelivery stores a delivery's idempotency key and reports whether
+// it is new; false means the task already accepted a delivery with that key
+func (r *WebhookRepository) RecordDelivery(taskID uuid.UUID, idempotencyKey string) (bool, error) {
+	delivery := models.WebhookDelivery{
+		TaskID:         taskID,
+		IdempotencyKey: idempotencyKey,
+		ReceivedAt:     time.Now(),
+	}
+	res := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&delivery)
+	return res.RowsAffected == 1, res.Error
+}
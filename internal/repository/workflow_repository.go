@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"task-scheduler/internal/models"
+)
+
+type WorkflowRepository struct {
+	db *gorm.DB
+}
+
+func NewWorkflowRepository(db *gorm.DB) *WorkflowRepository {
+	return &WorkflowRepository{db: db}
+}
+
+func (r *WorkflowRepository) Create(wf *models.Workflow) error {
+	return r.db.Create(wf).Error
+}
+
+func (r *WorkflowRepository) GetByID(id uuid.UUID) (*models.Workflow, error) {
+	var wf models.Workflow
+	err := r.db.First(&wf, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &wf, nil
+}
+
+func (r *WorkflowRepository) List(limit, offset int) ([]models.Workflow, int64, error) {
+	var workflows []models.Workflow
+	var total int64
+
+	query := r.db.Model(&models.Workflow{}).Where("status = ?", models.WorkflowStatusActive)
+
+	err := query.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = query.Limit(limit).Offset(offset).Order("created_at DESC").Find(&workflows).Error
+	return workflows, total, err
+}
+
+// GetActive returns every workflow that has not been cancelled
+func (r *WorkflowRepository) GetActive() ([]models.Workflow, error) {
+	var workflows []models.Workflow
+	err := r.db.Where("status = ?", models.WorkflowStatusActive).Find(&workflows).Error
+	return workflows, err
+}
+
+func (r *WorkflowRepository) Delete(id uuid.UUID) error {
+	return r.db.Model(&models.Workflow{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":     models.WorkflowStatusCancelled,
+		"updated_at": time.Now(),
+	}).Error
+}
+
+func (r *WorkflowRepository) UpdateNextRun(id uuid.UUID, nextRun *time.Time) error {
+	return r.db.Model(&models.Workflow{}).Where("id = ?", id).Update("next_run", nextRun).Error
+}
+
+// GetDue returns the active workflows whose next_run is at or before
+// before, soonest first, with only the fields needed to schedule them
+func (r *WorkflowRepository) GetDue(before time.Time, limit int) ([]models.Workflow, error) {
+	var workflows []models.Workflow
+	err := r.db.Select("id", "next_run").
+		Where("status = ? AND next_run IS NOT NULL AND next_run <= ?", models.WorkflowStatusActive, before).
+		Order("next_run").Limit(limit).Find(&workflows).Error
+	return workflows, err
+}
+
+// AdvanceNextRun moves next_run from one fire to the next, or clears it
+// when next is nil. It reports false when next_run was no longer from,
+// e.g. because another instance fired the workflow meanwhile.
+func (r *WorkflowRepository) AdvanceNextRun(id uuid.UUID, from time.Time, next *time.Time) (bool, error) {
+	res := r.db.Model(&models.Workflow{}).Where("id = ? AND next_run = ?", id, from).Update("next_run", next)
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *WorkflowRepository) CreateRun(run *models.WorkflowRun) error {
+	return r.db.Create(run).Error
+}
+
+func (r *WorkflowRepository) UpdateRun(run *models.WorkflowRun) error {
+	return r.db.Save(run).Error
+}
+
+func (r *WorkflowRepository) GetRun(id uuid.UUID) (*models.WorkflowRun, error) {
+	var run models.WorkflowRun
+	err := r.db.First(&run, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (r *WorkflowRepository) ListRuns(workflowID uuid.UUID, limit, offset int) ([]models.WorkflowRun, int64, error) {
+	var runs []models.WorkflowRun
+	var total int64
+
+	query := r.db.Model(&models.WorkflowRun{}).Where("workflow_id = ?", workflowID)
+
+	err := query.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = query.Limit(limit).Offset(offset).Order("started_at DESC").Find(&runs).Error
+	return runs, total, err
+}
+
+// FailInterruptedRuns marks runs that were still running when the process
+// stopped as failed; their in-flight nodes will never report back
+func (r *WorkflowRepository) FailInterruptedRuns() (int64, error) {
+	res := r.db.Model(&models.WorkflowRun{}).Where("status = ?", models.WorkflowRunRunning).Updates(map[string]interface{}{
+		"status":      models.WorkflowRunFailed,
+		"finished_at": time.Now(),
+	})
+	return res.RowsAffected, res.Error
+}
+
+// FailOrphanedRuns marks runs as failed whose owner no longer holds its
+// instance lease, named leasePrefix followed by the owner. Runs without an
+// owner were started outside cluster mode and are failed too.
+func (r *WorkflowRepository) FailOrphanedRuns(leasePrefix string) (int64, error) {
+	res := r.db.Model(&models.WorkflowRun{}).Where("status = ?", models.WorkflowRunRunning).
+		Where(`owner IS NULL OR NOT EXISTS (SELECT 1 FROM scheduler_leases
+			WHERE scheduler_leases.name = ? || workflow_runs.owner AND scheduler_leases.expires_at > now())`, leasePrefix).
+		Updates(map[string]interface{}{
+			"status":      models.WorkflowRunFailed,
+			"finished_at": time.Now(),
+		})
+	return res.RowsAffected, res.Error
+}
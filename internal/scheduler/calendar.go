@@ -0,0 +1,176 @@
+package scheduler
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"task-scheduler/internal/calendar"
+	"task-scheduler/internal/cronspec"
+	"task-scheduler/internal/models"
+	"task-scheduler/internal/repository"
+)
+
+// EnableCalendars makes the scheduler honour the blackouts on calendars
+// stored in repo. It must be called before Start.
+func (s *Scheduler) EnableCalendars(repo *repository.CalendarRepository) {
+	s.calendarRepo = repo
+}
+
+// maxFoldedFires bounds the count of cron fires folded into a deferred
+// run, so a long blackout over a frequent task stays cheap to count
+const maxFoldedFires = 100000
+
+// blackoutHold is the one run a task keeps waiting out a blackout for
+// triggers that are not scheduled fires, such as webhook deliveries
+type blackoutHold struct {
+	// task is the latest trigger, which the run goes ahead with
+	task *models.Task
+	// folded counts the earlier triggers it stands for
+	folded int
+	dones  []func(*models.TaskResult)
+}
+
+// holdForBlackout records and skips or defers a run that lands inside a
+// blackout on the task's calendar. It reports whether it took the run.
+func (s *Scheduler) holdForBlackout(task *models.Task, done func(*models.TaskResult)) bool {
+	if s.calendarRepo == nil || task.CalendarID == nil {
+		return false
+	}
+
+	cal, err := s.calendarRepo.GetByID(*task.CalendarID)
+	if err != nil {
+		// A missing calendar should not silently stop the task
+		log.Printf("Failed to load calendar %s for task %s, running anyway: %v", *task.CalendarID, task.ID, err)
+		return false
+	}
+
+	// A scheduled fire is judged by when it was due, not when it got to run
+	now := time.Now()
+	at := now
+	if task.Occurrence != nil {
+		at = *task.Occurrence
+	}
+	blackout, blocked := calendar.Check(cal, at)
+	if !blocked {
+		return false
+	}
+
+	outcome := models.OutcomeBlackoutSkipped
+	if task.OnBlackout == models.BlackoutDefer {
+		if !blackout.Until.After(now) {
+			// The fire was held up until after the blackout anyway
+			return false
+		}
+		outcome = models.OutcomeBlackoutDeferred
+	}
+	reason := blackout.Reason
+	result := &models.TaskResult{
+		ID:                uuid.New(),
+		TaskID:            task.ID,
+		RunAt:             now,
+		Outcome:           outcome,
+		SkipReason:        &reason,
+		CoalescedTriggers: task.Coalesced,
+		CreatedAt:         now,
+	}
+	s.metrics.RecordBlackout(outcome == models.OutcomeBlackoutDeferred)
+	if err := s.resultRepo.Create(result); err != nil {
+		log.Printf("Failed to save result for task %s: %v", task.ID, err)
+	}
+
+	if outcome == models.OutcomeBlackoutSkipped {
+		log.Printf("Skipping task %s: %s", task.ID, reason)
+		done(result)
+		return true
+	}
+
+	log.Printf("Task %s is in %s", task.ID, reason)
+	if task.Occurrence != nil {
+		s.deferFire(task, blackout.Until)
+		done(result)
+		return true
+	}
+	s.holdUntil(task, blackout.Until, done)
+	return true
+}
+
+// deferFire moves a task's next_run to the end of the blackout its fire
+// fell in, so the due loop runs it then, once for this fire and every
+// later one due before then. Keeping it in next_run means it survives a
+// restart and is not repeated by every fire in the blackout.
+func (s *Scheduler) deferFire(task *models.Task, until time.Time) {
+	folded := task.Coalesced
+	if task.TriggerType == models.TriggerTypeCron {
+		if schedule, err := cronspec.Parse(task.TriggerValue, task.ID, task.Jitter()); err == nil {
+			for next := schedule.Next(*task.Occurrence); next.Before(until) && folded < maxFoldedFires; next = schedule.Next(next) {
+				folded++
+			}
+		}
+	}
+
+	if err := s.taskRepo.DeferNextRun(task.ID, until, folded); err != nil {
+		log.Printf("Failed to defer task %s: %v", task.ID, err)
+		return
+	}
+	s.wakeDueLoop()
+	log.Printf("Deferred task %s to %s", task.ID, until.Format(time.RFC3339))
+}
+
+// holdUntil runs a task once the blackout ends. Triggers landing in the
+// blackout while a run is held join it instead of holding one each; the
+// run goes ahead with the latest and reports the others as coalesced.
+func (s *Scheduler) holdUntil(task *models.Task, until time.Time, done func(*models.TaskResult)) {
+	s.blackoutMu.Lock()
+	if hold, ok := s.blackoutHolds[task.ID]; ok {
+		hold.folded += 1 + hold.task.Coalesced
+		hold.task = task
+		hold.dones = append(hold.dones, done)
+		s.blackoutMu.Unlock()
+		return
+	}
+	if task.Deferrals >= maxDeferrals {
+		s.blackoutMu.Unlock()
+		s.dropDeferred(task, fmt.Sprintf("deferred %d times", task.Deferrals), done)
+		return
+	}
+	hold := &blackoutHold{task: task, dones: []func(*models.TaskResult){done}}
+	if s.blackoutHolds == nil {
+		s.blackoutHolds = make(map[uuid.UUID]*blackoutHold)
+	}
+	s.blackoutHolds[task.ID] = hold
+	s.blackoutMu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		timer := time.NewTimer(time.Until(until))
+		defer timer.Stop()
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		s.blackoutMu.Lock()
+		delete(s.blackoutHolds, task.ID)
+		s.blackoutMu.Unlock()
+
+		done := func(result *models.TaskResult) {
+			for _, done := range hold.dones {
+				done(result)
+			}
+		}
+		current, reason := s.reloadTask(hold.task)
+		if reason != "" {
+			s.dropDeferred(hold.task, reason, done)
+			return
+		}
+		current.Coalesced += hold.folded
+		current.Deferrals++
+		s.runTask(current, done)
+	}()
+}
@@ -0,0 +1,47 @@
+package scheduler
+
+import (
+	"log"
+
+	"task-scheduler/internal/cluster"
+)
+
+// Roles reported by Role
+const (
+	RoleStandalone = "standalone"
+	RoleLeader     = "leader"
+	RoleFollower   = "follower"
+)
+
+// EnableCluster makes the scheduler one of several instances sharing the
+// database: only the instance elector makes leader loads tasks and fires
+// them. It must be called before Start.
+func (s *Scheduler) EnableCluster(elector *cluster.Elector) {
+	s.elector = elector
+}
+
+// Role reports whether this instance schedules tasks in a cluster
+func (s *Scheduler) Role() string {
+	switch {
+	case s.elector == nil:
+		return RoleStandalone
+	case s.elector.IsLeader():
+		return RoleLeader
+	default:
+		return RoleFollower
+	}
+}
+
+func (s *Scheduler) isLeader() bool {
+	return s.elector == nil || s.elector.IsLeader()
+}
+
+func (s *Scheduler) runElection() {
+	defer s.wg.Done()
+
+	s.elector.Run(s.ctx, func() {
+		if err := s.startScheduling(); err != nil {
+			log.Printf("Failed to load tasks after becoming leader: %v", err)
+		}
+	}, s.stopScheduling)
+}
@@ -0,0 +1,221 @@
+package scheduler
+
+import (
+	"container/heap"
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"task-scheduler/internal/cronspec"
+	"task-scheduler/internal/models"
+)
+
+const (
+	// duePollInterval is how often the loop rereads next_run, which picks
+	// up tasks created or edited on any instance
+	duePollInterval = 5 * time.Second
+	// dueLookahead is how far ahead each poll caches upcoming fires
+	dueLookahead = time.Minute
+	// duePollLimit caps the fires cached by one poll; later ones are read
+	// by the next poll
+	duePollLimit = 500
+)
+
+// upcomingFire is a task's or workflow's next_run as last read from the
+// database
+type upcomingFire struct {
+	id       uuid.UUID
+	workflow bool
+	at       time.Time
+}
+
+// fireHeap orders upcoming fires soonest first
+type fireHeap []upcomingFire
+
+func (h fireHeap) Len() int           { return len(h) }
+func (h fireHeap) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+func (h fireHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *fireHeap) Push(x any) {
+	*h = append(*h, x.(upcomingFire))
+}
+
+func (h *fireHeap) Pop() any {
+	old := *h
+	fire := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return fire
+}
+
+// wakeDueLoop makes the loop poll now instead of at its next interval
+func (s *Scheduler) wakeDueLoop() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// runDueLoop fires one-off and cron tasks and cron workflows as their
+// next_run comes due until ctx is done. next_run is the only record of what is due; the heap
+// just saves asking the database again before each fire.
+func (s *Scheduler) runDueLoop(ctx context.Context) {
+	defer s.wg.Done()
+
+	var due fireHeap
+	var nextPoll time.Time
+
+	for {
+		if now := time.Now(); !now.Before(nextPoll) {
+			due = s.pollDue(now)
+			nextPoll = now.Add(duePollInterval)
+		}
+
+		for due.Len() > 0 && !due[0].at.After(time.Now()) {
+			if ctx.Err() != nil {
+				return
+			}
+			s.fireDue(heap.Pop(&due).(upcomingFire))
+		}
+
+		wait := time.Until(nextPoll)
+		if due.Len() > 0 {
+			if untilFire := time.Until(due[0].at); untilFire < wait {
+				wait = untilFire
+			}
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-s.wake:
+			nextPoll = time.Time{}
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// pollDue reads the fires due within the lookahead from next_run
+func (s *Scheduler) pollDue(now time.Time) fireHeap {
+	tasks, err := s.taskRepo.GetDue(now.Add(dueLookahead), duePollLimit)
+	if err != nil {
+		log.Printf("Failed to load due tasks: %v", err)
+		return nil
+	}
+
+	due := make(fireHeap, 0, len(tasks))
+	for _, task := range tasks {
+		due = append(due, upcomingFire{id: task.ID, at: *task.NextRun})
+	}
+
+	if s.workflowRepo != nil {
+		workflows, err := s.workflowRepo.GetDue(now.Add(dueLookahead), duePollLimit)
+		if err != nil {
+			log.Printf("Failed to load due workflows: %v", err)
+		}
+		for _, wf := range workflows {
+			due = append(due, upcomingFire{id: wf.ID, workflow: true, at: *wf.NextRun})
+		}
+	}
+
+	heap.Init(&due)
+	return due
+}
+
+// fireDue fires the occurrence at a task's next_run and moves next_run on:
+// a cron task's to its next occurrence, a one-off task's to nothing
+func (s *Scheduler) fireDue(fire upcomingFire) {
+	if fire.workflow {
+		s.fireDueWorkflow(fire)
+		return
+	}
+
+	task, err := s.taskRepo.GetByID(fire.id)
+	if err != nil {
+		log.Printf("Failed to get task %s: %v", fire.id, err)
+		return
+	}
+
+	// The task may have been paused, cancelled or edited since the poll
+	if task.Status != models.TaskStatusScheduled || task.NextRun == nil || !task.NextRun.Equal(fire.at) {
+		return
+	}
+	at := *task.NextRun
+	// Fires folded into this one while deferred past a blackout
+	task.Coalesced = task.DeferredTriggers
+
+	var next *time.Time
+	dispatch := func(task *models.Task) { go s.executeTask(task) }
+	if task.TriggerType == models.TriggerTypeCron {
+		schedule, err := cronspec.Parse(task.TriggerValue, task.ID, task.Jitter())
+		if err != nil {
+			// Left alone it would come due on every poll
+			log.Printf("Failed to parse cron expression of task %s, clearing next_run: %v", task.ID, err)
+			s.advanceNextRun(task.ID, at, nil)
+			return
+		}
+		// Occurrences missed while no instance was scheduling are dropped
+		// apart from this one
+		nextRun := schedule.Next(time.Now())
+		next = &nextRun
+		dispatch = func(task *models.Task) {
+			go s.triggerTask(task, s.executeTask)
+		}
+	}
+
+	if !s.fireOccurrence(task, at, dispatch) {
+		// next_run stays on this occurrence so a later poll retries it
+		return
+	}
+	s.advanceNextRun(task.ID, at, next)
+}
+
+func (s *Scheduler) advanceNextRun(taskID uuid.UUID, from time.Time, next *time.Time) {
+	if _, err := s.taskRepo.AdvanceNextRun(taskID, from, next); err != nil {
+		log.Printf("Failed to update next run of task %s: %v", taskID, err)
+	}
+}
+
+// fireDueWorkflow starts a run of a cron workflow whose next_run is due.
+// next_run is moved on first, so the run starts on the one instance whose
+// update wins even if several fire the same occurrence.
+func (s *Scheduler) fireDueWorkflow(fire upcomingFire) {
+	wf, err := s.workflowRepo.GetByID(fire.id)
+	if err != nil {
+		log.Printf("Failed to get workflow %s: %v", fire.id, err)
+		return
+	}
+
+	// The workflow may have been cancelled or rescheduled since the poll
+	if wf.Status != models.WorkflowStatusActive || wf.NextRun == nil || !wf.NextRun.Equal(fire.at) {
+		return
+	}
+
+	var next *time.Time
+	if wf.Cron != nil && *wf.Cron != "" {
+		schedule, err := cronspec.Parse(*wf.Cron, wf.ID, 0)
+		if err != nil {
+			log.Printf("Failed to parse cron expression of workflow %s, clearing next_run: %v", wf.ID, err)
+		} else {
+			nextRun := schedule.Next(time.Now())
+			next = &nextRun
+		}
+	}
+
+	advanced, err := s.workflowRepo.AdvanceNextRun(wf.ID, fire.at, next)
+	if err != nil {
+		log.Printf("Failed to update next run of workflow %s: %v", wf.ID, err)
+		return
+	}
+	if !advanced || next == nil {
+		return
+	}
+
+	if _, err := s.StartWorkflowRun(wf.ID); err != nil {
+		log.Printf("Failed to start workflow %s: %v", wf.ID, err)
+	}
+}
@@ -0,0 +1,146 @@
+package scheduler
+
+import (
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"task-scheduler/internal/models"
+	"task-scheduler/internal/templating"
+)
+
+// maxFollowUpDepth bounds how long a chain of follow-ups may grow, on top
+// of refusing to run a task that already ran earlier in the chain
+const maxFollowUpDepth = 10
+
+// runFollowUps starts the task's on_success or on_failure follow-ups with
+// the finished run as their parent
+func (s *Scheduler) runFollowUps(task *models.Task, result *models.TaskResult) {
+	followUps := task.OnFailure
+	if result.Success {
+		followUps = task.OnSuccess
+	}
+	if len(followUps) == 0 {
+		return
+	}
+
+	parent := models.NewParentResult(task, result)
+	if len(parent.Chain) > maxFollowUpDepth {
+		log.Printf("Not running follow-ups of task %s: chain is %d tasks deep", task.ID, len(parent.Chain))
+		return
+	}
+
+	for _, followUp := range followUps {
+		switch {
+		case followUp.TaskID != nil:
+			s.runFollowUpTask(*followUp.TaskID, parent)
+		case followUp.Callback != nil:
+			go s.runCallback(task, followUp.Callback, parent)
+		}
+	}
+}
+
+func (s *Scheduler) runFollowUpTask(taskID uuid.UUID, parent *models.ParentResult) {
+	if parent.InChain(taskID) {
+		log.Printf("Not running follow-up task %s of %s: it already ran in this chain", taskID, parent.TaskID)
+		return
+	}
+
+	task, err := s.taskRepo.GetByID(taskID)
+	if err != nil {
+		log.Printf("Failed to get follow-up task %s: %v", taskID, err)
+		return
+	}
+	if task.Status == models.TaskStatusCancelled || task.Status == models.TaskStatusPaused {
+		log.Printf("Skipping follow-up task %s (status: %s)", taskID, task.Status)
+		return
+	}
+
+	// Like workflow nodes, follow-up runs leave the task's own status alone.
+	// They run here rather than through the run queue, which cannot carry
+	// the parent result.
+	task.Parent = parent
+	s.triggerTask(task, func(task *models.Task) {
+		go s.runTask(task, func(*models.TaskResult) {})
+	})
+}
+
+// runCallback renders an inline HTTP callback against the parent result and
+// sends it through the executor pipeline. Callbacks have no task of their
+// own, so their outcome is only logged; callbacks the maintenance mode keeps
+// back are recorded against the task they follow.
+func (s *Scheduler) runCallback(task *models.Task, callback *models.HTTPCallback, parent *models.ParentResult) {
+	callbackTask, err := renderCallback(task, callback, parent)
+	if err != nil {
+		log.Printf("Failed to render callback for task %s: %v", task.ID, err)
+		return
+	}
+
+	for deferrals := 0; ; deferrals++ {
+		state, admitted := s.admit()
+		if !admitted {
+			s.skipCallback(task, state)
+			return
+		}
+		result := s.executor.Execute(callbackTask)
+		s.inFlight.Add(-1)
+
+		if result.Outcome != models.OutcomeRateLimited {
+			s.taskLogger.LogTaskExecution(callbackTask, result)
+			log.Printf("Callback for task %s completed: %s %s (success: %t)",
+				task.ID, callbackTask.Method, callbackTask.URL, result.Success)
+			return
+		}
+
+		// Like a deferred run, the callback waits out the limit and is
+		// admitted again before it goes out
+		if deferrals >= maxDeferrals {
+			log.Printf("Dropping callback of task %s: deferred %d times", task.ID, deferrals)
+			return
+		}
+		s.metrics.RecordRateLimitDeferral()
+		log.Printf("Deferring callback of task %s by %s", task.ID, result.DeferFor)
+
+		timer := time.NewTimer(result.DeferFor)
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+func renderCallback(task *models.Task, callback *models.HTTPCallback, parent *models.ParentResult) (*models.Task, error) {
+	data := templating.NewData(task, time.Now())
+	data.Parent = parent
+
+	url, err := templating.Render(callback.URL, data)
+	if err != nil {
+		return nil, err
+	}
+	headers := make(models.Headers, len(callback.Headers))
+	for key, value := range callback.Headers {
+		if headers[key], err = templating.Render(value, data); err != nil {
+			return nil, err
+		}
+	}
+
+	callbackTask := &models.Task{
+		ID:         task.ID,
+		Name:       task.Name + "/callback",
+		ActionType: models.ActionTypeHTTP,
+		Method:     callback.Method,
+		URL:        url,
+		Headers:    headers,
+	}
+	if callback.Payload != "" {
+		payload, err := templating.Render(callback.Payload, data)
+		if err != nil {
+			return nil, err
+		}
+		callbackTask.Payload = &payload
+	}
+	return callbackTask, nil
+}
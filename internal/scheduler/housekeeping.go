@@ -0,0 +1,78 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"task-scheduler/internal/cluster"
+	"task-scheduler/internal/models"
+)
+
+// housekeepingInterval is how often the scheduling instance cleans up after
+// instances that have gone
+const housekeepingInterval = 30 * time.Second
+
+// runHousekeeping does cleanup that only one instance should do, on the
+// instance that schedules, until ctx is done
+func (s *Scheduler) runHousekeeping(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(housekeepingInterval)
+	defer ticker.Stop()
+
+	for {
+		s.housekeep()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) housekeep() {
+	// A standalone instance fails interrupted runs once, at start
+	if s.workflowRepo != nil && s.elector != nil {
+		failed, err := s.workflowRepo.FailOrphanedRuns(cluster.InstanceLeasePrefix)
+		if err != nil {
+			log.Printf("Failed to fail orphaned workflow runs: %v", err)
+		} else if failed > 0 {
+			log.Printf("Marked %d workflow runs of stopped instances as failed", failed)
+		}
+	}
+
+	// Claim skips runs that used up their attempts; this marks them for good
+	if s.runRepo != nil {
+		if abandoned, err := s.runRepo.AbandonExhausted(s.runQueue.MaxAttempts); err != nil {
+			log.Printf("Failed to abandon exhausted runs: %v", err)
+		} else if len(abandoned) > 0 {
+			log.Printf("Abandoned %d runs that used up their attempts", len(abandoned))
+			for _, taskID := range abandoned {
+				s.failAbandoned(taskID)
+			}
+		}
+	}
+}
+
+// failAbandoned marks a one-off task failed once its run is abandoned.
+// The due loop cleared its next_run when it queued the run, so it would
+// otherwise stay scheduled with nothing left to fire it.
+func (s *Scheduler) failAbandoned(taskID uuid.UUID) {
+	task, err := s.taskRepo.GetByID(taskID)
+	if err != nil {
+		log.Printf("Failed to get task %s: %v", taskID, err)
+		return
+	}
+	if task.TriggerType != models.TriggerTypeOneOff || task.NextRun != nil {
+		return
+	}
+	if _, err := s.taskRepo.Transition(taskID, models.TaskEventFail); err != nil {
+		log.Printf("Failed to fail task %s: %v", taskID, err)
+		return
+	}
+	log.Printf("Task %s failed: its run was abandoned", taskID)
+}
@@ -0,0 +1,339 @@
+package scheduler
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"task-scheduler/internal/models"
+	"task-scheduler/internal/repository"
+)
+
+// modePollInterval is how often an instance rereads the persisted mode, so
+// a mode set through any instance of a cluster reaches all of them
+const modePollInterval = 2 * time.Second
+
+// EnableMaintenance persists the maintenance mode and queued fires in repo
+// so they survive a restart and are shared by every instance using repo.
+// It must be called before Start; without it the mode lives in memory only.
+func (s *Scheduler) EnableMaintenance(repo *repository.SchedulerStateRepository) {
+	s.modeRepo = repo
+}
+
+// Mode returns the current maintenance mode
+func (s *Scheduler) Mode() models.SchedulerMode {
+	s.modeMu.RLock()
+	defer s.modeMu.RUnlock()
+	return s.state.Mode
+}
+
+// Status reports the maintenance mode with the runs in flight and queued
+func (s *Scheduler) Status() models.SchedulerStatus {
+	s.modeMu.RLock()
+	status := models.SchedulerStatus{
+		SchedulerState: s.state,
+		InFlight:       s.inFlight.Load(),
+		Queued:         int64(len(s.queuedRuns)),
+	}
+	s.modeMu.RUnlock()
+
+	if s.modeRepo != nil {
+		if queued, err := s.modeRepo.CountQueued(); err == nil {
+			status.Queued = queued
+		}
+	}
+	status.Drained = status.Mode == models.SchedulerDraining && status.InFlight == 0
+	return status
+}
+
+// Pause stops every run from starting until Resume. Fires in the meantime
+// are recorded and either skipped or queued to run on resume.
+func (s *Scheduler) Pause(onFire models.PausedFireAction, reason *string) (models.SchedulerStatus, error) {
+	if onFire == "" {
+		onFire = models.PausedFireSkip
+	}
+	return s.setMode(models.SchedulerState{Mode: models.SchedulerPaused, OnFire: onFire, Reason: reason})
+}
+
+// Drain lets runs already in flight finish and skips new ones until Resume
+func (s *Scheduler) Drain(reason *string) (models.SchedulerStatus, error) {
+	return s.setMode(models.SchedulerState{Mode: models.SchedulerDraining, Reason: reason})
+}
+
+// Resume returns to normal operation and replays fires queued while paused
+func (s *Scheduler) Resume() (models.SchedulerStatus, error) {
+	status, err := s.setMode(models.SchedulerState{Mode: models.SchedulerRunning})
+	if err != nil {
+		return status, err
+	}
+	s.replayQueued()
+	return s.Status(), nil
+}
+
+func (s *Scheduler) setMode(state models.SchedulerState) (models.SchedulerStatus, error) {
+	state.UpdatedAt = time.Now()
+
+	// A mode read by syncMode before this save must not be applied after it
+	s.modeSyncMu.Lock()
+	defer s.modeSyncMu.Unlock()
+
+	// Holding the lock while switching means no run is admitted under the
+	// old mode once this returns, so InFlight is accurate for draining
+	s.modeMu.Lock()
+	if s.modeRepo != nil {
+		if err := s.modeRepo.Save(&state); err != nil {
+			s.modeMu.Unlock()
+			return s.Status(), fmt.Errorf("failed to save scheduler mode: %w", err)
+		}
+	}
+	s.state = state
+	s.modeMu.Unlock()
+
+	log.Printf("Scheduler mode set to %s", state.Mode)
+	return s.Status(), nil
+}
+
+// loadMode restores the persisted mode and replays fires left queued by a
+// previous process that was resumed before it could run them
+func (s *Scheduler) loadMode() error {
+	state, err := s.modeRepo.Get()
+	if err != nil {
+		return err
+	}
+
+	s.modeMu.Lock()
+	s.state = *state
+	s.modeMu.Unlock()
+
+	if state.Mode != models.SchedulerRunning {
+		log.Printf("Scheduler starting in %s mode", state.Mode)
+		return nil
+	}
+	s.replayQueued()
+	return nil
+}
+
+// runModeSync applies modes set through other instances until Stop
+func (s *Scheduler) runModeSync() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(modePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.syncMode()
+		}
+	}
+}
+
+// syncMode adopts the persisted mode if another instance changed it. An
+// instance that sees the scheduler resumed replays the queued fires too;
+// each fire is replayed by whichever instance takes it off the queue.
+func (s *Scheduler) syncMode() {
+	s.modeSyncMu.Lock()
+	state, err := s.modeRepo.Get()
+	if err != nil {
+		s.modeSyncMu.Unlock()
+		log.Printf("Failed to load scheduler mode: %v", err)
+		return
+	}
+
+	s.modeMu.Lock()
+	previous := s.state
+	changed := previous.Mode != state.Mode || previous.OnFire != state.OnFire
+	if changed {
+		s.state = *state
+	}
+	s.modeMu.Unlock()
+	s.modeSyncMu.Unlock()
+
+	if !changed {
+		return
+	}
+	log.Printf("Scheduler mode set to %s by another instance", state.Mode)
+	if state.Mode == models.SchedulerRunning {
+		s.replayQueued()
+	}
+}
+
+// admit counts a run as in flight if the mode lets it start. Every
+// admitted run must call s.inFlight.Add(-1) when it finishes.
+func (s *Scheduler) admit() (models.SchedulerState, bool) {
+	s.modeMu.RLock()
+	defer s.modeMu.RUnlock()
+
+	if s.state.Mode != models.SchedulerRunning {
+		return s.state, false
+	}
+	s.inFlight.Add(1)
+	return s.state, true
+}
+
+// queueState reports the maintenance mode and whether a run may go in the
+// run queue under it. Draining stops every worker, so a run queued then
+// would wait for Resume instead of being skipped; a fire while paused is
+// queued only when fires are to be queued.
+func (s *Scheduler) queueState() (models.SchedulerState, bool) {
+	s.modeMu.RLock()
+	defer s.modeMu.RUnlock()
+
+	switch s.state.Mode {
+	case models.SchedulerDraining:
+		return s.state, false
+	case models.SchedulerPaused:
+		return s.state, s.state.OnFire == models.PausedFireQueue
+	}
+	return s.state, true
+}
+
+// holdForMode records a run that the maintenance mode does not let start
+// and skips or queues it
+func (s *Scheduler) holdForMode(task *models.Task, state models.SchedulerState, done func(*models.TaskResult)) {
+	now := time.Now()
+	outcome := models.OutcomeDrainingSkipped
+	if state.Mode == models.SchedulerPaused {
+		outcome = models.OutcomePausedSkipped
+		if state.OnFire == models.PausedFireQueue {
+			outcome = models.OutcomePausedQueued
+		}
+	}
+	reason := modeReason(state)
+
+	result := &models.TaskResult{
+		ID:                uuid.New(),
+		TaskID:            task.ID,
+		RunAt:             now,
+		Outcome:           outcome,
+		SkipReason:        &reason,
+		CoalescedTriggers: task.Coalesced,
+		CreatedAt:         now,
+	}
+	if err := s.resultRepo.Create(result); err != nil {
+		log.Printf("Failed to save result for task %s: %v", task.ID, err)
+	}
+
+	if outcome != models.OutcomePausedQueued {
+		log.Printf("Skipping task %s: %s", task.ID, reason)
+		done(result)
+		return
+	}
+
+	// Queue under the lock so a concurrent Resume either sees this fire
+	// or has already switched the mode, in which case it runs now
+	s.modeMu.Lock()
+	if s.state.Mode == models.SchedulerRunning {
+		s.modeMu.Unlock()
+		go s.runTask(task, done)
+		return
+	}
+	fire := &models.QueuedFire{ID: uuid.New(), TaskID: task.ID, QueuedAt: now}
+	if s.modeRepo != nil {
+		if err := s.modeRepo.QueueFire(fire); err != nil {
+			s.modeMu.Unlock()
+			log.Printf("Failed to queue task %s: %v", task.ID, err)
+			done(result)
+			return
+		}
+	}
+	s.queuedRuns[fire.ID] = func() { s.runTask(task, done) }
+	s.modeMu.Unlock()
+
+	log.Printf("Queued task %s until the scheduler resumes", task.ID)
+}
+
+// skipCallback records an inline callback that the maintenance mode did not
+// let out against the task it follows. Callbacks have no task of their own
+// to queue, so they are skipped even when fires are queued.
+func (s *Scheduler) skipCallback(task *models.Task, state models.SchedulerState) {
+	outcome := models.OutcomeDrainingSkipped
+	if state.Mode == models.SchedulerPaused {
+		outcome = models.OutcomePausedSkipped
+	}
+	reason := "callback skipped: " + modeReason(state)
+
+	now := time.Now()
+	result := &models.TaskResult{
+		ID:         uuid.New(),
+		TaskID:     task.ID,
+		RunAt:      now,
+		Outcome:    outcome,
+		SkipReason: &reason,
+		CreatedAt:  now,
+	}
+	if err := s.resultRepo.Create(result); err != nil {
+		log.Printf("Failed to save result for task %s: %v", task.ID, err)
+	}
+	log.Printf("Skipping callback of task %s: %s", task.ID, reason)
+}
+
+// modeReason explains why state keeps runs from starting
+func modeReason(state models.SchedulerState) string {
+	reason := "scheduler is draining"
+	if state.Mode == models.SchedulerPaused {
+		reason = "scheduler is paused"
+	}
+	if state.Reason != nil && *state.Reason != "" {
+		reason += ": " + *state.Reason
+	}
+	return reason
+}
+
+// replayQueued runs the fires queued while paused, oldest first. Fires
+// queued by an earlier process run as plain fires of their task.
+func (s *Scheduler) replayQueued() {
+	s.modeMu.Lock()
+	runs := s.queuedRuns
+	s.queuedRuns = make(map[uuid.UUID]func())
+	s.modeMu.Unlock()
+
+	if s.modeRepo == nil {
+		for _, run := range runs {
+			go run()
+		}
+		return
+	}
+
+	fires, err := s.modeRepo.ListQueued()
+	if err != nil {
+		log.Printf("Failed to load queued fires: %v", err)
+		return
+	}
+
+	replayed := 0
+	for _, fire := range fires {
+		claimed, err := s.modeRepo.DeleteQueued(fire.ID)
+		if err != nil {
+			log.Printf("Failed to dequeue fire %s: %v", fire.ID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		replayed++
+
+		if run, ok := runs[fire.ID]; ok {
+			go run()
+			continue
+		}
+
+		task, err := s.taskRepo.GetByID(fire.TaskID)
+		if err != nil {
+			log.Printf("Failed to get queued task %s: %v", fire.TaskID, err)
+			continue
+		}
+		if task.Status == models.TaskStatusCancelled {
+			continue
+		}
+		go s.executeTask(task)
+	}
+
+	if replayed > 0 {
+		log.Printf("Replayed %d queued fires", replayed)
+	}
+}
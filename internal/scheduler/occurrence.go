@@ -0,0 +1,78 @@
+package scheduler
+
+import (
+	"log"
+	"time"
+
+	"task-scheduler/internal/models"
+	"task-scheduler/internal/repository"
+)
+
+// EnableOccurrences makes the scheduler record every planned fire in repo
+// before dispatching it, so an occurrence seen again after a restart or by
+// a new leader is not fired twice. Without the run queue the recorded run
+// is only held in memory, so a crash before it finishes loses it and each
+// occurrence fires at most once; with it, exactly once. It must be called
+// before Start.
+func (s *Scheduler) EnableOccurrences(repo *repository.OccurrenceRepository) {
+	s.occurrenceRepo = repo
+}
+
+// fireOccurrence dispatches the run of task planned for scheduledFor
+// unless that occurrence was already fired. It reports whether the
+// occurrence is dealt with; false means it could not be recorded and
+// should be tried again later.
+func (s *Scheduler) fireOccurrence(task *models.Task, scheduledFor time.Time, dispatch func(*models.Task)) bool {
+	scheduledFor = models.OccurrenceTime(scheduledFor)
+	task.Occurrence = &scheduledFor
+
+	if s.occurrenceRepo == nil {
+		dispatch(task)
+		return true
+	}
+
+	// A run for the queue goes in with its occurrence record, so a record
+	// never stands for a run that was lost. A cron run held back by a
+	// debounce or throttle is queued later and is recorded on its own, as
+	// is a fire the maintenance mode skips instead of queueing.
+	if s.queuesWithRecord(task) {
+		run := newQueuedRun(task)
+		recorded, err := s.occurrenceRepo.RecordWithRun(run)
+		if err != nil {
+			// Nothing was recorded; the due loop retries it from next_run
+			log.Printf("Failed to queue occurrence %s of task %s: %v", scheduledFor.Format(time.RFC3339), task.ID, err)
+			return false
+		}
+		if !recorded {
+			log.Printf("Skipping occurrence %s of task %s: already fired", scheduledFor.Format(time.RFC3339), task.ID)
+			return true
+		}
+		log.Printf("Queued run %s of task %s", run.ID, task.ID)
+		return true
+	}
+
+	recorded, err := s.occurrenceRepo.Record(task.ID, scheduledFor)
+	if err != nil {
+		// Firing without the record could fire it twice; the due loop
+		// retries it from next_run
+		log.Printf("Failed to record occurrence %s of task %s: %v", scheduledFor.Format(time.RFC3339), task.ID, err)
+		return false
+	}
+	if !recorded {
+		log.Printf("Skipping occurrence %s of task %s: already fired", scheduledFor.Format(time.RFC3339), task.ID)
+		return true
+	}
+
+	dispatch(task)
+	return true
+}
+
+// queuesWithRecord reports whether a fire of task goes straight in the run
+// queue, together with its occurrence record
+func (s *Scheduler) queuesWithRecord(task *models.Task) bool {
+	if s.runRepo == nil || (task.TriggerType == models.TriggerTypeCron && holdsTriggers(task)) {
+		return false
+	}
+	_, queued := s.queueState()
+	return queued
+}
@@ -0,0 +1,196 @@
+package scheduler
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"task-scheduler/internal/models"
+	"task-scheduler/internal/repository"
+)
+
+// RunQueueConfig tunes the task_runs queue and this instance's workers
+type RunQueueConfig struct {
+	// WorkerID names this instance in claimed_by
+	WorkerID string
+	// Workers is how many runs this instance executes at once; zero only
+	// queues runs for other instances
+	Workers int
+	// VisibilityTimeout is how long a claim lasts without renewal. Workers
+	// renew it while a run executes, so it only runs out when a worker dies.
+	VisibilityTimeout time.Duration
+	// MaxAttempts is how many claims a run gets before it is abandoned
+	MaxAttempts int
+	// PollInterval is how long an idle worker waits before looking again
+	PollInterval time.Duration
+}
+
+func (c RunQueueConfig) withDefaults() RunQueueConfig {
+	if c.VisibilityTimeout <= 0 {
+		c.VisibilityTimeout = 5 * time.Minute
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 3
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = time.Second
+	}
+	return c
+}
+
+// EnableRunQueue makes the scheduler put due runs in the task_runs queue
+// instead of executing them itself, and starts cfg.Workers workers that
+// claim runs from it. It must be called before Start.
+func (s *Scheduler) EnableRunQueue(repo *repository.RunRepository, cfg RunQueueConfig) {
+	s.runRepo = repo
+	s.runQueue = cfg.withDefaults()
+}
+
+// enqueueRun queues a due run of task for a worker. A one-off task is
+// fired once per next_run, which the due loop clears, so it needs no check
+// for a run already queued.
+func (s *Scheduler) enqueueRun(task *models.Task) {
+	run := newQueuedRun(task)
+	if err := s.runRepo.Enqueue(run); err != nil {
+		log.Printf("Failed to queue run of task %s: %v", task.ID, err)
+		return
+	}
+	log.Printf("Queued run %s of task %s", run.ID, task.ID)
+}
+
+// newQueuedRun is the queue entry for a due run of task
+func newQueuedRun(task *models.Task) *models.TaskRun {
+	return &models.TaskRun{
+		TaskID:            task.ID,
+		Status:            models.TaskRunQueued,
+		CoalescedTriggers: task.Coalesced,
+		ScheduledFor:      task.Occurrence,
+	}
+}
+
+func (s *Scheduler) startWorkers() {
+	for i := 0; i < s.runQueue.Workers; i++ {
+		s.wg.Add(1)
+		go s.runWorker()
+	}
+	if s.runQueue.Workers > 0 {
+		log.Printf("Started %d run queue workers as %s", s.runQueue.Workers, s.runQueue.WorkerID)
+	}
+}
+
+func (s *Scheduler) runWorker() {
+	defer s.wg.Done()
+
+	for s.ctx.Err() == nil {
+		if s.workNext() {
+			continue
+		}
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(s.runQueue.PollInterval):
+		}
+	}
+}
+
+// workNext claims and executes one run, reporting whether there was one
+func (s *Scheduler) workNext() bool {
+	// Draining applies to every instance, so queued runs wait for Resume;
+	// the due loop skips fires rather than queue them meanwhile
+	if s.Mode() == models.SchedulerDraining {
+		return false
+	}
+
+	run, err := s.runRepo.Claim(s.runQueue.WorkerID, s.runQueue.VisibilityTimeout, s.runQueue.MaxAttempts)
+	if err != nil {
+		log.Printf("Failed to claim a run: %v", err)
+		return false
+	}
+	if run == nil {
+		return false
+	}
+	if run.Attempts > 1 {
+		log.Printf("Reclaimed run %s of task %s (attempt %d)", run.ID, run.TaskID, run.Attempts)
+	}
+
+	s.workRun(run)
+	return true
+}
+
+// workRun executes a claimed run, renewing the claim until the run is done
+func (s *Scheduler) workRun(run *models.TaskRun) {
+	task, err := s.taskRepo.GetByID(run.TaskID)
+	if err != nil {
+		s.completeRun(run, models.TaskRunFailed, nil, fmt.Sprintf("task not found: %v", err))
+		return
+	}
+	if task.Status != models.TaskStatusScheduled {
+		s.completeRun(run, models.TaskRunSkipped, nil, fmt.Sprintf("task is %s", task.Status))
+		return
+	}
+	task.Coalesced = run.CoalescedTriggers
+	task.Occurrence = run.ScheduledFor
+
+	stop := make(chan struct{})
+	go s.renewClaim(run, stop)
+
+	// done may come after runTask returns when the run is deferred, e.g.
+	// by a rate limit; the claim is renewed until then
+	s.runTask(task, func(result *models.TaskResult) {
+		close(stop)
+
+		status := models.TaskRunFailed
+		switch {
+		case result.Outcome != "":
+			status = models.TaskRunSkipped
+		case result.Success:
+			status = models.TaskRunSucceeded
+		}
+		s.completeRun(run, status, result, "")
+		s.completeOneOff(task, result)
+	})
+}
+
+func (s *Scheduler) renewClaim(run *models.TaskRun, stop chan struct{}) {
+	ticker := time.NewTicker(s.runQueue.VisibilityTimeout / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-s.ctx.Done():
+			// The claim runs out and another worker picks the run up
+			return
+		case <-ticker.C:
+			held, err := s.runRepo.Extend(run.ID, s.runQueue.WorkerID, s.runQueue.VisibilityTimeout)
+			if err != nil {
+				log.Printf("Failed to renew claim on run %s: %v", run.ID, err)
+			} else if !held {
+				log.Printf("Lost claim on run %s; another worker may run it again", run.ID)
+				return
+			}
+		}
+	}
+}
+
+func (s *Scheduler) completeRun(run *models.TaskRun, status models.TaskRunStatus, result *models.TaskResult, failure string) {
+	var resultID *uuid.UUID
+	if result != nil {
+		resultID = &result.ID
+	}
+	var errMsg *string
+	if failure != "" {
+		errMsg = &failure
+	}
+
+	held, err := s.runRepo.Complete(run.ID, s.runQueue.WorkerID, status, resultID, errMsg)
+	if err != nil {
+		log.Printf("Failed to complete run %s: %v", run.ID, err)
+	} else if !held {
+		log.Printf("Run %s finished after its claim was lost", run.ID)
+	}
+}
@@ -2,13 +2,17 @@ package scheduler
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/robfig/cron/v3"
 
+	"task-scheduler/internal/cluster"
+	"task-scheduler/internal/coalesce"
+	"task-scheduler/internal/cronspec"
 	"task-scheduler/internal/executor"
 	"task-scheduler/internal/logger"
 	"task-scheduler/internal/metrics"
@@ -17,53 +21,103 @@ import (
 )
 
 type Scheduler struct {
-	taskRepo    *repository.TaskRepository
-	resultRepo  *repository.ResultRepository
-	executor    executor.ExecutorInterface
-	taskLogger  *logger.TaskLogger
-	metrics     *metrics.Metrics
-	cron        *cron.Cron
-	oneOffTasks map[uuid.UUID]*time.Timer
-	mu          sync.RWMutex
-	ctx         context.Context
-	cancel      context.CancelFunc
-	wg          sync.WaitGroup
+	taskRepo   *repository.TaskRepository
+	resultRepo *repository.ResultRepository
+	executor   executor.ExecutorInterface
+	taskLogger *logger.TaskLogger
+	metrics    *metrics.Metrics
+	coalescer  *coalesce.Coalescer
+	mu         sync.RWMutex
+
+	// The due loop fires tasks and workflows from next_run; see due_loop.go
+	wake             chan struct{}
+	cancelScheduling context.CancelFunc
+
+	// Workflows are optional; see EnableWorkflows
+	workflowRepo *repository.WorkflowRepository
+	workflowMu   sync.Mutex
+
+	// Calendars are optional; see EnableCalendars
+	calendarRepo  *repository.CalendarRepository
+	blackoutHolds map[uuid.UUID]*blackoutHold
+	blackoutMu    sync.Mutex
+
+	// Maintenance mode; see EnableMaintenance
+	modeRepo   *repository.SchedulerStateRepository
+	state      models.SchedulerState
+	queuedRuns map[uuid.UUID]func()
+	inFlight   atomic.Int64
+	modeMu     sync.RWMutex
+	modeSyncMu sync.Mutex
+
+	// Cluster mode is optional; see EnableCluster
+	elector *cluster.Elector
+
+	// The run queue is optional; see EnableRunQueue
+	runRepo  *repository.RunRepository
+	runQueue RunQueueConfig
+
+	// Occurrence records are optional; see EnableOccurrences
+	occurrenceRepo *repository.OccurrenceRepository
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
 }
 
 func NewScheduler(taskRepo *repository.TaskRepository, resultRepo *repository.ResultRepository,
-	httpExecutor *executor.HTTPExecutor, taskLogger *logger.TaskLogger, metrics *metrics.Metrics) *Scheduler {
+	taskExecutor executor.ExecutorInterface, taskLogger *logger.TaskLogger, metrics *metrics.Metrics) *Scheduler {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	// Wrap executor with retry logic
-	retryExecutor := executor.NewRetryExecutor(httpExecutor, 2, 5*time.Second)
-
 	return &Scheduler{
-		taskRepo:    taskRepo,
-		resultRepo:  resultRepo,
-		executor:    retryExecutor,
-		taskLogger:  taskLogger,
-		metrics:     metrics,
-		cron:        cron.New(cron.WithSeconds()),
-		oneOffTasks: make(map[uuid.UUID]*time.Timer),
-		ctx:         ctx,
-		cancel:      cancel,
+		taskRepo:   taskRepo,
+		resultRepo: resultRepo,
+		executor:   taskExecutor,
+		taskLogger: taskLogger,
+		metrics:    metrics,
+		coalescer:  coalesce.New(),
+		wake:       make(chan struct{}, 1),
+		state:      models.SchedulerState{Mode: models.SchedulerRunning, UpdatedAt: time.Now()},
+		queuedRuns: make(map[uuid.UUID]func()),
+		ctx:        ctx,
+		cancel:     cancel,
 	}
 }
 
 func (s *Scheduler) Start() error {
 	log.Println("Starting task scheduler...")
 
-	// Start cron scheduler
-	s.cron.Start()
+	if s.modeRepo != nil {
+		if err := s.loadMode(); err != nil {
+			return err
+		}
+		s.wg.Add(1)
+		go s.runModeSync()
+	}
+
+	// Runs left running by this process's previous life will never finish.
+	// In a cluster the leader fails them once their owner's lease runs out.
+	if s.workflowRepo != nil && s.elector == nil {
+		if interrupted, err := s.workflowRepo.FailInterruptedRuns(); err != nil {
+			return err
+		} else if interrupted > 0 {
+			log.Printf("Marked %d interrupted workflow runs as failed", interrupted)
+		}
+	}
 
-	// Load existing tasks from database
-	if err := s.loadExistingTasks(); err != nil {
+	if s.elector != nil {
+		// Only the leader loads and fires tasks; see cluster.go
+		s.wg.Add(1)
+		go s.runElection()
+	} else if err := s.startScheduling(); err != nil {
 		return err
 	}
 
-	// Start periodic task checker for missed tasks
-	s.wg.Add(1)
-	go s.periodicTaskChecker()
+	// Workers claim from the shared queue whether or not this instance
+	// schedules
+	if s.runRepo != nil {
+		s.startWorkers()
+	}
 
 	log.Println("Task scheduler started successfully")
 	return nil
@@ -75,16 +129,11 @@ func (s *Scheduler) Stop() {
 	// Cancel context to stop all goroutines
 	s.cancel()
 
-	// Stop cron scheduler
-	s.cron.Stop()
+	// Stop the due loop and housekeeping
+	s.stopScheduling()
 
-	// Cancel all one-off timers
-	s.mu.Lock()
-	for _, timer := range s.oneOffTasks {
-		timer.Stop()
-	}
-	s.oneOffTasks = make(map[uuid.UUID]*time.Timer)
-	s.mu.Unlock()
+	// Drop runs waiting out a debounce or throttle
+	s.coalescer.Stop()
 
 	// Wait for all goroutines to finish
 	s.wg.Wait()
@@ -92,123 +141,239 @@ func (s *Scheduler) Stop() {
 	log.Println("Task scheduler stopped")
 }
 
-func (s *Scheduler) ScheduleTask(task *models.Task) error {
+// startScheduling starts the loop that fires tasks and workflows as their
+// next_run comes due, and the housekeeping only one instance should do
+func (s *Scheduler) startScheduling() error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	ctx, cancel := context.WithCancel(s.ctx)
+	s.cancelScheduling = cancel
+	s.mu.Unlock()
 
-	switch task.TriggerType {
-	case models.TriggerTypeOneOff:
-		return s.scheduleOneOffTask(task)
-	case models.TriggerTypeCron:
-		return s.scheduleCronTask(task)
-	default:
-		log.Printf("Unknown trigger type: %s for task %s", task.TriggerType, task.ID)
-		return nil
+	// Tasks due while no instance was scheduling are fired by the loop's
+	// first poll
+	s.wg.Add(2)
+	go s.runDueLoop(ctx)
+	go s.runHousekeeping(ctx)
+
+	if s.workflowRepo != nil {
+		if err := s.loadExistingWorkflows(); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
-func (s *Scheduler) UnscheduleTask(taskID uuid.UUID) {
+// stopScheduling stops what startScheduling started, so it can be started
+// again
+func (s *Scheduler) stopScheduling() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Remove from one-off tasks if exists
-	if timer, exists := s.oneOffTasks[taskID]; exists {
-		timer.Stop()
-		delete(s.oneOffTasks, taskID)
-		log.Printf("Unscheduled one-off task: %s", taskID)
+	if s.cancelScheduling != nil {
+		s.cancelScheduling()
+		s.cancelScheduling = nil
 	}
-
-	// Note: Cron tasks are handled by checking status in the execution function
-	log.Printf("Task unscheduled: %s", taskID)
 }
 
-func (s *Scheduler) scheduleOneOffTask(task *models.Task) error {
-	if task.TriggerType != models.TriggerTypeOneOff {
+// ScheduleTask checks a task's trigger and fills in its next_run if it has
+// none. The due loop fires it from next_run, so tasks whose next_run is set
+// some other way are picked up without this.
+func (s *Scheduler) ScheduleTask(task *models.Task) error {
+	switch task.TriggerType {
+	case models.TriggerTypeOneOff, models.TriggerTypeCron:
+	case models.TriggerTypeWebhook:
+		// Webhook tasks only run when a delivery arrives
+		return nil
+	default:
+		log.Printf("Unknown trigger type: %s for task %s", task.TriggerType, task.ID)
 		return nil
 	}
 
-	// Parse trigger time from TriggerValue
-	triggerTime, err := time.Parse(time.RFC3339, task.TriggerValue)
+	nextRun, err := firstRun(task)
 	if err != nil {
-		log.Printf("Failed to parse trigger time for task %s: %v", task.ID, err)
+		log.Printf("Failed to schedule task %s: %v", task.ID, err)
 		return err
 	}
-
-	// Cancel existing timer if any
-	if timer, exists := s.oneOffTasks[task.ID]; exists {
-		timer.Stop()
+	if task.NextRun == nil {
+		if err := s.taskRepo.UpdateNextRun(task.ID, &nextRun); err != nil {
+			return err
+		}
+		task.NextRun = &nextRun
 	}
 
-	duration := time.Until(triggerTime)
-	if duration <= 0 {
-		// Task should run immediately
-		go s.executeTask(task)
-		return nil
-	}
+	s.wakeDueLoop()
+	log.Printf("Scheduled task %s to run at %s", task.ID, task.NextRun.Format(time.RFC3339))
+	return nil
+}
 
-	timer := time.AfterFunc(duration, func() {
-		s.executeTask(task)
+// UnscheduleTask drops a task from the due loop's cache. The loop checks
+// the task's status before each fire, so this only saves a lookup.
+func (s *Scheduler) UnscheduleTask(taskID uuid.UUID) {
+	s.wakeDueLoop()
+	log.Printf("Task unscheduled: %s", taskID)
+}
 
-		// Clean up timer reference
-		s.mu.Lock()
-		delete(s.oneOffTasks, task.ID)
-		s.mu.Unlock()
+// RunTaskNow runs a task on demand. The trigger goes through the task's
+// debounce or throttle like a webhook delivery does.
+func (s *Scheduler) RunTaskNow(task *models.Task) {
+	s.triggerTask(task, func(task *models.Task) {
+		go s.runTask(task, func(*models.TaskResult) {})
 	})
+}
 
-	s.oneOffTasks[task.ID] = timer
-	log.Printf("Scheduled one-off task %s to run at %s", task.ID, triggerTime.Format(time.RFC3339))
+// RunWebhookTask runs a webhook task for one accepted delivery. It runs
+// in this process even with the run queue, since a queued run has nowhere
+// to keep the delivery it renders.
+func (s *Scheduler) RunWebhookTask(task *models.Task, inbound *models.InboundRequest) {
+	task.Inbound = inbound
+	s.triggerTask(task, func(task *models.Task) {
+		go s.runTask(task, func(*models.TaskResult) {})
+	})
+}
 
-	return nil
+// triggerTask passes a trigger through the task's debounce or throttle, if
+// it has one, before calling run. A run held back that way is for the task
+// as it was when triggered, so the task is loaded again before it goes
+// ahead and the run is dropped if the task was paused or cancelled since.
+func (s *Scheduler) triggerTask(task *models.Task, run func(*models.Task)) {
+	held := holdsTriggers(task)
+	s.coalescer.Trigger(task.ID, task.Coalesce, func(coalesced int) {
+		if s.ctx.Err() != nil {
+			return
+		}
+		if held {
+			current, reason := s.reloadTask(task)
+			if reason != "" {
+				log.Printf("Dropping coalesced run of task %s: %s", task.ID, reason)
+				return
+			}
+			task = current
+		}
+		if coalesced > 0 {
+			log.Printf("Running task %s once for %d coalesced triggers", task.ID, coalesced+1)
+		}
+		task.Coalesced += coalesced
+		run(task)
+	})
 }
 
-func (s *Scheduler) scheduleCronTask(task *models.Task) error {
-	if task.TriggerType != models.TriggerTypeCron {
-		return nil
+// holdsTriggers reports whether triggerTask may hold a task's runs back
+func holdsTriggers(task *models.Task) bool {
+	return task.Coalesce.Debounce() > 0 || task.Coalesce.Throttle() > 0
+}
+
+// reloadTask loads a task again for a run that was held back, keeping the
+// run's own context. It returns why the run should be dropped instead, if
+// the task is gone or was paused or cancelled meanwhile.
+func (s *Scheduler) reloadTask(task *models.Task) (*models.Task, string) {
+	current, err := s.taskRepo.GetByID(task.ID)
+	if err != nil {
+		return nil, fmt.Sprintf("task not found: %v", err)
+	}
+	if current.Status == models.TaskStatusPaused || current.Status == models.TaskStatusCancelled {
+		return nil, fmt.Sprintf("task is %s", current.Status)
 	}
 
-	cronExpr := task.TriggerValue
+	current.Parent = task.Parent
+	current.Inbound = task.Inbound
+	current.Coalesced = task.Coalesced
+	current.Occurrence = task.Occurrence
+	current.Deferrals = task.Deferrals
+	return current, ""
+}
 
-	_, err := s.cron.AddFunc(cronExpr, func() {
-		// Check if task is still active before executing
-		currentTask, err := s.taskRepo.GetByID(task.ID)
-		if err != nil {
-			log.Printf("Failed to get task %s: %v", task.ID, err)
+// executeTask runs a due fire of a task, or queues it for a worker when
+// the run queue is enabled
+func (s *Scheduler) executeTask(task *models.Task) {
+	done := func(result *models.TaskResult) {
+		s.completeOneOff(task, result)
+	}
+	if s.runRepo != nil {
+		if state, queued := s.queueState(); !queued {
+			s.holdForMode(task, state, done)
 			return
 		}
+		s.enqueueRun(task)
+		return
+	}
+	s.runTask(task, done)
+}
 
-		if currentTask.Status != models.TaskStatusScheduled {
-			log.Printf("Skipping execution of task %s (status: %s)", task.ID, currentTask.Status)
-			return
-		}
+// oneOffRetryDelay is how long a one-off task whose run did not go ahead
+// waits before it is tried again
+const oneOffRetryDelay = time.Minute
 
-		s.executeTask(currentTask)
+// completeOneOff marks a one-off task completed after its run, or after
+// the run was skipped on purpose, e.g. for a blackout. A run that could
+// not go ahead, because a circuit was open or a deferred run was dropped,
+// leaves the task scheduled and puts its next_run back so it is tried
+// again.
+func (s *Scheduler) completeOneOff(task *models.Task, result *models.TaskResult) {
+	if task.TriggerType != models.TriggerTypeOneOff {
+		return
+	}
 
-		// Update next run time
-		if nextRun, err := s.calculateNextCronRun(currentTask.TriggerValue); err == nil {
-			s.taskRepo.UpdateNextRun(currentTask.ID, &nextRun)
+	switch result.Outcome {
+	case models.OutcomeBlackoutDeferred:
+		// The fire already moved next_run to the end of the blackout
+		return
+	case models.OutcomeCircuitOpen, models.OutcomeDeferralDropped, models.OutcomePausedQueued:
+		// A paused_queued result only gets here when it could not be queued
+		retryAt := time.Now().Add(oneOffRetryDelay)
+		retried, err := s.taskRepo.RetryNextRun(task.ID, retryAt)
+		if err != nil {
+			log.Printf("Failed to reschedule task %s: %v", task.ID, err)
+			return
 		}
-	})
-
-	if err != nil {
-		log.Printf("Failed to schedule cron task %s: %v", task.ID, err)
-		return err
+		if retried {
+			log.Printf("Task %s did not run (%s); trying again at %s", task.ID, result.Outcome, retryAt.Format(time.RFC3339))
+		}
+		return
 	}
 
-	log.Printf("Scheduled cron task %s with expression: %s", task.ID, cronExpr)
-	return nil
+	if _, err := s.taskRepo.Transition(task.ID, models.TaskEventComplete); err != nil {
+		log.Printf("Failed to complete task %s: %v", task.ID, err)
+	}
 }
 
-func (s *Scheduler) executeTask(task *models.Task) {
+// runTask executes a task and records its result, then calls done with it.
+// Deferred runs call done once they finally go ahead.
+func (s *Scheduler) runTask(task *models.Task, done func(*models.TaskResult)) {
+	state, admitted := s.admit()
+	if !admitted {
+		s.holdForMode(task, state, done)
+		return
+	}
+	defer s.inFlight.Add(-1)
+
+	if s.holdForBlackout(task, done) {
+		return
+	}
+
 	log.Printf("Executing task: %s (%s)", task.ID, task.Name)
 
 	startTime := time.Now()
 	result := s.executor.Execute(task)
 	result.RunAt = startTime
 	result.TaskID = task.ID
+	result.CoalescedTriggers = task.Coalesced
+
+	if result.Outcome == models.OutcomeRateLimited {
+		s.metrics.RecordRateLimitDeferral()
+		s.deferTask(task, result.DeferFor, done)
+		return
+	}
 
 	// Record metrics
-	duration := time.Duration(result.DurationMs) * time.Millisecond
-	s.metrics.RecordTaskExecution(duration, result.Success)
+	if result.Outcome == models.OutcomeCircuitOpen {
+		s.metrics.RecordCircuitOpen()
+	} else {
+		duration := time.Duration(result.DurationMs) * time.Millisecond
+		s.metrics.RecordTaskExecution(duration, result.Success)
+	}
+	if result.RateLimitWaitMs > 0 {
+		s.metrics.RecordRateLimitWait(time.Duration(result.RateLimitWaitMs) * time.Millisecond)
+	}
 
 	// Log execution
 	s.taskLogger.LogTaskExecution(task, result)
@@ -218,80 +383,77 @@ func (s *Scheduler) executeTask(task *models.Task) {
 		log.Printf("Failed to save result for task %s: %v", task.ID, err)
 	}
 
-	// Update task status if it's a one-off task
-	if task.TriggerType == models.TriggerTypeOneOff {
-		task.Status = models.TaskStatusCompleted
-		if err := s.taskRepo.Update(task); err != nil {
-			log.Printf("Failed to update task status for %s: %v", task.ID, err)
-		}
-	}
+	done(result)
+	s.runFollowUps(task, result)
 
 	log.Printf("Task execution completed: %s (success: %t, duration: %dms)",
 		task.ID, result.Success, result.DurationMs)
 }
 
-func (s *Scheduler) loadExistingTasks() error {
-	log.Println("Loading existing tasks from database...")
-
-	tasks, err := s.taskRepo.GetScheduledTasks()
-	if err != nil {
-		return err
-	}
+// maxDeferrals caps how often one run is pushed back before it is dropped
+const maxDeferrals = 10
 
-	for _, task := range tasks {
-		if err := s.ScheduleTask(&task); err != nil {
-			log.Printf("Failed to schedule task %s: %v", task.ID, err)
-			continue
-		}
+// deferTask re-runs a task that the pipeline pushed back, e.g. because its
+// destination host is over its rate limit. The task is loaded again first,
+// so a run of a task paused or cancelled meanwhile is dropped.
+func (s *Scheduler) deferTask(task *models.Task, delay time.Duration, done func(*models.TaskResult)) {
+	if task.Deferrals >= maxDeferrals {
+		s.dropDeferred(task, fmt.Sprintf("deferred %d times", task.Deferrals), done)
+		return
 	}
+	log.Printf("Deferring task %s by %s", task.ID, delay)
 
-	log.Printf("Loaded %d existing tasks", len(tasks))
-	return nil
-}
-
-func (s *Scheduler) periodicTaskChecker() {
-	defer s.wg.Done()
-
-	ticker := time.NewTicker(1 * time.Minute)
-	defer ticker.Stop()
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
 
-	for {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
 		select {
 		case <-s.ctx.Done():
 			return
-		case <-ticker.C:
-			s.checkMissedTasks()
+		case <-timer.C:
 		}
-	}
+
+		current, reason := s.reloadTask(task)
+		if reason != "" {
+			s.dropDeferred(task, reason, done)
+			return
+		}
+		current.Deferrals++
+		s.runTask(current, done)
+	}()
 }
 
-func (s *Scheduler) checkMissedTasks() {
-	tasks, err := s.taskRepo.GetScheduledTasks()
-	if err != nil {
-		log.Printf("Failed to check for missed tasks: %v", err)
-		return
-	}
+// dropDeferred records a deferred run that will not be re-run
+func (s *Scheduler) dropDeferred(task *models.Task, reason string, done func(*models.TaskResult)) {
+	log.Printf("Dropping deferred run of task %s: %s", task.ID, reason)
 
 	now := time.Now()
-	for _, task := range tasks {
-		if task.NextRun != nil && task.NextRun.Before(now) {
-			// This task should have run already
-			if task.TriggerType == models.TriggerTypeOneOff {
-				// Execute missed one-off task
-				go s.executeTask(&task)
-			} else if task.TriggerType == models.TriggerTypeCron {
-				// Update next run time for cron task
-				if nextRun, err := s.calculateNextCronRun(task.TriggerValue); err == nil {
-					s.taskRepo.UpdateNextRun(task.ID, &nextRun)
-				}
-			}
-		}
+	result := &models.TaskResult{
+		ID:                uuid.New(),
+		TaskID:            task.ID,
+		RunAt:             now,
+		Outcome:           models.OutcomeDeferralDropped,
+		SkipReason:        &reason,
+		CoalescedTriggers: task.Coalesced,
+		CreatedAt:         now,
+	}
+	if err := s.resultRepo.Create(result); err != nil {
+		log.Printf("Failed to save result for task %s: %v", task.ID, err)
 	}
+	done(result)
 }
 
-func (s *Scheduler) calculateNextCronRun(cronExpr string) (time.Time, error) {
-	parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-	schedule, err := parser.Parse(cronExpr)
+// firstRun is when a task is next due by its trigger alone
+func firstRun(task *models.Task) (time.Time, error) {
+	if task.TriggerType == models.TriggerTypeOneOff {
+		return time.Parse(time.RFC3339, task.TriggerValue)
+	}
+
+	// Expressions are standard 5-field ones, possibly with H tokens, so
+	// they are parsed by cronspec rather than the cron runner's parser
+	schedule, err := cronspec.Parse(task.TriggerValue, task.ID, task.Jitter())
 	if err != nil {
 		return time.Time{}, err
 	}
@@ -0,0 +1,188 @@
+package scheduler
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"task-scheduler/internal/cronspec"
+	"task-scheduler/internal/models"
+	"task-scheduler/internal/repository"
+	"task-scheduler/internal/workflow"
+)
+
+// EnableWorkflows lets the scheduler run workflows stored in repo. It must
+// be called before Start.
+func (s *Scheduler) EnableWorkflows(repo *repository.WorkflowRepository) {
+	s.workflowRepo = repo
+}
+
+// ScheduleWorkflow fills in a workflow's next_run from its cron if it has
+// none, and clears it for a workflow without a cron, which only runs on
+// demand. The due loop starts runs from next_run, so this works on any
+// instance of a cluster.
+func (s *Scheduler) ScheduleWorkflow(wf *models.Workflow) error {
+	if wf.Cron == nil || *wf.Cron == "" {
+		if wf.NextRun != nil {
+			if err := s.workflowRepo.UpdateNextRun(wf.ID, nil); err != nil {
+				return err
+			}
+			wf.NextRun = nil
+		}
+		return nil
+	}
+
+	if wf.NextRun == nil {
+		schedule, err := cronspec.Parse(*wf.Cron, wf.ID, 0)
+		if err != nil {
+			return err
+		}
+		nextRun := schedule.Next(time.Now())
+		if err := s.workflowRepo.UpdateNextRun(wf.ID, &nextRun); err != nil {
+			return err
+		}
+		wf.NextRun = &nextRun
+	}
+
+	s.wakeDueLoop()
+	log.Printf("Scheduled workflow %s with expression: %s", wf.ID, *wf.Cron)
+	return nil
+}
+
+// UnscheduleWorkflow clears a workflow's next_run so the due loop no
+// longer starts it
+func (s *Scheduler) UnscheduleWorkflow(workflowID uuid.UUID) {
+	if err := s.workflowRepo.UpdateNextRun(workflowID, nil); err != nil {
+		log.Printf("Failed to unschedule workflow %s: %v", workflowID, err)
+		return
+	}
+	s.wakeDueLoop()
+	log.Printf("Unscheduled workflow: %s", workflowID)
+}
+
+// StartWorkflowRun creates a run of the workflow and starts its root nodes
+func (s *Scheduler) StartWorkflowRun(workflowID uuid.UUID) (*models.WorkflowRun, error) {
+	wf, err := s.workflowRepo.GetByID(workflowID)
+	if err != nil {
+		return nil, err
+	}
+	if wf.Status != models.WorkflowStatusActive {
+		return nil, fmt.Errorf("workflow %s is %s", wf.ID, wf.Status)
+	}
+
+	s.workflowMu.Lock()
+	run := workflow.NewRun(wf, time.Now())
+	if s.elector != nil {
+		// This instance runs the nodes, so the run is failed if it goes
+		owner := s.elector.ID()
+		run.Owner = &owner
+	}
+	ready := workflow.Advance(wf, run, run.StartedAt)
+	err = s.workflowRepo.CreateRun(run)
+	s.workflowMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Started workflow run %s for workflow %s", run.ID, wf.ID)
+	s.dispatchNodes(wf, run.ID, ready)
+	return run, nil
+}
+
+// dispatchNodes runs each node's task; the node's outcome is fed back
+// through completeNode
+func (s *Scheduler) dispatchNodes(wf *models.Workflow, runID uuid.UUID, nodeIDs []string) {
+	taskIDs := make(map[string]uuid.UUID, len(wf.Nodes))
+	for _, node := range wf.Nodes {
+		taskIDs[node.ID] = node.TaskID
+	}
+
+	for _, nodeID := range nodeIDs {
+		nodeID := nodeID
+		task, err := s.taskRepo.GetByID(taskIDs[nodeID])
+		if err != nil {
+			s.completeNode(wf, runID, nodeID, nil, fmt.Sprintf("task not found: %v", err))
+			continue
+		}
+		if task.Status == models.TaskStatusCancelled || task.Status == models.TaskStatusPaused {
+			s.completeNode(wf, runID, nodeID, nil, fmt.Sprintf("task is %s", task.Status))
+			continue
+		}
+
+		// Node runs leave the task's own status alone, so a one-off task
+		// used in a workflow is not marked completed. They bypass the run
+		// queue because completeNode has to hear back from this process.
+		go s.runTask(task, func(result *models.TaskResult) {
+			s.completeNode(wf, runID, nodeID, result, "")
+		})
+	}
+}
+
+// completeNode records a node's outcome and starts whatever it unblocks.
+// result is nil when the node could not run at all.
+func (s *Scheduler) completeNode(wf *models.Workflow, runID uuid.UUID, nodeID string, result *models.TaskResult, failure string) {
+	s.workflowMu.Lock()
+
+	run, err := s.workflowRepo.GetRun(runID)
+	if err != nil {
+		s.workflowMu.Unlock()
+		log.Printf("Failed to load workflow run %s: %v", runID, err)
+		return
+	}
+	if run.Status != models.WorkflowRunRunning {
+		// The run was failed as interrupted, e.g. while this instance's
+		// lease had run out
+		s.workflowMu.Unlock()
+		return
+	}
+
+	now := time.Now()
+	state := run.Nodes[nodeID]
+	state.State = models.NodeStateFailed
+	state.FinishedAt = &now
+	if result != nil {
+		state.ResultID = &result.ID
+		if result.Success {
+			state.State = models.NodeStateSucceeded
+		}
+	} else {
+		state.Error = &failure
+	}
+	run.Nodes[nodeID] = state
+
+	ready := workflow.Advance(wf, run, now)
+	err = s.workflowRepo.UpdateRun(run)
+	s.workflowMu.Unlock()
+	if err != nil {
+		log.Printf("Failed to save workflow run %s: %v", runID, err)
+		return
+	}
+
+	if run.Status != models.WorkflowRunRunning {
+		log.Printf("Workflow run %s finished: %s", run.ID, run.Status)
+		return
+	}
+	s.dispatchNodes(wf, runID, ready)
+}
+
+// loadExistingWorkflows fills in next_run for cron workflows that have
+// none, e.g. ones created before workflows were scheduled from next_run
+func (s *Scheduler) loadExistingWorkflows() error {
+	log.Println("Loading existing workflows from database...")
+
+	workflows, err := s.workflowRepo.GetActive()
+	if err != nil {
+		return err
+	}
+
+	for i := range workflows {
+		if err := s.ScheduleWorkflow(&workflows[i]); err != nil {
+			log.Printf("Failed to schedule workflow %s: %v", workflows[i].ID, err)
+		}
+	}
+
+	log.Printf("Loaded %d existing workflows", len(workflows))
+	return nil
+}
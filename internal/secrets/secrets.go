@@ -0,0 +1,73 @@
+// Package secrets resolves the secret references tasks use in place of
+// literal credentials.
+//
+// A reference is either "env:NAME", naming an environment variable that must
+// start with SECRET_, or "file:NAME", naming a file directly inside the
+// configured secrets directory. The restrictions keep a task from reading
+// the scheduler's own configuration (DATABASE_URL and the like) or arbitrary
+// files on the host.
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// EnvPrefix is the prefix environment variables must carry to be referenced
+const EnvPrefix = "SECRET_"
+
+// ErrNotFound is returned when a well-formed reference resolves to nothing
+var ErrNotFound = errors.New("secret not found")
+
+// Resolver looks up secret references
+type Resolver struct {
+	dir string
+}
+
+// NewResolver creates a resolver reading file: references from dir
+func NewResolver(dir string) *Resolver {
+	return &Resolver{dir: dir}
+}
+
+// Resolve returns the value a reference points to. Trailing newlines are
+// trimmed from file secrets.
+func (r *Resolver) Resolve(ref string) (string, error) {
+	kind, name, ok := strings.Cut(ref, ":")
+	if !ok || name == "" {
+		return "", fmt.Errorf("secret reference %q must look like env:NAME or file:NAME", ref)
+	}
+
+	switch kind {
+	case "env":
+		if !strings.HasPrefix(name, EnvPrefix) {
+			return "", fmt.Errorf("secret environment variables must start with %s", EnvPrefix)
+		}
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("%w: %s", ErrNotFound, ref)
+		}
+		return value, nil
+
+	case "file":
+		if r == nil || r.dir == "" {
+			return "", fmt.Errorf("file secrets are not configured")
+		}
+		if name != filepath.Base(name) || name == "." || name == ".." {
+			return "", fmt.Errorf("secret file %q must be a plain file name", name)
+		}
+		data, err := os.ReadFile(filepath.Join(r.dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("%w: %s", ErrNotFound, ref)
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret %s: %w", ref, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+
+	default:
+		return "", fmt.Errorf("unsupported secret reference kind %q", kind)
+	}
+}
@@ -0,0 +1,101 @@
+// Package templating renders the Go text/template values tasks may use in
+// their action configuration (SQL parameters, email subjects and the like).
+package templating
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+
+	"task-scheduler/internal/models"
+)
+
+// Data is the context a template is rendered against
+type Data struct {
+	TaskID   uuid.UUID
+	TaskName string
+	// Now is the time the run started
+	Now time.Time
+
+	// Vars holds the values extracted by earlier steps of an http_steps
+	// action and Prev is the previous step's response
+	Vars map[string]string
+	Prev *Response
+
+	// Parent is the finished run that started this one as a follow-up; it
+	// is empty for runs that were not
+	Parent *models.ParentResult
+
+	// Webhook is the delivery that started a webhook-triggered run; it is
+	// empty for other runs
+	Webhook *models.InboundRequest
+}
+
+// Response is the part of an HTTP response templates can refer to
+type Response struct {
+	StatusCode int
+	Headers    map[string][]string
+	Body       string
+}
+
+// Header returns the first value of a response header, ignoring case
+func (r *Response) Header(name string) string {
+	for key, values := range r.Headers {
+		if strings.EqualFold(key, name) && len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// NewData builds the template context for a run of task starting at now
+func NewData(task *models.Task, now time.Time) *Data {
+	parent := task.Parent
+	if parent == nil {
+		parent = &models.ParentResult{}
+	}
+	inbound := task.Inbound
+	if inbound == nil {
+		inbound = &models.InboundRequest{}
+	}
+	return &Data{TaskID: task.ID, TaskName: task.Name, Now: now, Vars: map[string]string{}, Parent: parent, Webhook: inbound}
+}
+
+var funcs = template.FuncMap{
+	"contains": strings.Contains,
+	"json": func(v interface{}) (string, error) {
+		encoded, err := json.Marshal(v)
+		return string(encoded), err
+	},
+}
+
+func parse(text string) (*template.Template, error) {
+	return template.New("value").Funcs(funcs).Option("missingkey=error").Parse(text)
+}
+
+// Validate reports whether text parses as a template
+func Validate(text string) error {
+	if _, err := parse(text); err != nil {
+		return fmt.Errorf("invalid template %q: %w", text, err)
+	}
+	return nil
+}
+
+// Render executes text against data
+func Render(text string, data interface{}) (string, error) {
+	tmpl, err := parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template %q: %w", text, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render template %q: %w", text, err)
+	}
+	return buf.String(), nil
+}
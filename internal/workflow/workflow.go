@@ -0,0 +1,187 @@
+// Package workflow holds the DAG rules for workflows: validation on create
+// and deciding which nodes run next as a run progresses.
+package workflow
+
+import (
+	"fmt"
+	"time"
+
+	"task-scheduler/internal/models"
+)
+
+const maxNodes = 100
+
+// Validate checks that node IDs are unique, edges join known nodes with a
+// known condition and the graph has no cycles
+func Validate(nodes []models.WorkflowNode, edges []models.WorkflowEdge) error {
+	if len(nodes) == 0 || len(nodes) > maxNodes {
+		return fmt.Errorf("workflows need between 1 and %d nodes", maxNodes)
+	}
+
+	known := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		if node.ID == "" {
+			return fmt.Errorf("node ids must not be empty")
+		}
+		if known[node.ID] {
+			return fmt.Errorf("duplicate node id %q", node.ID)
+		}
+		known[node.ID] = true
+	}
+
+	seen := make(map[[2]string]bool, len(edges))
+	for _, edge := range edges {
+		if !known[edge.From] || !known[edge.To] {
+			return fmt.Errorf("edge %s -> %s references an unknown node", edge.From, edge.To)
+		}
+		if edge.From == edge.To {
+			return fmt.Errorf("node %q cannot depend on itself", edge.From)
+		}
+		switch edge.GetCondition() {
+		case models.EdgeOnSuccess, models.EdgeOnFailure, models.EdgeAlways:
+		default:
+			return fmt.Errorf("edge %s -> %s: condition must be one of success, failure, always", edge.From, edge.To)
+		}
+		key := [2]string{edge.From, edge.To}
+		if seen[key] {
+			return fmt.Errorf("duplicate edge %s -> %s", edge.From, edge.To)
+		}
+		seen[key] = true
+	}
+
+	if cycle := findCycle(nodes, edges); cycle != "" {
+		return fmt.Errorf("workflow has a cycle through node %q", cycle)
+	}
+	return nil
+}
+
+// findCycle runs Kahn's algorithm and returns a node that could not be
+// ordered, or "" when the graph is acyclic
+func findCycle(nodes []models.WorkflowNode, edges []models.WorkflowEdge) string {
+	inDegree := make(map[string]int, len(nodes))
+	children := make(map[string][]string, len(nodes))
+	for _, edge := range edges {
+		inDegree[edge.To]++
+		children[edge.From] = append(children[edge.From], edge.To)
+	}
+
+	var queue []string
+	for _, node := range nodes {
+		if inDegree[node.ID] == 0 {
+			queue = append(queue, node.ID)
+		}
+	}
+
+	ordered := 0
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		ordered++
+		for _, child := range children[id] {
+			inDegree[child]--
+			if inDegree[child] == 0 {
+				queue = append(queue, child)
+			}
+		}
+	}
+
+	if ordered == len(nodes) {
+		return ""
+	}
+	for _, node := range nodes {
+		if inDegree[node.ID] > 0 {
+			return node.ID
+		}
+	}
+	return ""
+}
+
+// NewRun returns a run with every node pending. Advance starts the roots.
+func NewRun(wf *models.Workflow, now time.Time) *models.WorkflowRun {
+	run := &models.WorkflowRun{
+		WorkflowID: wf.ID,
+		Status:     models.WorkflowRunRunning,
+		Nodes:      make(models.NodeRuns, len(wf.Nodes)),
+		StartedAt:  now,
+	}
+	for _, node := range wf.Nodes {
+		run.Nodes[node.ID] = models.NodeRun{State: models.NodeStatePending}
+	}
+	return run
+}
+
+// Advance moves a run forward after a node finishes. A pending node becomes
+// ready once all of its parents have finished: it starts if every incoming
+// edge's condition holds and is skipped otherwise, so skips propagate
+// downstream. Ready nodes are marked running and returned. When nothing is
+// left to run the run's final status is set.
+func Advance(wf *models.Workflow, run *models.WorkflowRun, now time.Time) []string {
+	incoming := make(map[string][]models.WorkflowEdge, len(wf.Nodes))
+	for _, edge := range wf.Edges {
+		incoming[edge.To] = append(incoming[edge.To], edge)
+	}
+
+	var ready []string
+	for changed := true; changed; {
+		changed = false
+		for _, node := range wf.Nodes {
+			state := run.Nodes[node.ID]
+			if state.State != models.NodeStatePending {
+				continue
+			}
+
+			decided, runs := evaluate(incoming[node.ID], run.Nodes)
+			if !decided {
+				continue
+			}
+			if runs {
+				state.State = models.NodeStateRunning
+				state.StartedAt = &now
+				ready = append(ready, node.ID)
+			} else {
+				state.State = models.NodeStateSkipped
+				state.FinishedAt = &now
+				changed = true
+			}
+			run.Nodes[node.ID] = state
+		}
+	}
+
+	finish(run, now)
+	return ready
+}
+
+// evaluate reports whether all of a node's parents have finished and, if
+// so, whether every incoming edge lets it run
+func evaluate(edges []models.WorkflowEdge, nodes models.NodeRuns) (decided, runs bool) {
+	runs = true
+	for _, edge := range edges {
+		parent := nodes[edge.From].State
+		if !parent.Finished() {
+			return false, false
+		}
+		switch edge.GetCondition() {
+		case models.EdgeOnSuccess:
+			runs = runs && parent == models.NodeStateSucceeded
+		case models.EdgeOnFailure:
+			runs = runs && parent == models.NodeStateFailed
+		case models.EdgeAlways:
+			runs = runs && parent != models.NodeStateSkipped
+		}
+	}
+	return true, runs
+}
+
+func finish(run *models.WorkflowRun, now time.Time) {
+	status := models.WorkflowRunSucceeded
+	for _, state := range run.Nodes {
+		if !state.State.Finished() {
+			return
+		}
+		if state.State == models.NodeStateFailed {
+			status = models.WorkflowRunFailed
+		}
+	}
+	run.Status = status
+	run.FinishedAt = &now
+}
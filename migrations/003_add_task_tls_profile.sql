@@ -0,0 +1,2 @@
+-- Named TLS profile used for mutual TLS / private CA calls
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS tls_profile VARCHAR(255);
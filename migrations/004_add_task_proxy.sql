@@ -0,0 +1,2 @@
+-- Optional per-task outbound proxy
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS proxy TEXT;
@@ -0,0 +1,3 @@
+-- Pipeline outcome and time spent waiting on per-host rate limits
+ALTER TABLE task_results ADD COLUMN IF NOT EXISTS outcome VARCHAR(32);
+ALTER TABLE task_results ADD COLUMN IF NOT EXISTS rate_limit_wait_ms INT DEFAULT 0;
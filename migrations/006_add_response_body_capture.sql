@@ -0,0 +1,6 @@
+-- Response body capture policy and out-of-band body references
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS body_capture JSONB;
+
+ALTER TABLE task_results ADD COLUMN IF NOT EXISTS response_body_size BIGINT DEFAULT 0;
+ALTER TABLE task_results ADD COLUMN IF NOT EXISTS response_body_truncated BOOLEAN DEFAULT false;
+ALTER TABLE task_results ADD COLUMN IF NOT EXISTS response_body_ref VARCHAR(64);
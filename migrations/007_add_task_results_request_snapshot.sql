@@ -0,0 +1,3 @@
+-- Redacted snapshot of the request sent for each run. response_headers now
+-- stores every value of each header as a JSON array.
+ALTER TABLE task_results ADD COLUMN IF NOT EXISTS request JSONB;
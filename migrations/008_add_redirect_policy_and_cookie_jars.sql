@@ -0,0 +1,11 @@
+-- Per-task redirect policy and persisted cookie jars
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS redirects JSONB;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS cookie_jar BOOLEAN DEFAULT false;
+
+ALTER TABLE task_results ADD COLUMN IF NOT EXISTS redirect_chain JSONB;
+
+CREATE TABLE IF NOT EXISTS task_cookie_jars (
+    task_id UUID PRIMARY KEY REFERENCES tasks(id) ON DELETE CASCADE,
+    cookies JSONB,
+    updated_at TIMESTAMPTZ DEFAULT now()
+);
@@ -0,0 +1,7 @@
+-- Action type discriminator and command action results
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS action_type VARCHAR(32) NOT NULL DEFAULT 'http';
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS action_config JSONB;
+
+ALTER TABLE task_results ADD COLUMN IF NOT EXISTS exit_code INT;
+ALTER TABLE task_results ADD COLUMN IF NOT EXISTS stdout TEXT;
+ALTER TABLE task_results ADD COLUMN IF NOT EXISTS stderr TEXT;
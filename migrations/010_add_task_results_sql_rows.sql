@@ -0,0 +1,3 @@
+-- Row count and first rows returned by sql actions
+ALTER TABLE task_results ADD COLUMN IF NOT EXISTS row_count BIGINT;
+ALTER TABLE task_results ADD COLUMN IF NOT EXISTS rows JSONB;
@@ -0,0 +1,2 @@
+-- Status code name returned by grpc actions
+ALTER TABLE task_results ADD COLUMN IF NOT EXISTS grpc_status VARCHAR(32);
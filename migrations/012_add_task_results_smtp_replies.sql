@@ -0,0 +1,2 @@
+-- SMTP conversation recorded by email actions
+ALTER TABLE task_results ADD COLUMN IF NOT EXISTS smtp_replies JSONB;
@@ -0,0 +1,2 @@
+-- Per-step requests and responses recorded by http_steps actions
+ALTER TABLE task_results ADD COLUMN IF NOT EXISTS steps JSONB;
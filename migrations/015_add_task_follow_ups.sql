@@ -0,0 +1,3 @@
+-- Follow-up tasks and callbacks run after a task succeeds or fails
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS on_success JSONB;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS on_failure JSONB;
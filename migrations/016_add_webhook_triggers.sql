@@ -0,0 +1,15 @@
+-- Webhook triggers: per-task delivery settings and accepted idempotency keys
+ALTER TABLE tasks DROP CONSTRAINT IF EXISTS tasks_trigger_type_check;
+ALTER TABLE tasks ADD CONSTRAINT tasks_trigger_type_check
+    CHECK (trigger_type IN ('one-off', 'cron', 'webhook'));
+
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS webhook JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_webhook_token ON tasks(trigger_value) WHERE trigger_type = 'webhook';
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    idempotency_key TEXT NOT NULL,
+    received_at TIMESTAMPTZ DEFAULT now(),
+    PRIMARY KEY (task_id, idempotency_key)
+);
@@ -0,0 +1,4 @@
+-- Debounce / throttle policy per task and the trigger count folded into each run
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS coalesce JSONB;
+
+ALTER TABLE task_results ADD COLUMN IF NOT EXISTS coalesced_triggers INTEGER DEFAULT 0;
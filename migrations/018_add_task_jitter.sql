@@ -0,0 +1,2 @@
+-- Per-task cron jitter, in seconds
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS jitter_seconds INTEGER DEFAULT 0;
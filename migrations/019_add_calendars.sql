@@ -0,0 +1,19 @@
+-- Blackout calendars that tasks can be attached to
+CREATE TABLE IF NOT EXISTS calendars (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    windows JSONB,
+    dates JSONB,
+    periods JSONB,
+    created_at TIMESTAMPTZ DEFAULT now(),
+    updated_at TIMESTAMPTZ DEFAULT now()
+);
+
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS calendar_id UUID REFERENCES calendars(id);
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS on_blackout VARCHAR(16);
+
+CREATE INDEX IF NOT EXISTS idx_tasks_calendar_id ON tasks(calendar_id);
+
+-- Why a run was skipped or deferred, e.g. the blackout it fell in
+ALTER TABLE task_results ADD COLUMN IF NOT EXISTS skip_reason TEXT;
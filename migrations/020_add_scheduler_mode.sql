@@ -0,0 +1,16 @@
+-- Scheduler-wide maintenance mode (a single row) and fires queued while paused
+CREATE TABLE IF NOT EXISTS scheduler_states (
+    id INTEGER PRIMARY KEY,
+    mode VARCHAR(16) NOT NULL DEFAULT 'running',
+    on_fire VARCHAR(16),
+    reason TEXT,
+    updated_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS queued_fires (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    task_id UUID NOT NULL,
+    queued_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_queued_fires_task_id ON queued_fires(task_id);
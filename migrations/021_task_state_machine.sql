@@ -0,0 +1,7 @@
+-- Statuses of the task state machine. Paused was never allowed by the
+-- original constraint; active is only left over from the old resume, and
+-- database.Migrate moves such tasks to scheduled on startup.
+ALTER TABLE tasks DROP CONSTRAINT IF EXISTS tasks_status_check;
+
+ALTER TABLE tasks ADD CONSTRAINT tasks_status_check
+    CHECK (status IN ('pending', 'scheduled', 'active', 'paused', 'cancelled', 'completed'));
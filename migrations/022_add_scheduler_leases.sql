@@ -0,0 +1,8 @@
+-- Leadership lease for cluster mode; the holder of an unexpired lease is
+-- the only instance that schedules tasks
+CREATE TABLE IF NOT EXISTS scheduler_leases (
+    name TEXT PRIMARY KEY,
+    holder TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    renewed_at TIMESTAMPTZ NOT NULL
+);
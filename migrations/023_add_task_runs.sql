@@ -0,0 +1,20 @@
+-- Queue of due runs that workers claim with FOR UPDATE SKIP LOCKED
+CREATE TABLE IF NOT EXISTS task_runs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    status VARCHAR(16) NOT NULL DEFAULT 'queued',
+    visible_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    claimed_by TEXT,
+    coalesced_triggers INTEGER DEFAULT 0,
+    result_id UUID,
+    error TEXT,
+    created_at TIMESTAMPTZ DEFAULT now(),
+    updated_at TIMESTAMPTZ DEFAULT now(),
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_runs_task_id ON task_runs(task_id);
+-- Workers only look at open runs
+CREATE INDEX IF NOT EXISTS idx_task_runs_claimable ON task_runs(visible_at)
+    WHERE status IN ('queued', 'running');
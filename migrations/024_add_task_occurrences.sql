@@ -0,0 +1,10 @@
+-- Each planned fire of a task is recorded once before it is dispatched, so
+-- a restart or failover cannot fire it twice
+CREATE TABLE IF NOT EXISTS task_occurrences (
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    scheduled_for TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT now(),
+    PRIMARY KEY (task_id, scheduled_for)
+);
+
+ALTER TABLE task_runs ADD COLUMN IF NOT EXISTS scheduled_for TIMESTAMPTZ;
@@ -0,0 +1,3 @@
+-- The scheduler polls next_run for scheduled tasks coming due
+CREATE INDEX IF NOT EXISTS idx_tasks_due ON tasks(next_run)
+    WHERE status = 'scheduled' AND next_run IS NOT NULL;
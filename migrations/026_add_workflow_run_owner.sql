@@ -0,0 +1,7 @@
+-- The cluster instance running a workflow run's nodes; runs whose owner
+-- has gone are failed by the leader
+ALTER TABLE workflow_runs ADD COLUMN IF NOT EXISTS owner TEXT;
+
+-- The scheduler polls next_run for active workflows coming due
+CREATE INDEX IF NOT EXISTS idx_workflows_due ON workflows(next_run)
+    WHERE status = 'active' AND next_run IS NOT NULL;
@@ -0,0 +1,3 @@
+-- Scheduled fires folded into a run deferred past a blackout; the run
+-- fires from next_run and reports them as coalesced triggers
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS deferred_triggers INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,6 @@
+-- A one-off task whose queued run was abandoned after every attempt ends up
+-- failed rather than scheduled with no next_run.
+ALTER TABLE tasks DROP CONSTRAINT IF EXISTS tasks_status_check;
+
+ALTER TABLE tasks ADD CONSTRAINT tasks_status_check
+    CHECK (status IN ('pending', 'scheduled', 'active', 'paused', 'cancelled', 'completed', 'failed'));
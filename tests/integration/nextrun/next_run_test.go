@@ -0,0 +1,159 @@
+package nextrun
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+	gormPostgres "gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"task-scheduler/internal/logger"
+	"task-scheduler/internal/metrics"
+	"task-scheduler/internal/models"
+	"task-scheduler/internal/repository"
+	"task-scheduler/internal/scheduler"
+)
+
+// recordingExecutor records the occurrence of every run
+type recordingExecutor struct {
+	mu          sync.Mutex
+	occurrences []time.Time
+}
+
+func (e *recordingExecutor) Execute(task *models.Task) *models.TaskResult {
+	e.mu.Lock()
+	if task.Occurrence != nil {
+		e.occurrences = append(e.occurrences, *task.Occurrence)
+	}
+	e.mu.Unlock()
+	return &models.TaskResult{ID: uuid.New(), TaskID: task.ID, Success: true}
+}
+
+func (e *recordingExecutor) ExecuteWithTimeout(task *models.Task, _ time.Duration) *models.TaskResult {
+	return e.Execute(task)
+}
+
+func (e *recordingExecutor) runs() []time.Time {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]time.Time(nil), e.occurrences...)
+}
+
+func setupDB(t *testing.T) *gorm.DB {
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+	ctx := context.Background()
+
+	container, err := postgres.RunContainer(ctx,
+		testcontainers.WithImage("postgres:15-alpine"),
+		postgres.WithDatabase("test_task_scheduler"),
+		postgres.WithUsername("test_user"),
+		postgres.WithPassword("test_password"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(5*time.Minute)),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { container.Terminate(ctx) })
+
+	dsn, err := container.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+	db, err := gorm.Open(gormPostgres.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Task{}, &models.TaskResult{}, &models.TaskOccurrence{}))
+	return db
+}
+
+func newScheduler(t *testing.T, db *gorm.DB, exec *recordingExecutor) *scheduler.Scheduler {
+	t.Helper()
+
+	taskLogger, err := logger.NewTaskLogger(filepath.Join(t.TempDir(), "tasks.log"))
+	require.NoError(t, err)
+
+	s := scheduler.NewScheduler(repository.NewTaskRepository(db), repository.NewResultRepository(db),
+		exec, taskLogger, metrics.NewMetrics())
+	s.EnableOccurrences(repository.NewOccurrenceRepository(db))
+	return s
+}
+
+func TestFutureOneOffFiresAfterRestart(t *testing.T) {
+	db := setupDB(t)
+	taskRepo := repository.NewTaskRepository(db)
+
+	at := time.Now().Add(3 * time.Second).UTC().Truncate(time.Second)
+	task := &models.Task{
+		Name:         "once",
+		TriggerType:  models.TriggerTypeOneOff,
+		TriggerValue: at.Format(time.RFC3339),
+		Method:       "GET",
+		URL:          "http://example.com",
+		Status:       models.TaskStatusScheduled,
+		NextRun:      &at,
+	}
+	require.NoError(t, taskRepo.Create(task))
+
+	// The first process stops before the task is due
+	exec := &recordingExecutor{}
+	first := newScheduler(t, db, exec)
+	require.NoError(t, first.Start())
+	first.Stop()
+
+	second := newScheduler(t, db, exec)
+	require.NoError(t, second.Start())
+	defer second.Stop()
+
+	require.Eventually(t, func() bool { return len(exec.runs()) == 1 }, 15*time.Second, 100*time.Millisecond)
+	assert.True(t, exec.runs()[0].Equal(at))
+
+	require.Eventually(t, func() bool {
+		current, err := taskRepo.GetByID(task.ID)
+		return err == nil && current.Status == models.TaskStatusCompleted && current.NextRun == nil
+	}, 5*time.Second, 100*time.Millisecond)
+}
+
+func TestOverdueCronFiresOnceAcrossInstances(t *testing.T) {
+	db := setupDB(t)
+	taskRepo := repository.NewTaskRepository(db)
+
+	// next_run was left in the past while no instance was up
+	missed := time.Now().Add(-90 * time.Second).UTC().Truncate(time.Second)
+	task := &models.Task{
+		Name:         "new year",
+		TriggerType:  models.TriggerTypeCron,
+		TriggerValue: "0 0 1 1 *",
+		Method:       "GET",
+		URL:          "http://example.com",
+		Status:       models.TaskStatusScheduled,
+		NextRun:      &missed,
+	}
+	require.NoError(t, taskRepo.Create(task))
+
+	exec := &recordingExecutor{}
+	for i := 0; i < 3; i++ {
+		s := newScheduler(t, db, exec)
+		require.NoError(t, s.Start())
+		defer s.Stop()
+	}
+
+	require.Eventually(t, func() bool { return len(exec.runs()) >= 1 }, 15*time.Second, 100*time.Millisecond)
+	time.Sleep(500 * time.Millisecond)
+
+	// Only the missed occurrence fires, once, and next_run moves past now
+	runs := exec.runs()
+	require.Len(t, runs, 1)
+	assert.True(t, runs[0].Equal(missed))
+
+	current, err := taskRepo.GetByID(task.ID)
+	require.NoError(t, err)
+	require.NotNil(t, current.NextRun)
+	assert.True(t, current.NextRun.After(time.Now()))
+}
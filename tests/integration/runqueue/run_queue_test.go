@@ -0,0 +1,169 @@
+package runqueue
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+	gormPostgres "gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"task-scheduler/internal/models"
+	"task-scheduler/internal/repository"
+)
+
+func setupRepo(t *testing.T) *repository.RunRepository {
+	return repository.NewRunRepository(setupDB(t))
+}
+
+func setupDB(t *testing.T) *gorm.DB {
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+	ctx := context.Background()
+
+	container, err := postgres.RunContainer(ctx,
+		testcontainers.WithImage("postgres:15-alpine"),
+		postgres.WithDatabase("test_task_scheduler"),
+		postgres.WithUsername("test_user"),
+		postgres.WithPassword("test_password"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(5*time.Minute)),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { container.Terminate(ctx) })
+
+	dsn, err := container.ConnectionString(ctx, "sslmode=disable")
+	require.NoError(t, err)
+	db, err := gorm.Open(gormPostgres.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.TaskRun{}, &models.TaskOccurrence{}))
+
+	return db
+}
+
+func TestConcurrentWorkersClaimEachRunOnce(t *testing.T) {
+	repo := setupRepo(t)
+
+	for i := 0; i < 50; i++ {
+		require.NoError(t, repo.Enqueue(&models.TaskRun{TaskID: uuid.New(), Status: models.TaskRunQueued}))
+	}
+
+	var mu sync.Mutex
+	claimed := make(map[uuid.UUID]int)
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(worker string) {
+			defer wg.Done()
+			for {
+				run, err := repo.Claim(worker, time.Minute, 3)
+				if !assert.NoError(t, err) || run == nil {
+					return
+				}
+				mu.Lock()
+				claimed[run.ID]++
+				mu.Unlock()
+			}
+		}(fmt.Sprintf("worker-%d", w))
+	}
+	wg.Wait()
+
+	assert.Len(t, claimed, 50)
+	for id, count := range claimed {
+		assert.Equal(t, 1, count, "run %s", id)
+	}
+}
+
+func TestExpiredClaimIsReclaimedThenAbandoned(t *testing.T) {
+	repo := setupRepo(t)
+	run := &models.TaskRun{TaskID: uuid.New(), Status: models.TaskRunQueued}
+	require.NoError(t, repo.Enqueue(run))
+
+	first, err := repo.Claim("crashed", 50*time.Millisecond, 2)
+	require.NoError(t, err)
+	require.NotNil(t, first)
+
+	// Not visible while the claim lasts
+	none, err := repo.Claim("other", 50*time.Millisecond, 2)
+	require.NoError(t, err)
+	assert.Nil(t, none)
+
+	time.Sleep(100 * time.Millisecond)
+	second, err := repo.Claim("other", 50*time.Millisecond, 2)
+	require.NoError(t, err)
+	require.NotNil(t, second)
+	assert.Equal(t, run.ID, second.ID)
+	assert.Equal(t, 2, second.Attempts)
+
+	// The first worker's claim is gone
+	held, err := repo.Complete(run.ID, "crashed", models.TaskRunSucceeded, nil, nil)
+	require.NoError(t, err)
+	assert.False(t, held)
+
+	time.Sleep(100 * time.Millisecond)
+	abandoned, err := repo.AbandonExhausted(2)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{run.TaskID}, abandoned)
+
+	runs, _, err := repo.ListByTask(run.TaskID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, models.TaskRunFailed, runs[0].Status)
+}
+
+func TestExtendKeepsClaim(t *testing.T) {
+	repo := setupRepo(t)
+	require.NoError(t, repo.Enqueue(&models.TaskRun{TaskID: uuid.New(), Status: models.TaskRunQueued}))
+
+	run, err := repo.Claim("worker", 100*time.Millisecond, 3)
+	require.NoError(t, err)
+	require.NotNil(t, run)
+
+	for i := 0; i < 3; i++ {
+		time.Sleep(60 * time.Millisecond)
+		held, err := repo.Extend(run.ID, "worker", 100*time.Millisecond)
+		require.NoError(t, err)
+		require.True(t, held)
+	}
+
+	stolen, err := repo.Claim("other", 100*time.Millisecond, 3)
+	require.NoError(t, err)
+	assert.Nil(t, stolen)
+}
+
+func TestOccurrenceIsRecordedWithItsRun(t *testing.T) {
+	db := setupDB(t)
+	occurrences := repository.NewOccurrenceRepository(db)
+
+	taskID := uuid.New()
+	scheduledFor := time.Now().Truncate(time.Second)
+	for i := 0; i < 2; i++ {
+		run := &models.TaskRun{TaskID: taskID, Status: models.TaskRunQueued, ScheduledFor: &scheduledFor}
+		recorded, err := occurrences.RecordWithRun(run)
+		require.NoError(t, err)
+		assert.Equal(t, i == 0, recorded)
+	}
+
+	var runs int64
+	require.NoError(t, db.Model(&models.TaskRun{}).Where("task_id = ?", taskID).Count(&runs).Error)
+	assert.Equal(t, int64(1), runs)
+
+	// A run that cannot be inserted leaves the occurrence unrecorded
+	require.NoError(t, db.Migrator().DropTable(&models.TaskRun{}))
+	later := scheduledFor.Add(time.Minute)
+	_, err := occurrences.RecordWithRun(&models.TaskRun{TaskID: taskID, Status: models.TaskRunQueued, ScheduledFor: &later})
+	require.Error(t, err)
+
+	var recorded int64
+	require.NoError(t, db.Model(&models.TaskOccurrence{}).Where("task_id = ?", taskID).Count(&recorded).Error)
+	assert.Equal(t, int64(1), recorded)
+}
@@ -0,0 +1,71 @@
+package actions
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"task-scheduler/internal/executor"
+	"task-scheduler/internal/models"
+)
+
+type stubExecutor struct {
+	runs int
+}
+
+func (s *stubExecutor) Execute(task *models.Task) *models.TaskResult {
+	return s.ExecuteWithTimeout(task, 0)
+}
+
+func (s *stubExecutor) ExecuteWithTimeout(task *models.Task, timeout time.Duration) *models.TaskResult {
+	s.runs++
+	return &models.TaskResult{ID: uuid.New(), TaskID: task.ID, Success: true}
+}
+
+func TestRegistryDispatchesByActionType(t *testing.T) {
+	httpStub, publishStub := &stubExecutor{}, &stubExecutor{}
+
+	registry := executor.NewRegistry()
+	require.NoError(t, registry.Register(models.ActionTypeHTTP, httpStub, nil))
+	require.NoError(t, registry.Register("publish", publishStub, nil))
+	assert.Error(t, registry.Register("publish", publishStub, nil))
+	assert.Equal(t, []models.ActionType{"http", "publish"}, registry.Types())
+
+	// Tasks saved before action types existed have an empty type and run as HTTP
+	assert.True(t, registry.Execute(&models.Task{ID: uuid.New()}).Success)
+	assert.True(t, registry.Execute(&models.Task{ID: uuid.New(), ActionType: "publish"}).Success)
+	assert.Equal(t, 1, httpStub.runs)
+	assert.Equal(t, 1, publishStub.runs)
+
+	result := registry.Execute(&models.Task{ID: uuid.New(), ActionType: "carrier-pigeon"})
+	assert.False(t, result.Success)
+	require.NotNil(t, result.ErrorMessage)
+	assert.Contains(t, *result.ErrorMessage, "unsupported action type")
+}
+
+func TestRegistryRunsValidationHooks(t *testing.T) {
+	errNoTopic := errors.New("topic is required")
+
+	registry := executor.NewRegistry()
+	require.NoError(t, registry.Register("publish", &stubExecutor{}, func(task *models.Task) error {
+		var config struct {
+			Topic string `json:"topic"`
+		}
+		if err := task.DecodeActionConfig(&config); err != nil {
+			return err
+		}
+		if config.Topic == "" {
+			return errNoTopic
+		}
+		return nil
+	}))
+
+	assert.NoError(t, registry.ValidateTask(&models.Task{ActionType: "publish", ActionConfig: models.RawConfig(`{"topic":"events"}`)}))
+	assert.ErrorIs(t, registry.ValidateTask(&models.Task{ActionType: "publish", ActionConfig: models.RawConfig(`{}`)}), errNoTopic)
+	assert.Error(t, registry.ValidateTask(&models.Task{ActionType: "publish"}))
+	assert.Error(t, registry.ValidateTask(&models.Task{ActionType: "sql"}))
+}
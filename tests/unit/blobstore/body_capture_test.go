@@ -0,0 +1,109 @@
+package blobstore
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"task-scheduler/internal/blobstore"
+	"task-scheduler/internal/executor"
+	"task-scheduler/internal/models"
+)
+
+func newBodyServer(t *testing.T, body string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTask(url string, capture *models.BodyCapture) *models.Task {
+	return &models.Task{ID: uuid.New(), Name: "report", Method: "GET", URL: url, BodyCapture: capture}
+}
+
+func TestStorePutIsContentAddressed(t *testing.T) {
+	store, err := blobstore.New(t.TempDir())
+	require.NoError(t, err)
+
+	digest, size, err := store.Put(strings.NewReader("hello blob"))
+	require.NoError(t, err)
+	assert.Len(t, digest, 64)
+	assert.Equal(t, int64(10), size)
+
+	again, _, err := store.Put(strings.NewReader("hello blob"))
+	require.NoError(t, err)
+	assert.Equal(t, digest, again)
+
+	reader, err := store.Open(digest)
+	require.NoError(t, err)
+	defer reader.Close()
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "hello blob", string(content))
+
+	_, err = store.Open("../../etc/passwd")
+	assert.ErrorIs(t, err, blobstore.ErrNotFound)
+}
+
+func TestHeadCaptureDoesNotSplitRunes(t *testing.T) {
+	// Each "é" is two bytes, so a 5 byte head would split the third rune
+	server := newBodyServer(t, strings.Repeat("é", 10))
+	httpExecutor := executor.NewHTTPExecutor(executor.WithEgressPolicy(executor.PermissiveEgressPolicy()))
+
+	result := httpExecutor.Execute(newTask(server.URL, &models.BodyCapture{Mode: models.BodyCaptureHead, HeadBytes: 5}))
+	require.True(t, result.Success, "error: %v", result.ErrorMessage)
+	require.NotNil(t, result.ResponseBody)
+	assert.Equal(t, "éé", *result.ResponseBody)
+	assert.True(t, utf8.ValidString(*result.ResponseBody))
+	assert.True(t, result.ResponseBodyTruncated)
+	assert.Equal(t, int64(20), result.ResponseBodySize)
+	assert.Nil(t, result.ResponseBodyRef)
+}
+
+func TestFullCaptureStreamsToBlobStore(t *testing.T) {
+	body := strings.Repeat("0123456789", 5000)
+	server := newBodyServer(t, body)
+
+	store, err := blobstore.New(t.TempDir())
+	require.NoError(t, err)
+	httpExecutor := executor.NewHTTPExecutor(executor.WithEgressPolicy(executor.PermissiveEgressPolicy()), executor.WithBlobStore(store))
+
+	result := httpExecutor.Execute(newTask(server.URL, &models.BodyCapture{Mode: models.BodyCaptureFull, HeadBytes: 100}))
+	require.True(t, result.Success, "error: %v", result.ErrorMessage)
+	require.NotNil(t, result.ResponseBodyRef)
+	assert.Len(t, *result.ResponseBody, 100)
+	assert.Equal(t, int64(len(body)), result.ResponseBodySize)
+
+	reader, err := store.Open(*result.ResponseBodyRef)
+	require.NoError(t, err)
+	defer reader.Close()
+	stored, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, body, string(stored))
+}
+
+func TestCapturePolicyNoneAndReadGuard(t *testing.T) {
+	server := newBodyServer(t, strings.Repeat("x", 1000))
+
+	httpExecutor := executor.NewHTTPExecutor(executor.WithEgressPolicy(executor.PermissiveEgressPolicy()), executor.WithMaxResponseBytes(100))
+
+	result := httpExecutor.Execute(newTask(server.URL, &models.BodyCapture{Mode: models.BodyCaptureNone}))
+	assert.True(t, result.Success)
+	assert.Nil(t, result.ResponseBody)
+
+	result = httpExecutor.Execute(newTask(server.URL, &models.BodyCapture{Mode: models.BodyCaptureFull}))
+	assert.True(t, result.Success)
+	assert.Equal(t, int64(100), result.ResponseBodySize)
+	assert.True(t, result.ResponseBodyTruncated)
+
+	assert.Error(t, httpExecutor.ValidateTask(newTask(server.URL, &models.BodyCapture{Mode: "everything"})))
+}
@@ -0,0 +1,126 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"task-scheduler/internal/calendar"
+	"task-scheduler/internal/models"
+)
+
+func TestWindowBlocksInsideAndEndsAtWindowEnd(t *testing.T) {
+	cal := &models.Calendar{
+		Timezone: "UTC",
+		Windows:  models.BlackoutWindows{{Name: "partner maintenance", Days: []string{"sun"}, Start: "02:00", End: "04:00"}},
+	}
+	require.NoError(t, calendar.Validate(cal))
+
+	// 2026-03-01 is a Sunday
+	blackout, blocked := calendar.Check(cal, time.Date(2026, 3, 1, 3, 0, 0, 0, time.UTC))
+	require.True(t, blocked)
+	assert.Equal(t, time.Date(2026, 3, 1, 4, 0, 0, 0, time.UTC), blackout.Until.UTC())
+	assert.Contains(t, blackout.Reason, "partner maintenance")
+
+	_, blocked = calendar.Check(cal, time.Date(2026, 3, 1, 4, 0, 0, 0, time.UTC))
+	assert.False(t, blocked)
+	_, blocked = calendar.Check(cal, time.Date(2026, 3, 2, 3, 0, 0, 0, time.UTC))
+	assert.False(t, blocked, "window only applies on Sundays")
+}
+
+func TestWindowPastMidnight(t *testing.T) {
+	cal := &models.Calendar{
+		Windows: models.BlackoutWindows{{Days: []string{"fri"}, Start: "22:00", End: "02:00"}},
+	}
+
+	// Friday 2026-03-06 23:00 and the following Saturday 01:00
+	blackout, blocked := calendar.Check(cal, time.Date(2026, 3, 6, 23, 0, 0, 0, time.UTC))
+	require.True(t, blocked)
+	assert.Equal(t, time.Date(2026, 3, 7, 2, 0, 0, 0, time.UTC), blackout.Until.UTC())
+
+	_, blocked = calendar.Check(cal, time.Date(2026, 3, 7, 1, 0, 0, 0, time.UTC))
+	assert.True(t, blocked)
+	_, blocked = calendar.Check(cal, time.Date(2026, 3, 7, 23, 0, 0, 0, time.UTC))
+	assert.False(t, blocked)
+}
+
+func TestDatesUseCalendarTimeZone(t *testing.T) {
+	cal := &models.Calendar{
+		Timezone: "America/New_York",
+		Dates:    models.CalendarDates{{Date: "2026-12-25", Name: "Christmas Day"}},
+	}
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	// 03:00 UTC on the 25th is still the 24th in New York
+	_, blocked := calendar.Check(cal, time.Date(2026, 12, 25, 3, 0, 0, 0, time.UTC))
+	assert.False(t, blocked)
+
+	blackout, blocked := calendar.Check(cal, time.Date(2026, 12, 25, 12, 0, 0, 0, time.UTC))
+	require.True(t, blocked)
+	assert.True(t, blackout.Until.Equal(time.Date(2026, 12, 26, 0, 0, 0, 0, ny)))
+}
+
+func TestBackToBackBlackoutsAreFollowed(t *testing.T) {
+	cal := &models.Calendar{
+		Dates:   models.CalendarDates{{Date: "2026-12-25"}, {Date: "2026-12-26"}},
+		Windows: models.BlackoutWindows{{Start: "00:00", End: "01:00"}},
+	}
+
+	blackout, blocked := calendar.Check(cal, time.Date(2026, 12, 25, 9, 0, 0, 0, time.UTC))
+	require.True(t, blocked)
+	assert.Equal(t, time.Date(2026, 12, 27, 1, 0, 0, 0, time.UTC), blackout.Until.UTC())
+}
+
+func TestValidateRejectsBadInput(t *testing.T) {
+	assert.Error(t, calendar.Validate(&models.Calendar{Timezone: "Mars/Olympus"}))
+	assert.Error(t, calendar.Validate(&models.Calendar{Windows: models.BlackoutWindows{{Start: "25:00", End: "01:00"}}}))
+	assert.Error(t, calendar.Validate(&models.Calendar{Windows: models.BlackoutWindows{{Days: []string{"funday"}, Start: "01:00", End: "02:00"}}}))
+	assert.Error(t, calendar.Validate(&models.Calendar{Dates: models.CalendarDates{{Date: "25/12/2026"}}}))
+
+	now := time.Now()
+	assert.Error(t, calendar.Validate(&models.Calendar{Periods: models.BlackoutPeriods{{Start: now, End: now}}}))
+}
+
+const holidays = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Christmas\r\n" +
+	"  Break\r\n" +
+	"DTSTART;VALUE=DATE:20261224\r\n" +
+	"DTEND;VALUE=DATE:20261227\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Release freeze\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20261201T180000\r\n" +
+	"DTEND;TZID=Europe/Berlin:20261201T220000\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Weekly sync\r\n" +
+	"DTSTART:20260105T090000Z\r\n" +
+	"DTEND:20260105T100000Z\r\n" +
+	"RRULE:FREQ=WEEKLY\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseICS(t *testing.T) {
+	imported, err := calendar.ParseICS(strings.NewReader(holidays))
+	require.NoError(t, err)
+
+	require.Len(t, imported.Dates, 3)
+	assert.Equal(t, "2026-12-24", imported.Dates[0].Date)
+	assert.Equal(t, "2026-12-26", imported.Dates[2].Date)
+	assert.Equal(t, "Christmas Break", imported.Dates[0].Name)
+
+	require.Len(t, imported.Periods, 1)
+	assert.Equal(t, time.Date(2026, 12, 1, 17, 0, 0, 0, time.UTC), imported.Periods[0].Start.UTC())
+	assert.Equal(t, time.Date(2026, 12, 1, 21, 0, 0, 0, time.UTC), imported.Periods[0].End.UTC())
+
+	assert.Equal(t, 1, imported.Skipped)
+
+	_, err = calendar.ParseICS(strings.NewReader("not a calendar"))
+	assert.Error(t, err)
+}
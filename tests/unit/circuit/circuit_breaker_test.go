@@ -0,0 +1,155 @@
+package circuit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"task-scheduler/internal/executor"
+	"task-scheduler/internal/handlers"
+	"task-scheduler/internal/models"
+)
+
+// scriptedExecutor returns the configured status code for every call
+type scriptedExecutor struct {
+	calls  int32
+	status int32
+}
+
+func (e *scriptedExecutor) Execute(task *models.Task) *models.TaskResult {
+	atomic.AddInt32(&e.calls, 1)
+	status := int(atomic.LoadInt32(&e.status))
+	return &models.TaskResult{
+		ID:         uuid.New(),
+		TaskID:     task.ID,
+		StatusCode: &status,
+		Success:    status < 400,
+	}
+}
+
+func (e *scriptedExecutor) ExecuteWithTimeout(task *models.Task, timeout time.Duration) *models.TaskResult {
+	return e.Execute(task)
+}
+
+func newTask() *models.Task {
+	return &models.Task{ID: uuid.New(), Name: "downstream", Method: "GET", URL: "https://down.example.com/ping"}
+}
+
+func TestCircuitBreakerOpensAndRecovers(t *testing.T) {
+	breakers := executor.NewCircuitBreakers(executor.CircuitBreakerConfig{
+		WindowSize:           4,
+		MinRequests:          4,
+		FailureRateThreshold: 0.5,
+		CoolDown:             50 * time.Millisecond,
+	})
+	inner := &scriptedExecutor{status: 503}
+	cb := executor.NewCircuitBreakerExecutor(inner, breakers)
+	task := newTask()
+
+	for i := 0; i < 4; i++ {
+		result := cb.Execute(task)
+		assert.False(t, result.NotAttempted())
+	}
+
+	result := cb.Execute(task)
+	assert.Equal(t, models.OutcomeCircuitOpen, result.Outcome)
+	assert.False(t, result.Success)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&inner.calls))
+
+	snapshot := breakers.Snapshot()
+	require.Len(t, snapshot, 1)
+	assert.Equal(t, "down.example.com", snapshot[0].Host)
+	assert.Equal(t, executor.CircuitOpen, snapshot[0].State)
+	require.NotNil(t, snapshot[0].RetryAt)
+
+	// After the cool-down one probe is let through; a success closes it
+	time.Sleep(60 * time.Millisecond)
+	atomic.StoreInt32(&inner.status, 200)
+	result = cb.Execute(task)
+	assert.True(t, result.Success)
+	assert.Equal(t, executor.CircuitClosed, breakers.Snapshot()[0].State)
+}
+
+func TestCircuitBreakerIgnoresClientErrors(t *testing.T) {
+	breakers := executor.NewCircuitBreakers(executor.CircuitBreakerConfig{WindowSize: 2, MinRequests: 2})
+	cb := executor.NewCircuitBreakerExecutor(&scriptedExecutor{status: 404}, breakers)
+	task := newTask()
+
+	for i := 0; i < 5; i++ {
+		assert.Empty(t, cb.Execute(task).Outcome)
+	}
+	assert.Equal(t, executor.CircuitClosed, breakers.Snapshot()[0].State)
+}
+
+func TestCircuitBreakerIgnoresLocalErrors(t *testing.T) {
+	breakers := executor.NewCircuitBreakers(executor.CircuitBreakerConfig{WindowSize: 2, MinRequests: 2})
+
+	// The default egress policy refuses loopback before anything is sent
+	denied := executor.NewCircuitBreakerExecutor(executor.NewHTTPExecutor(), breakers)
+	task := &models.Task{ID: uuid.New(), Name: "internal", Method: "GET", URL: "http://127.0.0.1:1/ping"}
+	for i := 0; i < 3; i++ {
+		result := denied.Execute(task)
+		assert.Empty(t, result.Outcome)
+		assert.False(t, result.TransportFailed)
+	}
+	assert.Equal(t, executor.CircuitClosed, breakers.Snapshot()[0].State)
+
+	// A refused connection is the destination's problem
+	policy, err := executor.NewEgressPolicy(executor.EgressPolicyConfig{AllowPrivateNetworks: true})
+	require.NoError(t, err)
+	breakers = executor.NewCircuitBreakers(executor.CircuitBreakerConfig{WindowSize: 2, MinRequests: 2})
+	refused := executor.NewCircuitBreakerExecutor(executor.NewHTTPExecutor(executor.WithEgressPolicy(policy)), breakers)
+	for i := 0; i < 2; i++ {
+		assert.True(t, refused.Execute(task).TransportFailed)
+	}
+	assert.Equal(t, executor.CircuitOpen, breakers.Snapshot()[0].State)
+}
+
+func TestHalfOpenProbeFailureReopens(t *testing.T) {
+	breakers := executor.NewCircuitBreakers(executor.CircuitBreakerConfig{
+		WindowSize:  2,
+		MinRequests: 2,
+		CoolDown:    20 * time.Millisecond,
+	})
+	cb := executor.NewCircuitBreakerExecutor(&scriptedExecutor{status: 500}, breakers)
+	task := newTask()
+
+	cb.Execute(task)
+	cb.Execute(task)
+	time.Sleep(30 * time.Millisecond)
+
+	probe := cb.Execute(task)
+	assert.Empty(t, probe.Outcome)
+	assert.Equal(t, models.OutcomeCircuitOpen, cb.Execute(task).Outcome)
+	assert.Equal(t, executor.CircuitOpen, breakers.Snapshot()[0].State)
+}
+
+func TestGetCircuitsEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	breakers := executor.NewCircuitBreakers(executor.DefaultCircuitBreakerConfig())
+	breakers.Allow("api.example.com")
+	breakers.Record("api.example.com", true)
+
+	router := gin.New()
+	router.GET("/api/v1/circuits", handlers.NewCircuitHandler(breakers).GetCircuits)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/circuits", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var body struct {
+		Circuits []executor.CircuitStatus `json:"circuits"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Circuits, 1)
+	assert.Equal(t, executor.CircuitClosed, body.Circuits[0].State)
+	assert.Equal(t, 1, body.Circuits[0].Requests)
+}
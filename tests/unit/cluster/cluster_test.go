@@ -0,0 +1,157 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"task-scheduler/internal/cluster"
+)
+
+// memoryLeases is an in-process LeaseStore
+type memoryLeases struct {
+	mu     sync.Mutex
+	leases map[string]memoryLease
+	down   atomic.Bool
+}
+
+type memoryLease struct {
+	holder  string
+	expires time.Time
+}
+
+func (m *memoryLeases) TryAcquire(name, holder string, ttl time.Duration) (bool, error) {
+	if m.down.Load() {
+		return false, errors.New("database unavailable")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if lease := m.leases[name]; lease.holder != holder && now.Before(lease.expires) {
+		return false, nil
+	}
+	if m.leases == nil {
+		m.leases = make(map[string]memoryLease)
+	}
+	m.leases[name] = memoryLease{holder: holder, expires: now.Add(ttl)}
+	return true, nil
+}
+
+func (m *memoryLeases) Release(name, holder string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if lease := m.leases[name]; lease.holder == holder {
+		m.leases[name] = memoryLease{holder: holder}
+	}
+	return nil
+}
+
+// held reports whether name is held by holder and has not run out
+func (m *memoryLeases) held(name, holder string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lease := m.leases[name]
+	return lease.holder == holder && time.Now().Before(lease.expires)
+}
+
+type instance struct {
+	elector *cluster.Elector
+	cancel  context.CancelFunc
+	leading atomic.Bool
+	done    chan struct{}
+}
+
+func start(store cluster.LeaseStore, id string, ttl time.Duration) *instance {
+	ctx, cancel := context.WithCancel(context.Background())
+	inst := &instance{elector: cluster.NewElector(store, id, ttl), cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(inst.done)
+		inst.elector.Run(ctx, func() { inst.leading.Store(true) }, func() { inst.leading.Store(false) })
+	}()
+	return inst
+}
+
+func (i *instance) stop() {
+	i.cancel()
+	<-i.done
+}
+
+func TestOnlyOneLeader(t *testing.T) {
+	store := &memoryLeases{}
+	a := start(store, "a", 200*time.Millisecond)
+	defer a.stop()
+	require.Eventually(t, a.elector.IsLeader, time.Second, 5*time.Millisecond)
+
+	b := start(store, "b", 200*time.Millisecond)
+	defer b.stop()
+
+	time.Sleep(300 * time.Millisecond)
+	assert.True(t, a.leading.Load())
+	assert.False(t, b.elector.IsLeader())
+	assert.False(t, b.leading.Load())
+}
+
+func TestGracefulShutdownHandsOver(t *testing.T) {
+	store := &memoryLeases{}
+	a := start(store, "a", time.Second)
+	require.Eventually(t, a.elector.IsLeader, time.Second, 5*time.Millisecond)
+	b := start(store, "b", time.Second)
+	defer b.stop()
+
+	a.stop()
+	assert.False(t, a.leading.Load())
+	// The released lease is taken on b's next attempt, well before it expires
+	require.Eventually(t, b.elector.IsLeader, 500*time.Millisecond, 5*time.Millisecond)
+	assert.True(t, b.leading.Load())
+}
+
+func TestLeaderStepsDownWhenLeaseCannotBeRenewed(t *testing.T) {
+	store := &memoryLeases{}
+	a := start(store, "a", 200*time.Millisecond)
+	defer a.stop()
+	require.Eventually(t, a.elector.IsLeader, time.Second, 5*time.Millisecond)
+
+	store.down.Store(true)
+	// Still leader while the lease it holds has not run out
+	time.Sleep(100 * time.Millisecond)
+	assert.True(t, a.elector.IsLeader())
+	require.Eventually(t, func() bool { return !a.leading.Load() }, time.Second, 5*time.Millisecond)
+}
+
+func TestFailoverWithinLeaseTimeout(t *testing.T) {
+	store := &memoryLeases{}
+	ttl := 200 * time.Millisecond
+
+	// a takes the lease and dies without releasing it
+	a := cluster.NewElector(store, "a", ttl)
+	acquired, err := store.TryAcquire("scheduler", a.ID(), ttl)
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	b := start(store, "b", ttl)
+	defer b.stop()
+	require.Eventually(t, b.elector.IsLeader, ttl+ttl/2, 5*time.Millisecond)
+}
+
+func TestInstancesKeepTheirOwnLease(t *testing.T) {
+	store := &memoryLeases{}
+	a := start(store, "a", time.Second)
+	require.Eventually(t, a.elector.IsLeader, time.Second, 5*time.Millisecond)
+	b := start(store, "b", time.Second)
+	defer b.stop()
+
+	// Followers hold their own lease too
+	require.Eventually(t, func() bool { return store.held(cluster.InstanceLeasePrefix+"b", "b") },
+		time.Second, 5*time.Millisecond)
+	assert.True(t, store.held(cluster.InstanceLeasePrefix+"a", "a"))
+
+	a.stop()
+	assert.False(t, store.held(cluster.InstanceLeasePrefix+"a", "a"))
+}
@@ -0,0 +1,109 @@
+package coalesce
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"task-scheduler/internal/coalesce"
+	"task-scheduler/internal/models"
+)
+
+// recorder collects the runs a coalescer lets through
+type recorder struct {
+	mu   sync.Mutex
+	runs []string
+}
+
+func (r *recorder) fire(label string) func(int) {
+	return func(coalesced int) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if coalesced > 0 {
+			label = fmt.Sprintf("%s+%d", label, coalesced)
+		}
+		r.runs = append(r.runs, label)
+	}
+}
+
+func (r *recorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.runs...)
+}
+
+func TestNoPolicyRunsEveryTrigger(t *testing.T) {
+	c := coalesce.New()
+	rec := &recorder{}
+	id := uuid.New()
+
+	c.Trigger(id, nil, rec.fire("a"))
+	c.Trigger(id, &models.CoalescePolicy{}, rec.fire("b"))
+	assert.Equal(t, []string{"a", "b"}, rec.get())
+}
+
+func TestDebounceRunsOnceAfterQuiet(t *testing.T) {
+	c := coalesce.New()
+	defer c.Stop()
+	rec := &recorder{}
+	id := uuid.New()
+	policy := &models.CoalescePolicy{DebounceSeconds: 1}
+
+	c.Trigger(id, policy, rec.fire("a"))
+	time.Sleep(500 * time.Millisecond)
+	c.Trigger(id, policy, rec.fire("b"))
+	time.Sleep(500 * time.Millisecond)
+	c.Trigger(id, policy, rec.fire("c"))
+
+	// The quiet period restarts with every trigger
+	time.Sleep(700 * time.Millisecond)
+	assert.Empty(t, rec.get())
+
+	require.Eventually(t, func() bool { return len(rec.get()) == 1 }, 2*time.Second, 20*time.Millisecond)
+	assert.Equal(t, []string{"c+2"}, rec.get())
+}
+
+func TestThrottleRunsLeadingAndCoalescesTheRest(t *testing.T) {
+	c := coalesce.New()
+	defer c.Stop()
+	rec := &recorder{}
+	id := uuid.New()
+	policy := &models.CoalescePolicy{ThrottleSeconds: 1}
+
+	c.Trigger(id, policy, rec.fire("a"))
+	c.Trigger(id, policy, rec.fire("b"))
+	c.Trigger(id, policy, rec.fire("c"))
+	assert.Equal(t, []string{"a"}, rec.get())
+
+	require.Eventually(t, func() bool { return len(rec.get()) == 2 }, 2*time.Second, 20*time.Millisecond)
+	assert.Equal(t, []string{"a", "c+1"}, rec.get())
+
+	// A trigger right after the trailing run waits out a new window
+	c.Trigger(id, policy, rec.fire("d"))
+	assert.Len(t, rec.get(), 2)
+	require.Eventually(t, func() bool { return len(rec.get()) == 3 }, 2*time.Second, 20*time.Millisecond)
+	assert.Equal(t, "d", rec.get()[2])
+}
+
+func TestStopDropsPendingRuns(t *testing.T) {
+	c := coalesce.New()
+	rec := &recorder{}
+	c.Trigger(uuid.New(), &models.CoalescePolicy{DebounceSeconds: 1}, rec.fire("a"))
+	c.Stop()
+
+	time.Sleep(1200 * time.Millisecond)
+	assert.Empty(t, rec.get())
+}
+
+func TestCoalescePolicyValidate(t *testing.T) {
+	assert.NoError(t, (*models.CoalescePolicy)(nil).Validate())
+	assert.NoError(t, (&models.CoalescePolicy{ThrottleSeconds: 60}).Validate())
+	assert.Error(t, (&models.CoalescePolicy{DebounceSeconds: 5, ThrottleSeconds: 60}).Validate())
+	assert.Error(t, (&models.CoalescePolicy{DebounceSeconds: -1}).Validate())
+	assert.Error(t, (&models.CoalescePolicy{ThrottleSeconds: 90000}).Validate())
+}
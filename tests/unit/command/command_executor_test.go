@@ -0,0 +1,149 @@
+package command
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"task-scheduler/internal/executor"
+	"task-scheduler/internal/models"
+)
+
+func newCommandTask(t *testing.T, action models.CommandAction) *models.Task {
+	config, err := json.Marshal(action)
+	require.NoError(t, err)
+	return &models.Task{
+		ID:           uuid.New(),
+		Name:         "command",
+		ActionType:   models.ActionTypeCommand,
+		ActionConfig: config,
+	}
+}
+
+func TestCommandCapturesOutputAndExitCode(t *testing.T) {
+	commandExecutor := executor.NewCommandExecutor([]string{"sh"})
+
+	result := commandExecutor.Execute(newCommandTask(t, models.CommandAction{
+		Argv: []string{"sh", "-c", `echo "hello $GREETING"; echo oops >&2`},
+		Env:  map[string]string{"GREETING": "world"},
+	}))
+	require.True(t, result.Success, "error: %v", result.ErrorMessage)
+	require.NotNil(t, result.ExitCode)
+	assert.Equal(t, 0, *result.ExitCode)
+	assert.Equal(t, "hello world\n", *result.Stdout)
+	assert.Equal(t, "oops\n", *result.Stderr)
+
+	result = commandExecutor.Execute(newCommandTask(t, models.CommandAction{
+		Argv: []string{"sh", "-c", "exit 3"},
+	}))
+	assert.False(t, result.Success)
+	require.NotNil(t, result.ExitCode)
+	assert.Equal(t, 3, *result.ExitCode)
+}
+
+func TestCommandOutputIsTruncated(t *testing.T) {
+	commandExecutor := executor.NewCommandExecutor([]string{"sh"})
+
+	result := commandExecutor.Execute(newCommandTask(t, models.CommandAction{
+		Argv:           []string{"sh", "-c", "yes | head -c 10000"},
+		MaxOutputBytes: 16,
+	}))
+	require.True(t, result.Success, "error: %v", result.ErrorMessage)
+	assert.Equal(t, strings.Repeat("y\n", 8), *result.Stdout)
+}
+
+func TestCommandTimeoutKillsProcessGroup(t *testing.T) {
+	pidFile := t.TempDir() + "/child.pid"
+	commandExecutor := executor.NewCommandExecutor([]string{"sh"})
+
+	start := time.Now()
+	result := commandExecutor.Execute(newCommandTask(t, models.CommandAction{
+		Argv:           []string{"sh", "-c", "sleep 30 & echo $! > " + pidFile + "; wait"},
+		TimeoutSeconds: 1,
+	}))
+	assert.Less(t, time.Since(start), 10*time.Second)
+	assert.False(t, result.Success)
+	require.NotNil(t, result.ErrorMessage)
+	assert.Contains(t, *result.ErrorMessage, "timed out")
+
+	// The backgrounded child belongs to the same group and must be gone too
+	pid, err := os.ReadFile(pidFile)
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat("/proc/" + strings.TrimSpace(string(pid)))
+		return os.IsNotExist(err)
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestCommandAllowList(t *testing.T) {
+	commandExecutor := executor.NewCommandExecutor([]string{"echo"})
+
+	assert.NoError(t, commandExecutor.ValidateTask(newCommandTask(t, models.CommandAction{Argv: []string{"echo", "hi"}})))
+	assert.Error(t, commandExecutor.ValidateTask(newCommandTask(t, models.CommandAction{Argv: []string{"sh", "-c", "id"}})))
+	assert.Error(t, commandExecutor.ValidateTask(newCommandTask(t, models.CommandAction{Argv: []string{"echo"}, Dir: "relative"})))
+
+	result := commandExecutor.Execute(newCommandTask(t, models.CommandAction{Argv: []string{"sh", "-c", "id"}}))
+	assert.False(t, result.Success)
+	assert.Nil(t, result.ExitCode)
+
+	disabled := executor.NewCommandExecutor(nil)
+	assert.Error(t, disabled.ValidateTask(newCommandTask(t, models.CommandAction{Argv: []string{"echo"}})))
+}
+
+func TestCommandCannotChangeWhatIsLoaded(t *testing.T) {
+	commandExecutor := executor.NewCommandExecutor([]string{"echo"})
+
+	for _, key := range []string{"LD_PRELOAD", "LD_LIBRARY_PATH", "DYLD_INSERT_LIBRARIES", "PATH"} {
+		task := newCommandTask(t, models.CommandAction{Argv: []string{"echo"}, Env: map[string]string{key: "/tmp/evil"}})
+		assert.Error(t, commandExecutor.ValidateTask(task), key)
+
+		result := commandExecutor.Execute(task)
+		assert.False(t, result.Success, key)
+		assert.Nil(t, result.ExitCode, key)
+	}
+}
+
+func TestCommandDirMustBeListed(t *testing.T) {
+	allowed := t.TempDir()
+	require.NoError(t, os.Mkdir(allowed+"/work", 0o755))
+	outside := t.TempDir()
+	require.NoError(t, os.Symlink(outside, allowed+"/escape"))
+
+	commandExecutor := executor.NewCommandExecutor([]string{"sh"}, executor.WithCommandDirs([]string{allowed}))
+	dirTask := func(dir string) *models.Task {
+		return newCommandTask(t, models.CommandAction{Argv: []string{"sh", "-c", "pwd"}, Dir: dir})
+	}
+
+	assert.NoError(t, commandExecutor.ValidateTask(dirTask(allowed+"/work")))
+	assert.Error(t, commandExecutor.ValidateTask(dirTask(outside)))
+	assert.Error(t, commandExecutor.ValidateTask(dirTask(allowed+"/../"+filepath.Base(outside))))
+	assert.Error(t, commandExecutor.ValidateTask(dirTask(allowed+"/escape")))
+
+	result := commandExecutor.Execute(dirTask(allowed + "/work"))
+	require.True(t, result.Success, "error: %v", result.ErrorMessage)
+
+	// Without listed directories commands may not pick one
+	unlisted := executor.NewCommandExecutor([]string{"sh"})
+	assert.Error(t, unlisted.ValidateTask(dirTask(allowed)))
+}
+
+func TestFailedCommandIsNotRetried(t *testing.T) {
+	countFile := t.TempDir() + "/runs"
+	retrying := executor.NewRetryExecutor(executor.NewCommandExecutor([]string{"sh"}), 2, time.Millisecond)
+
+	result := retrying.Execute(newCommandTask(t, models.CommandAction{
+		Argv: []string{"sh", "-c", "echo run >> " + countFile + "; exit 1"},
+	}))
+	assert.False(t, result.Success)
+
+	runs, err := os.ReadFile(countFile)
+	require.NoError(t, err)
+	assert.Equal(t, "run\n", string(runs))
+}
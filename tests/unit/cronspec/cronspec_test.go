@@ -0,0 +1,136 @@
+package cronspec
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"task-scheduler/internal/cronspec"
+)
+
+func TestExpandIsStablePerTask(t *testing.T) {
+	id := uuid.New()
+
+	first, err := cronspec.Expand("H H * * *", id)
+	require.NoError(t, err)
+	second, err := cronspec.Expand("H H * * *", id)
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+
+	fields := strings.Fields(first)
+	minute, err := strconv.Atoi(fields[0])
+	require.NoError(t, err)
+	hour, err := strconv.Atoi(fields[1])
+	require.NoError(t, err)
+	assert.True(t, minute >= 0 && minute <= 59)
+	assert.True(t, hour >= 0 && hour <= 23)
+}
+
+func TestExpandSpreadsTasks(t *testing.T) {
+	minutes := make(map[string]bool)
+	for i := 0; i < 200; i++ {
+		expanded, err := cronspec.Expand("H * * * *", uuid.New())
+		require.NoError(t, err)
+		minutes[strings.Fields(expanded)[0]] = true
+	}
+	// 200 tasks should land on far more than a handful of minutes
+	assert.Greater(t, len(minutes), 30)
+}
+
+func TestExpandRangesAndSteps(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		id := uuid.New()
+
+		expanded, err := cronspec.Expand("H(10-19) H(2-4) H * H(1-5)", id)
+		require.NoError(t, err)
+		fields := strings.Fields(expanded)
+		assertBetween(t, fields[0], 10, 19)
+		assertBetween(t, fields[1], 2, 4)
+		assertBetween(t, fields[2], 1, 28)
+		assertBetween(t, fields[4], 1, 5)
+
+		expanded, err = cronspec.Expand("H/15 * * * *", id)
+		require.NoError(t, err)
+		start, rest, ok := strings.Cut(strings.Fields(expanded)[0], "-")
+		require.True(t, ok)
+		assertBetween(t, start, 0, 14)
+		assert.Equal(t, "59/15", rest)
+	}
+
+	expanded, err := cronspec.Expand("0 9 * * 1-5", uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, "0 9 * * 1-5", expanded)
+
+	expanded, err = cronspec.Expand("@hourly", uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, "@hourly", expanded)
+}
+
+func assertBetween(t *testing.T, value string, lo, hi int) {
+	t.Helper()
+	n, err := strconv.Atoi(value)
+	require.NoError(t, err, value)
+	assert.True(t, n >= lo && n <= hi, "%d not in %d-%d", n, lo, hi)
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, cronspec.Validate("*/5 * * * *"))
+	assert.NoError(t, cronspec.Validate("H H(0-6) * * *"))
+	assert.NoError(t, cronspec.Validate("@daily"))
+
+	assert.Error(t, cronspec.Validate("H(50-70) * * * *"))
+	assert.Error(t, cronspec.Validate("H(9-3) * * * *"))
+	assert.Error(t, cronspec.Validate("H/0 * * * *"))
+	assert.Error(t, cronspec.Validate("H/90 * * * *"))
+	assert.Error(t, cronspec.Validate("Hx * * * *"))
+	assert.Error(t, cronspec.Validate("H * * *"))
+	// Six-field expressions with seconds are not accepted
+	assert.Error(t, cronspec.Validate("0 0 * * * *"))
+}
+
+func TestExpandKeepsTimeZone(t *testing.T) {
+	id := uuid.MustParse("6f1c2a4e-8d3b-4c5a-9e7f-1a2b3c4d5e6f")
+
+	// The H in the zone name is not an H token
+	assert.NoError(t, cronspec.Validate("CRON_TZ=Europe/Helsinki 0 * * * *"))
+	expanded, err := cronspec.Expand("CRON_TZ=Europe/Helsinki 0 * * * *", id)
+	require.NoError(t, err)
+	assert.Equal(t, "CRON_TZ=Europe/Helsinki 0 * * * *", expanded)
+
+	expanded, err = cronspec.Expand("TZ=Asia/Tokyo H 9 * * *", id)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(expanded, "TZ=Asia/Tokyo "), expanded)
+	assert.NotContains(t, strings.TrimPrefix(expanded, "TZ=Asia/Tokyo "), "H")
+	assert.NoError(t, cronspec.Validate("TZ=Asia/Tokyo H 9 * * *"))
+}
+
+func TestJitterIsDeterministicAndBounded(t *testing.T) {
+	id := uuid.New()
+	assert.Equal(t, cronspec.Jitter(id, 10*time.Minute), cronspec.Jitter(id, 10*time.Minute))
+	assert.Zero(t, cronspec.Jitter(id, 0))
+
+	for i := 0; i < 100; i++ {
+		jitter := cronspec.Jitter(uuid.New(), 5*time.Minute)
+		assert.True(t, jitter >= 0 && jitter < 5*time.Minute)
+	}
+}
+
+func TestParseShiftsFiresByJitter(t *testing.T) {
+	id := uuid.New()
+	offset := cronspec.Jitter(id, time.Hour)
+
+	schedule, err := cronspec.Parse("0 * * * *", id, time.Hour)
+	require.NoError(t, err)
+
+	from := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC).Add(offset)
+	runs := cronspec.Preview(schedule, from, 3)
+	require.Len(t, runs, 3)
+	for i, run := range runs {
+		assert.Equal(t, from.Add(time.Duration(i+1)*time.Hour), run)
+	}
+}
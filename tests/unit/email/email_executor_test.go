@@ -0,0 +1,288 @@
+package email
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"task-scheduler/internal/executor"
+	"task-scheduler/internal/models"
+	"task-scheduler/internal/secrets"
+)
+
+// fakeSMTP is a minimal in-process SMTP server. It offers STARTTLS when it
+// has a TLS config, accepts the password "hunter2" and rejects recipients
+// whose address contains "blocked".
+type fakeSMTP struct {
+	tlsConfig *tls.Config
+
+	mu       sync.Mutex
+	messages []string
+	authOver []bool
+}
+
+func newFakeSMTP(t *testing.T, tlsConfig *tls.Config) (*fakeSMTP, int) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	server := &fakeSMTP{tlsConfig: tlsConfig}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server, listener.Addr().(*net.TCPAddr).Port
+}
+
+func (f *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	secured := false
+
+	text.PrintfLine("220 fake ESMTP")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch {
+		case verb == "EHLO":
+			text.PrintfLine("250-fake")
+			if f.tlsConfig != nil && !secured {
+				text.PrintfLine("250-STARTTLS")
+			}
+			text.PrintfLine("250 AUTH PLAIN LOGIN")
+		case verb == "STARTTLS":
+			text.PrintfLine("220 go ahead")
+			tlsConn := tls.Server(conn, f.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			text = textproto.NewConn(tlsConn)
+			secured = true
+		case verb == "AUTH":
+			decoded, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(line, "AUTH PLAIN "))
+			f.mu.Lock()
+			f.authOver = append(f.authOver, secured)
+			f.mu.Unlock()
+			if strings.HasSuffix(string(decoded), "\x00hunter2") {
+				text.PrintfLine("235 2.7.0 authenticated")
+			} else {
+				text.PrintfLine("535 5.7.8 bad credentials")
+			}
+		case verb == "MAIL":
+			text.PrintfLine("250 2.1.0 ok")
+		case verb == "RCPT":
+			if strings.Contains(line, "blocked") {
+				text.PrintfLine("550 5.1.1 mailbox unavailable")
+			} else {
+				text.PrintfLine("250 2.1.5 ok")
+			}
+		case verb == "DATA":
+			text.PrintfLine("354 end with .")
+			lines, err := text.ReadDotLines()
+			if err != nil {
+				return
+			}
+			f.mu.Lock()
+			f.messages = append(f.messages, strings.Join(lines, "\n"))
+			f.mu.Unlock()
+			text.PrintfLine("250 2.0.0 queued as 42")
+		case verb == "QUIT":
+			text.PrintfLine("221 2.0.0 bye")
+			return
+		default:
+			text.PrintfLine("502 5.5.2 unknown command")
+		}
+	}
+}
+
+func newEmailTask(t *testing.T, action models.EmailAction) *models.Task {
+	config, err := json.Marshal(action)
+	require.NoError(t, err)
+	return &models.Task{ID: uuid.New(), Name: "weekly", ActionType: models.ActionTypeEmail, ActionConfig: config}
+}
+
+func replyCodes(replies models.SMTPReplies) []int {
+	codes := make([]int, 0, len(replies))
+	for _, reply := range replies {
+		codes = append(codes, reply.Code)
+	}
+	return codes
+}
+
+func TestSendWithAuthTemplatesAndAttachment(t *testing.T) {
+	t.Setenv("SECRET_SMTP_PASSWORD", "hunter2")
+	server, port := newFakeSMTP(t, nil)
+
+	files := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/csv")
+		io.WriteString(w, "id,total\n1,42\n")
+	}))
+	defer files.Close()
+
+	emailExecutor := executor.NewEmailExecutor(secrets.NewResolver(t.TempDir()), executor.WithEmailEgressPolicy(executor.PermissiveEgressPolicy()))
+	task := newEmailTask(t, models.EmailAction{
+		Host:           "127.0.0.1",
+		Port:           port,
+		Username:       "reports",
+		PasswordSecret: "env:SECRET_SMTP_PASSWORD",
+		From:           "Reports <reports@example.com>",
+		To:             []string{"team@example.com"},
+		Subject:        "Report for {{.TaskName}}",
+		Body:           "Task {{.TaskID}} ran.",
+		Attachments:    []models.EmailAttachment{{URL: files.URL + "/exports/totals.csv"}},
+	})
+	require.NoError(t, emailExecutor.ValidateTask(task))
+
+	result := emailExecutor.Execute(task)
+	require.True(t, result.Success, "error: %v", result.ErrorMessage)
+	assert.Equal(t, []int{220, 250, 235, 250, 250, 354, 250, 221}, replyCodes(result.SMTPReplies))
+	assert.Equal(t, "END DATA", result.SMTPReplies[6].Command)
+	assert.Equal(t, "2.0.0 queued as 42", result.SMTPReplies[6].Message)
+
+	for _, reply := range result.SMTPReplies {
+		assert.NotContains(t, reply.Command, base64.StdEncoding.EncodeToString([]byte("\x00reports\x00hunter2")))
+	}
+
+	require.Len(t, server.messages, 1)
+	message := server.messages[0]
+	assert.Contains(t, message, "Subject: Report for weekly")
+	assert.Contains(t, message, "Task "+task.ID.String()+" ran.")
+	assert.Contains(t, message, `filename=totals.csv`)
+	assert.Contains(t, message, base64.StdEncoding.EncodeToString([]byte("id,total\n1,42\n")))
+}
+
+func TestStartTLSUsesProfileTrust(t *testing.T) {
+	t.Setenv("SECRET_SMTP_PASSWORD", "hunter2")
+
+	// Borrow httptest's self-signed certificate for the SMTP server
+	certSource := httptest.NewTLSServer(http.NotFoundHandler())
+	serverCert := certSource.TLS.Certificates
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certSource.Certificate().Raw})
+	certSource.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, caPEM, 0o600))
+	profiles, err := executor.NewTLSProfiles([]executor.TLSProfile{{Name: "smtp", CAFile: caFile}}, false)
+	require.NoError(t, err)
+
+	server, port := newFakeSMTP(t, &tls.Config{Certificates: serverCert})
+	emailExecutor := executor.NewEmailExecutor(secrets.NewResolver(""), executor.WithEmailTLSProfiles(profiles), executor.WithEmailEgressPolicy(executor.PermissiveEgressPolicy()))
+
+	action := models.EmailAction{
+		Host:           "127.0.0.1",
+		Port:           port,
+		StartTLS:       true,
+		TLSProfile:     "smtp",
+		Username:       "reports",
+		PasswordSecret: "env:SECRET_SMTP_PASSWORD",
+		From:           "reports@example.com",
+		To:             []string{"team@example.com"},
+		Subject:        "hello",
+		Body:           "over TLS",
+	}
+	result := emailExecutor.Execute(newEmailTask(t, action))
+	require.True(t, result.Success, "error: %v", result.ErrorMessage)
+	assert.Equal(t, "STARTTLS", result.SMTPReplies[2].Command)
+	assert.Equal(t, []bool{true}, server.authOver)
+
+	// Without the profile's CA the handshake fails
+	action.TLSProfile = ""
+	result = emailExecutor.Execute(newEmailTask(t, action))
+	assert.False(t, result.Success)
+	require.NotNil(t, result.ErrorMessage)
+	assert.Contains(t, *result.ErrorMessage, "handshake")
+}
+
+func TestRejectedRecipientRecordsReplyCode(t *testing.T) {
+	_, port := newFakeSMTP(t, nil)
+
+	result := executor.NewEmailExecutor(nil, executor.WithEmailEgressPolicy(executor.PermissiveEgressPolicy())).Execute(newEmailTask(t, models.EmailAction{
+		Host:    "127.0.0.1",
+		Port:    port,
+		From:    "reports@example.com",
+		To:      []string{"team@example.com", "blocked@example.com"},
+		Subject: "hello",
+		Body:    "hi",
+	}))
+	assert.False(t, result.Success)
+	require.NotNil(t, result.ErrorMessage)
+	assert.Contains(t, *result.ErrorMessage, "550")
+	assert.Contains(t, *result.ErrorMessage, "blocked@example.com")
+
+	last := result.SMTPReplies[len(result.SMTPReplies)-1]
+	assert.Equal(t, 550, last.Code)
+	assert.Equal(t, "RCPT TO:<blocked@example.com>", last.Command)
+}
+
+func TestSecretReferences(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "smtp"), []byte("from-file\n"), 0o600))
+	t.Setenv("SECRET_TOKEN", "from-env")
+	t.Setenv("DATABASE_URL", "postgres://scheduler")
+
+	resolver := secrets.NewResolver(dir)
+
+	value, err := resolver.Resolve("file:smtp")
+	require.NoError(t, err)
+	assert.Equal(t, "from-file", value)
+
+	value, err = resolver.Resolve("env:SECRET_TOKEN")
+	require.NoError(t, err)
+	assert.Equal(t, "from-env", value)
+
+	_, err = resolver.Resolve("env:DATABASE_URL")
+	assert.Error(t, err)
+	_, err = resolver.Resolve("file:../smtp")
+	assert.Error(t, err)
+	_, err = resolver.Resolve("file:missing")
+	assert.ErrorIs(t, err, secrets.ErrNotFound)
+	_, err = resolver.Resolve("vault:smtp")
+	assert.Error(t, err)
+
+	emailExecutor := executor.NewEmailExecutor(resolver, executor.WithEmailEgressPolicy(executor.PermissiveEgressPolicy()))
+	assert.Error(t, emailExecutor.ValidateTask(newEmailTask(t, models.EmailAction{
+		Host:           "127.0.0.1",
+		Port:           25,
+		Username:       "reports",
+		PasswordSecret: "env:DATABASE_URL",
+		From:           "reports@example.com",
+		To:             []string{"team@example.com"},
+	})))
+	assert.Error(t, emailExecutor.ValidateTask(newEmailTask(t, models.EmailAction{
+		Host:    "127.0.0.1",
+		Port:    25,
+		From:    "reports@example.com",
+		To:      []string{"team@example.com"},
+		Subject: "{{.Missing}}",
+	})))
+	assert.NoError(t, emailExecutor.ValidateTask(newEmailTask(t, models.EmailAction{
+		Host: "127.0.0.1",
+		Port: 25,
+		From: "reports@example.com",
+		To:   []string{"team@example.com"},
+	})))
+}
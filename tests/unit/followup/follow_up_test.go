@@ -0,0 +1,85 @@
+package followup
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"task-scheduler/internal/executor"
+	"task-scheduler/internal/models"
+	"task-scheduler/internal/templating"
+)
+
+func TestFollowUpValidate(t *testing.T) {
+	id := uuid.New()
+	callback := &models.HTTPCallback{Method: "POST", URL: "https://hooks.example.com/done"}
+
+	assert.NoError(t, models.FollowUp{TaskID: &id}.Validate())
+	assert.NoError(t, models.FollowUp{Callback: callback}.Validate())
+	assert.Error(t, models.FollowUp{}.Validate())
+	assert.Error(t, models.FollowUp{TaskID: &id, Callback: callback}.Validate())
+	assert.Error(t, models.FollowUp{Callback: &models.HTTPCallback{Method: "POST"}}.Validate())
+}
+
+func TestParentResultExtendsChain(t *testing.T) {
+	status, body := 500, `{"error":"boom"}`
+	first := &models.Task{ID: uuid.New(), Name: "first"}
+	result := &models.TaskResult{ID: uuid.New(), StatusCode: &status, ResponseBody: &body, RunAt: time.Now()}
+
+	parent := models.NewParentResult(first, result)
+	assert.Equal(t, []uuid.UUID{first.ID}, parent.Chain)
+	assert.Equal(t, 500, parent.StatusCode)
+	assert.False(t, parent.Success)
+
+	second := &models.Task{ID: uuid.New(), Name: "second", Parent: parent}
+	grandchild := models.NewParentResult(second, &models.TaskResult{ID: uuid.New(), Success: true})
+	assert.Equal(t, []uuid.UUID{first.ID, second.ID}, grandchild.Chain)
+	assert.True(t, grandchild.InChain(first.ID))
+	assert.False(t, grandchild.InChain(uuid.New()))
+}
+
+func TestTemplatesSeeParent(t *testing.T) {
+	parent := &models.ParentResult{TaskName: "export", StatusCode: 503, Error: "upstream down"}
+	data := templating.NewData(&models.Task{ID: uuid.New(), Parent: parent}, time.Now())
+
+	out, err := templating.Render("{{.Parent.TaskName}} failed with {{.Parent.StatusCode}}: {{.Parent.Error}}", data)
+	require.NoError(t, err)
+	assert.Equal(t, "export failed with 503: upstream down", out)
+
+	// Runs that are not follow-ups render an empty parent instead of failing
+	out, err = templating.Render("[{{.Parent.Body}}]", templating.NewData(&models.Task{ID: uuid.New()}, time.Now()))
+	require.NoError(t, err)
+	assert.Equal(t, "[]", out)
+}
+
+func TestHTTPFollowUpRendersParent(t *testing.T) {
+	var gotBody, gotHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotBody, gotHeader = string(body), r.Header.Get("X-Parent")
+	}))
+	defer server.Close()
+
+	payload := `{"text": "{{.Parent.TaskName}} failed: {{.Parent.Error}}"}`
+	task := &models.Task{
+		ID:          uuid.New(),
+		Name:        "alert",
+		TriggerType: models.TriggerTypeCron,
+		Method:      "POST",
+		URL:         server.URL,
+		Headers:     models.Headers{"X-Parent": "{{.Parent.TaskName}}"},
+		Payload:     &payload,
+		Parent:      &models.ParentResult{TaskName: "export", Error: "upstream down"},
+	}
+
+	result := executor.NewHTTPExecutor(executor.WithEgressPolicy(executor.PermissiveEgressPolicy())).Execute(task)
+	require.True(t, result.Success, "%v", result.ErrorMessage)
+	assert.Equal(t, `{"text": "export failed: upstream down"}`, gotBody)
+	assert.Equal(t, "export", gotHeader)
+}
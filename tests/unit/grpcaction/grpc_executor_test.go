@@ -0,0 +1,158 @@
+package grpcaction
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"task-scheduler/internal/executor"
+	"task-scheduler/internal/models"
+)
+
+// echoTenant copies the caller's x-tenant metadata into a response header and
+// blocks until the deadline when x-stall is set
+func echoTenant(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if tenant := md.Get("x-tenant"); len(tenant) > 0 {
+		grpc.SetHeader(ctx, metadata.Pairs("x-echo-tenant", tenant[0]))
+	}
+	if len(md.Get("x-stall")) > 0 {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return handler(ctx, req)
+}
+
+func newHealthServer(t *testing.T, withReflection bool) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := grpc.NewServer(grpc.UnaryInterceptor(echoTenant))
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus("billing", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+	if withReflection {
+		reflection.Register(server)
+	}
+
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return listener.Addr().String()
+}
+
+func newGRPCTask(t *testing.T, action models.GRPCAction) *models.Task {
+	config, err := json.Marshal(action)
+	require.NoError(t, err)
+	return &models.Task{ID: uuid.New(), Name: "health", ActionType: models.ActionTypeGRPC, ActionConfig: config}
+}
+
+func TestUnaryCallViaReflection(t *testing.T) {
+	target := newHealthServer(t, true)
+
+	result := executor.NewGRPCExecutor(executor.WithGRPCEgressPolicy(executor.PermissiveEgressPolicy())).Execute(newGRPCTask(t, models.GRPCAction{
+		Target:    target,
+		Method:    "grpc.health.v1.Health/Check",
+		Request:   json.RawMessage(`{"service":"billing"}`),
+		Metadata:  map[string]string{"x-tenant": "acme"},
+		Plaintext: true,
+	}))
+	require.True(t, result.Success, "error: %v", result.ErrorMessage)
+	assert.Equal(t, "OK", *result.GRPCStatus)
+	require.NotNil(t, result.ResponseBody)
+	assert.JSONEq(t, `{"status":"NOT_SERVING"}`, *result.ResponseBody)
+	assert.Equal(t, "acme", result.ResponseHeaders.Get("x-echo-tenant"))
+}
+
+func TestStatusCodeMapping(t *testing.T) {
+	target := newHealthServer(t, true)
+	grpcExecutor := executor.NewGRPCExecutor(executor.WithGRPCEgressPolicy(executor.PermissiveEgressPolicy()))
+
+	result := grpcExecutor.Execute(newGRPCTask(t, models.GRPCAction{
+		Target:    target,
+		Method:    "grpc.health.v1.Health/Check",
+		Request:   json.RawMessage(`{"service":"unknown"}`),
+		Plaintext: true,
+	}))
+	assert.False(t, result.Success)
+	assert.Equal(t, "NOT_FOUND", *result.GRPCStatus)
+	require.NotNil(t, result.ErrorMessage)
+	assert.Contains(t, *result.ErrorMessage, "unknown service")
+
+	start := time.Now()
+	result = grpcExecutor.Execute(newGRPCTask(t, models.GRPCAction{
+		Target:         target,
+		Method:         "grpc.health.v1.Health/Check",
+		Metadata:       map[string]string{"x-stall": "1"},
+		Plaintext:      true,
+		TimeoutSeconds: 1,
+	}))
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.False(t, result.Success)
+	assert.Equal(t, "DEADLINE_EXCEEDED", *result.GRPCStatus)
+
+	result = grpcExecutor.Execute(newGRPCTask(t, models.GRPCAction{
+		Target:    target,
+		Method:    "grpc.health.v1.Health/Reboot",
+		Plaintext: true,
+	}))
+	assert.False(t, result.Success)
+	assert.Equal(t, "UNIMPLEMENTED", *result.GRPCStatus)
+}
+
+func TestUnaryCallWithDescriptorSet(t *testing.T) {
+	target := newHealthServer(t, false)
+
+	set := &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{
+		protodesc.ToFileDescriptorProto(healthpb.File_grpc_health_v1_health_proto),
+	}}
+	raw, err := proto.Marshal(set)
+	require.NoError(t, err)
+
+	grpcExecutor := executor.NewGRPCExecutor(executor.WithGRPCEgressPolicy(executor.PermissiveEgressPolicy()))
+	task := newGRPCTask(t, models.GRPCAction{
+		Target:        target,
+		Method:        "/grpc.health.v1.Health/Check",
+		DescriptorSet: raw,
+		Plaintext:     true,
+	})
+	require.NoError(t, grpcExecutor.ValidateTask(task))
+
+	result := grpcExecutor.Execute(task)
+	require.True(t, result.Success, "error: %v", result.ErrorMessage)
+	assert.JSONEq(t, `{"status":"SERVING"}`, *result.ResponseBody)
+
+	// Without reflection or a descriptor set the method cannot be resolved
+	result = grpcExecutor.Execute(newGRPCTask(t, models.GRPCAction{
+		Target:    target,
+		Method:    "grpc.health.v1.Health/Check",
+		Plaintext: true,
+	}))
+	assert.False(t, result.Success)
+	assert.Equal(t, "UNIMPLEMENTED", *result.GRPCStatus)
+}
+
+func TestValidation(t *testing.T) {
+	grpcExecutor := executor.NewGRPCExecutor(executor.WithGRPCEgressPolicy(executor.PermissiveEgressPolicy()))
+
+	assert.NoError(t, grpcExecutor.ValidateTask(newGRPCTask(t, models.GRPCAction{Target: "127.0.0.1:50051", Method: "pkg.Svc/Do"})))
+	assert.Error(t, grpcExecutor.ValidateTask(newGRPCTask(t, models.GRPCAction{Target: "127.0.0.1", Method: "pkg.Svc/Do"})))
+	assert.Error(t, grpcExecutor.ValidateTask(newGRPCTask(t, models.GRPCAction{Target: "127.0.0.1:50051", Method: "pkg.Svc.Do"})))
+	assert.Error(t, grpcExecutor.ValidateTask(newGRPCTask(t, models.GRPCAction{Target: "169.254.169.254:80", Method: "pkg.Svc/Do"})))
+	assert.Error(t, grpcExecutor.ValidateTask(newGRPCTask(t, models.GRPCAction{Target: "127.0.0.1:50051", Method: "pkg.Svc/Do", TLSProfile: "missing"})))
+	assert.Error(t, grpcExecutor.ValidateTask(newGRPCTask(t, models.GRPCAction{Target: "127.0.0.1:50051", Method: "pkg.Svc/Do", DescriptorSet: []byte("junk")})))
+}
@@ -0,0 +1,115 @@
+package maintenance
+
+import (
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"task-scheduler/internal/logger"
+	"task-scheduler/internal/metrics"
+	"task-scheduler/internal/models"
+	"task-scheduler/internal/repository"
+	"task-scheduler/internal/scheduler"
+)
+
+// blockingExecutor counts runs and holds each one until release is closed
+type blockingExecutor struct {
+	runs    atomic.Int64
+	release chan struct{}
+}
+
+func (e *blockingExecutor) Execute(task *models.Task) *models.TaskResult {
+	e.runs.Add(1)
+	<-e.release
+	return &models.TaskResult{ID: uuid.New(), TaskID: task.ID, Success: true}
+}
+
+func (e *blockingExecutor) ExecuteWithTimeout(task *models.Task, _ time.Duration) *models.TaskResult {
+	return e.Execute(task)
+}
+
+func newScheduler(t *testing.T, exec *blockingExecutor) *scheduler.Scheduler {
+	t.Helper()
+
+	// Results are written through a dry-run session so no database is needed
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	require.NoError(t, err)
+
+	taskLogger, err := logger.NewTaskLogger(filepath.Join(t.TempDir(), "tasks.log"))
+	require.NoError(t, err)
+
+	return scheduler.NewScheduler(repository.NewTaskRepository(db), repository.NewResultRepository(db),
+		exec, taskLogger, metrics.NewMetrics())
+}
+
+func webhookTask() *models.Task {
+	return &models.Task{ID: uuid.New(), Name: "hook", TriggerType: models.TriggerTypeWebhook}
+}
+
+func TestPausedSkipDropsRuns(t *testing.T) {
+	exec := &blockingExecutor{release: make(chan struct{})}
+	close(exec.release)
+	s := newScheduler(t, exec)
+
+	status, err := s.Pause(models.PausedFireSkip, nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.SchedulerPaused, status.Mode)
+
+	s.RunWebhookTask(webhookTask(), &models.InboundRequest{})
+	time.Sleep(50 * time.Millisecond)
+	assert.Zero(t, exec.runs.Load())
+
+	status, err = s.Resume()
+	require.NoError(t, err)
+	assert.Equal(t, models.SchedulerRunning, status.Mode)
+	time.Sleep(50 * time.Millisecond)
+	assert.Zero(t, exec.runs.Load(), "skipped fires are not replayed")
+}
+
+func TestPausedQueueReplaysOnResume(t *testing.T) {
+	exec := &blockingExecutor{release: make(chan struct{})}
+	close(exec.release)
+	s := newScheduler(t, exec)
+
+	reason := "incident 42"
+	_, err := s.Pause(models.PausedFireQueue, &reason)
+	require.NoError(t, err)
+
+	s.RunWebhookTask(webhookTask(), &models.InboundRequest{})
+	s.RunWebhookTask(webhookTask(), &models.InboundRequest{})
+	require.Eventually(t, func() bool { return s.Status().Queued == 2 }, time.Second, 10*time.Millisecond)
+	assert.Zero(t, exec.runs.Load())
+
+	_, err = s.Resume()
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return exec.runs.Load() == 2 }, time.Second, 10*time.Millisecond)
+	assert.Zero(t, s.Status().Queued)
+}
+
+func TestDrainWaitsForInFlightRuns(t *testing.T) {
+	exec := &blockingExecutor{release: make(chan struct{})}
+	s := newScheduler(t, exec)
+
+	s.RunWebhookTask(webhookTask(), &models.InboundRequest{})
+	require.Eventually(t, func() bool { return exec.runs.Load() == 1 }, time.Second, 10*time.Millisecond)
+
+	status, err := s.Drain(nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), status.InFlight)
+	assert.False(t, status.Drained)
+
+	// New fires are refused while draining
+	s.RunWebhookTask(webhookTask(), &models.InboundRequest{})
+
+	close(exec.release)
+	require.Eventually(t, func() bool { return s.Status().Drained }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int64(1), exec.runs.Load())
+}
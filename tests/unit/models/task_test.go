@@ -161,7 +161,7 @@ func TestTaskResult_Creation(t *testing.T) {
 				RunAt:           time.Now(),
 				StatusCode:      intPtr(200),
 				Success:         true,
-				ResponseHeaders: models.Headers{"Content-Type": "application/json"},
+				ResponseHeaders: models.MultiHeaders{"Content-Type": {"application/json"}},
 				ResponseBody:    stringPtr(`{"status": "ok"}`),
 				DurationMs:      150,
 			},
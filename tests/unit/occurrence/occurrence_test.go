@@ -0,0 +1,66 @@
+package occurrence
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"task-scheduler/internal/executor"
+	"task-scheduler/internal/models"
+)
+
+func TestOccurrenceKeyIsStable(t *testing.T) {
+	taskID := uuid.MustParse("6f1c0d1e-8a7b-4c3d-9e2f-0a1b2c3d4e5f")
+	at := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+
+	key := models.OccurrenceKey(taskID, at)
+	assert.Equal(t, "6f1c0d1e-8a7b-4c3d-9e2f-0a1b2c3d4e5f:2026-03-01T09:30:00Z", key)
+
+	// The same instant seen from another zone, or a few milliseconds late,
+	// is the same occurrence
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	assert.Equal(t, key, models.OccurrenceKey(taskID, at.In(berlin)))
+	assert.Equal(t, key, models.OccurrenceKey(taskID, at.Add(250*time.Millisecond)))
+
+	assert.NotEqual(t, key, models.OccurrenceKey(taskID, at.Add(time.Minute)))
+	assert.NotEqual(t, key, models.OccurrenceKey(uuid.New(), at))
+}
+
+func TestHTTPExecutorSendsIdempotencyKey(t *testing.T) {
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get(models.IdempotencyKeyHeader))
+	}))
+	defer server.Close()
+
+	at := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	task := &models.Task{
+		ID:           uuid.New(),
+		Name:         "report",
+		TriggerType:  models.TriggerTypeCron,
+		TriggerValue: "30 9 * * *",
+		Method:       "POST",
+		URL:          server.URL,
+	}
+	httpExecutor := executor.NewHTTPExecutor(executor.WithEgressPolicy(executor.PermissiveEgressPolicy()))
+
+	// Runs without an occurrence, such as webhook deliveries, send no key
+	require.True(t, httpExecutor.Execute(task).Success)
+
+	task.Occurrence = &at
+	require.True(t, httpExecutor.Execute(task).Success)
+	require.True(t, httpExecutor.Execute(task).Success)
+
+	// A key set on the task wins
+	task.Headers = models.Headers{models.IdempotencyKeyHeader: "custom"}
+	require.True(t, httpExecutor.Execute(task).Success)
+
+	want := models.OccurrenceKey(task.ID, at)
+	assert.Equal(t, []string{"", want, want, "custom"}, keys)
+}
@@ -0,0 +1,132 @@
+package ratelimit
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"task-scheduler/internal/executor"
+	"task-scheduler/internal/models"
+	"task-scheduler/internal/ratelimit"
+)
+
+type countingExecutor struct {
+	calls int32
+}
+
+func (e *countingExecutor) Execute(task *models.Task) *models.TaskResult {
+	atomic.AddInt32(&e.calls, 1)
+	return &models.TaskResult{ID: uuid.New(), TaskID: task.ID, Success: true}
+}
+
+func (e *countingExecutor) ExecuteWithTimeout(task *models.Task, timeout time.Duration) *models.TaskResult {
+	return e.Execute(task)
+}
+
+func newTask(url string) *models.Task {
+	return &models.Task{ID: uuid.New(), Name: "partner call", Method: "GET", URL: url}
+}
+
+func TestHostLimiterOverridesAndBurst(t *testing.T) {
+	limiter, err := ratelimit.NewHostLimiter(ratelimit.Config{
+		Hosts: []ratelimit.HostLimit{{Pattern: "*.partner.com", Limit: ratelimit.Limit{Rate: 10, Burst: 2}}},
+	})
+	require.NoError(t, err)
+
+	// Hosts without an override are unlimited by default
+	for i := 0; i < 50; i++ {
+		wait, ok := limiter.Reserve("other.example.com")
+		require.True(t, ok)
+		require.Zero(t, wait)
+	}
+
+	wait, ok := limiter.Reserve("api.partner.com")
+	assert.True(t, ok)
+	assert.Zero(t, wait)
+	wait, ok = limiter.Reserve("API.partner.com")
+	assert.True(t, ok)
+	assert.Zero(t, wait)
+
+	// Burst exhausted: the next token is ~100ms away at 10 req/s
+	wait, ok = limiter.Reserve("api.partner.com")
+	assert.True(t, ok)
+	assert.InDelta(t, 100*time.Millisecond, wait, float64(20*time.Millisecond))
+
+	_, err = ratelimit.NewHostLimiter(ratelimit.Config{Default: ratelimit.Limit{Rate: -1}})
+	assert.Error(t, err)
+}
+
+func TestRateLimitedExecutorWaitsAndDefers(t *testing.T) {
+	limiter, err := ratelimit.NewHostLimiter(ratelimit.Config{
+		Default:        ratelimit.Limit{Rate: 20, Burst: 1},
+		MaxWaitSeconds: 1,
+	})
+	require.NoError(t, err)
+
+	inner := &countingExecutor{}
+	limited := executor.NewRateLimitedExecutor(context.Background(), inner, limiter)
+	task := newTask("https://api.partner.com/orders")
+
+	first := limited.Execute(task)
+	assert.True(t, first.Success)
+	assert.Zero(t, first.RateLimitWaitMs)
+
+	second := limited.Execute(task)
+	assert.True(t, second.Success)
+	assert.Greater(t, second.RateLimitWaitMs, 0)
+
+	// Queue up enough reservations that the next one is beyond max wait
+	for i := 0; i < 30; i++ {
+		limiter.Reserve("api.partner.com")
+	}
+	deferred := limited.Execute(task)
+	assert.False(t, deferred.Success)
+	assert.True(t, deferred.NotAttempted())
+	assert.Equal(t, models.OutcomeRateLimited, deferred.Outcome)
+	assert.Greater(t, deferred.DeferFor, time.Second)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&inner.calls))
+}
+
+func TestRateLimitedExecutorWaitEndsWithContext(t *testing.T) {
+	limiter, err := ratelimit.NewHostLimiter(ratelimit.Config{
+		Default:        ratelimit.Limit{Rate: 0.1, Burst: 1},
+		MaxWaitSeconds: 30,
+	})
+	require.NoError(t, err)
+
+	inner := &countingExecutor{}
+	task := newTask("https://api.partner.com/orders")
+	limiter.Reserve("api.partner.com")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	result := executor.NewRateLimitedExecutor(ctx, inner, limiter).Execute(task)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Equal(t, models.OutcomeRateLimited, result.Outcome)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&inner.calls))
+}
+
+func TestRetryExecutorDoesNotRetryDeferredRuns(t *testing.T) {
+	limiter, err := ratelimit.NewHostLimiter(ratelimit.Config{
+		Default:        ratelimit.Limit{Rate: 0.001, Burst: 1},
+		MaxWaitSeconds: 1,
+	})
+	require.NoError(t, err)
+
+	inner := &countingExecutor{}
+	task := newTask("https://api.partner.com/orders")
+	limiter.Reserve("api.partner.com")
+
+	retrying := executor.NewRetryExecutor(executor.NewRateLimitedExecutor(context.Background(), inner, limiter), 3, time.Millisecond)
+	result := retrying.Execute(task)
+
+	assert.Equal(t, models.OutcomeRateLimited, result.Outcome)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&inner.calls))
+}
@@ -329,11 +329,12 @@ func (suite *ResultRepositoryTestSuite) TestResultWithComplexData() {
 		RunAt:      time.Now(),
 		Success:    true,
 		StatusCode: intPtr(200),
-		ResponseHeaders: models.Headers{
-			"Content-Type":  "application/json",
-			"Cache-Control": "no-cache",
-			"X-RateLimit":   "100",
-			"Authorization": "Bearer token123",
+		ResponseHeaders: models.MultiHeaders{
+			"Content-Type":  {"application/json"},
+			"Cache-Control": {"no-cache"},
+			"X-RateLimit":   {"100"},
+			"Authorization": {"Bearer token123"},
+			"Set-Cookie":    {"a=1", "b=2"},
 		},
 		ResponseBody: stringPtr(`{
 			"status": "success",
@@ -372,8 +373,9 @@ func (suite *ResultRepositoryTestSuite) TestResultWithComplexData() {
 	assert.Equal(suite.T(), result.DurationMs, retrieved.DurationMs)
 
 	// Verify specific header values
-	assert.Equal(suite.T(), "application/json", retrieved.ResponseHeaders["Content-Type"])
-	assert.Equal(suite.T(), "Bearer token123", retrieved.ResponseHeaders["Authorization"])
+	assert.Equal(suite.T(), "application/json", retrieved.ResponseHeaders.Get("Content-Type"))
+	assert.Equal(suite.T(), "Bearer token123", retrieved.ResponseHeaders.Get("Authorization"))
+	assert.Equal(suite.T(), []string{"a=1", "b=2"}, retrieved.ResponseHeaders["Set-Cookie"])
 }
 
 func (suite *ResultRepositoryTestSuite) TestConcurrentResultCreation() {
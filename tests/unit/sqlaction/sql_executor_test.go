@@ -0,0 +1,194 @@
+package sqlaction
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"task-scheduler/internal/executor"
+	"task-scheduler/internal/models"
+)
+
+// fakeDriver records what the executor sends and answers SELECTs with three
+// rows, other statements with 7 affected rows and "pg_sleep" by blocking
+// until the context is cancelled
+type fakeDriver struct {
+	mu         sync.Mutex
+	statements []string
+	args       [][]driver.NamedValue
+	readOnly   []bool
+	committed  int
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) { return &fakeConn{d: d}, nil }
+
+func (d *fakeDriver) record(query string, args []driver.NamedValue) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.statements = append(d.statements, query)
+	d.args = append(d.args, args)
+}
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	c.d.readOnly = append(c.d.readOnly, opts.ReadOnly)
+	return &fakeTx{d: c.d}, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.d.record(query, args)
+	return driver.RowsAffected(7), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.d.record(query, args)
+	if strings.Contains(query, "pg_sleep") {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return &fakeRows{remaining: 3}, nil
+}
+
+type fakeTx struct{ d *fakeDriver }
+
+func (t *fakeTx) Commit() error {
+	t.d.mu.Lock()
+	defer t.d.mu.Unlock()
+	t.d.committed++
+	return nil
+}
+func (t *fakeTx) Rollback() error { return nil }
+
+type fakeRows struct{ remaining int }
+
+func (r *fakeRows) Columns() []string { return []string{"id", "name"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.remaining == 0 {
+		return io.EOF
+	}
+	dest[0] = int64(r.remaining)
+	dest[1] = []byte("row")
+	r.remaining--
+	return nil
+}
+
+var registerOnce sync.Once
+var fake = &fakeDriver{}
+
+func newSQLExecutor(t *testing.T, allowSchedulerDB bool) *executor.SQLExecutor {
+	registerOnce.Do(func() { sql.Register("fake", fake) })
+
+	schedulerDB, err := sql.Open("fake", "scheduler")
+	require.NoError(t, err)
+
+	sqlExecutor, err := executor.NewSQLExecutor(executor.SQLConnectionsConfig{
+		Connections:      []executor.SQLConnection{{Name: "analytics", Driver: "fake", DSN: "analytics"}},
+		AllowSchedulerDB: allowSchedulerDB,
+	}, schedulerDB)
+	require.NoError(t, err)
+	return sqlExecutor
+}
+
+func newSQLTask(t *testing.T, action models.SQLAction) *models.Task {
+	config, err := json.Marshal(action)
+	require.NoError(t, err)
+	return &models.Task{ID: uuid.New(), Name: "rollup", ActionType: models.ActionTypeSQL, ActionConfig: config}
+}
+
+func TestQueryCapturesRowCountAndFirstRows(t *testing.T) {
+	sqlExecutor := newSQLExecutor(t, false)
+
+	result := sqlExecutor.Execute(newSQLTask(t, models.SQLAction{
+		Connection: "analytics",
+		Statement:  "SELECT id, name FROM users",
+		ReadOnly:   true,
+		MaxRows:    2,
+	}))
+	require.True(t, result.Success, "error: %v", result.ErrorMessage)
+	require.NotNil(t, result.RowCount)
+	assert.Equal(t, int64(3), *result.RowCount)
+	require.Len(t, result.Rows, 2)
+	assert.Equal(t, "row", result.Rows[0]["name"])
+	assert.Equal(t, int64(3), result.Rows[0]["id"])
+
+	fake.mu.Lock()
+	assert.True(t, fake.readOnly[len(fake.readOnly)-1])
+	fake.mu.Unlock()
+}
+
+func TestExecBindsTemplatedParams(t *testing.T) {
+	sqlExecutor := newSQLExecutor(t, false)
+
+	task := newSQLTask(t, models.SQLAction{
+		Connection: "analytics",
+		Statement:  "DELETE FROM events WHERE created_at < $1 AND source = $2 AND shard = $3",
+		Params:     []interface{}{"{{.Now.Format \"2006\"}}", "{{.TaskName}}", 4},
+	})
+	result := sqlExecutor.Execute(task)
+	require.True(t, result.Success, "error: %v", result.ErrorMessage)
+	assert.Equal(t, int64(7), *result.RowCount)
+	assert.Nil(t, result.Rows)
+
+	fake.mu.Lock()
+	args := fake.args[len(fake.args)-1]
+	fake.mu.Unlock()
+	require.Len(t, args, 3)
+	assert.Equal(t, time.Now().Format("2006"), args[0].Value)
+	assert.Equal(t, "rollup", args[1].Value)
+	assert.Equal(t, int64(4), args[2].Value)
+}
+
+func TestStatementTimeout(t *testing.T) {
+	sqlExecutor := newSQLExecutor(t, false)
+
+	start := time.Now()
+	result := sqlExecutor.Execute(newSQLTask(t, models.SQLAction{
+		Connection:     "analytics",
+		Statement:      "SELECT pg_sleep(60)",
+		TimeoutSeconds: 1,
+	}))
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.False(t, result.Success)
+	require.NotNil(t, result.ErrorMessage)
+	assert.Contains(t, *result.ErrorMessage, "timed out")
+}
+
+func TestValidation(t *testing.T) {
+	sqlExecutor := newSQLExecutor(t, false)
+
+	assert.NoError(t, sqlExecutor.ValidateTask(newSQLTask(t, models.SQLAction{Connection: "analytics", Statement: "SELECT 1"})))
+	assert.Error(t, sqlExecutor.ValidateTask(newSQLTask(t, models.SQLAction{Connection: "missing", Statement: "SELECT 1"})))
+	assert.Error(t, sqlExecutor.ValidateTask(newSQLTask(t, models.SQLAction{Connection: "analytics"})))
+	assert.Error(t, sqlExecutor.ValidateTask(newSQLTask(t, models.SQLAction{
+		Connection: "analytics",
+		Statement:  "SELECT $1",
+		Params:     []interface{}{"{{.NoSuchField}}"},
+	})))
+
+	// The scheduler's own database is opt-in
+	own := newSQLTask(t, models.SQLAction{Connection: executor.SchedulerConnection, Statement: "SELECT 1"})
+	assert.Error(t, sqlExecutor.ValidateTask(own))
+	assert.NoError(t, newSQLExecutor(t, true).ValidateTask(own))
+}
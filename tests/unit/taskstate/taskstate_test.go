@@ -0,0 +1,67 @@
+package taskstate
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"task-scheduler/internal/models"
+)
+
+func TestAllowedTransitions(t *testing.T) {
+	cases := []struct {
+		from  models.TaskStatus
+		event models.TaskEvent
+		to    models.TaskStatus
+	}{
+		{models.TaskStatusScheduled, models.TaskEventPause, models.TaskStatusPaused},
+		{models.TaskStatusPaused, models.TaskEventResume, models.TaskStatusScheduled},
+		{models.TaskStatusScheduled, models.TaskEventComplete, models.TaskStatusCompleted},
+		{models.TaskStatusScheduled, models.TaskEventCancel, models.TaskStatusCancelled},
+		{models.TaskStatusScheduled, models.TaskEventFail, models.TaskStatusFailed},
+		{models.TaskStatusPaused, models.TaskEventCancel, models.TaskStatusCancelled},
+		// Tasks left active by the old resume can be moved back on
+		{models.TaskStatusActive, models.TaskEventResume, models.TaskStatusScheduled},
+	}
+
+	for _, tc := range cases {
+		next, err := tc.from.Next(tc.event)
+		require.NoError(t, err, "%s on %s", tc.event, tc.from)
+		assert.Equal(t, tc.to, next, "%s on %s", tc.event, tc.from)
+	}
+}
+
+func TestIllegalTransitions(t *testing.T) {
+	cases := []struct {
+		from  models.TaskStatus
+		event models.TaskEvent
+	}{
+		{models.TaskStatusScheduled, models.TaskEventResume},
+		{models.TaskStatusPaused, models.TaskEventPause},
+		{models.TaskStatusPaused, models.TaskEventComplete},
+		{models.TaskStatusCompleted, models.TaskEventResume},
+		{models.TaskStatusCompleted, models.TaskEventCancel},
+		{models.TaskStatusCancelled, models.TaskEventResume},
+		{models.TaskStatusCancelled, models.TaskEventCancel},
+		{models.TaskStatusPaused, models.TaskEventFail},
+		{models.TaskStatusFailed, models.TaskEventResume},
+	}
+
+	for _, tc := range cases {
+		next, err := tc.from.Next(tc.event)
+		var transitionErr *models.TransitionError
+		require.True(t, errors.As(err, &transitionErr), "%s on %s", tc.event, tc.from)
+		assert.Equal(t, tc.from, transitionErr.From)
+		assert.Equal(t, tc.from, next)
+	}
+}
+
+func TestTerminalStatuses(t *testing.T) {
+	assert.True(t, models.TaskStatusCompleted.Terminal())
+	assert.True(t, models.TaskStatusCancelled.Terminal())
+	assert.True(t, models.TaskStatusFailed.Terminal())
+	assert.False(t, models.TaskStatusScheduled.Terminal())
+	assert.False(t, models.TaskStatusPaused.Terminal())
+}
@@ -0,0 +1,207 @@
+package transport
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"task-scheduler/internal/executor"
+)
+
+func TestDefaultEgressPolicyBlocksMetadataEndpoint(t *testing.T) {
+	httpExecutor := executor.NewHTTPExecutor()
+
+	err := httpExecutor.ValidateTask(newTask("http://169.254.169.254/latest/meta-data/", nil))
+	require.Error(t, err)
+	assert.ErrorIs(t, err, executor.ErrEgressDenied)
+
+	result := httpExecutor.Execute(newTask("http://169.254.169.254/latest/meta-data/", nil))
+	assert.False(t, result.Success)
+	require.NotNil(t, result.ErrorMessage)
+	assert.Contains(t, *result.ErrorMessage, "egress policy violation")
+}
+
+func TestDefaultEgressPolicyBlocksPrivateNetworks(t *testing.T) {
+	policy := executor.DefaultEgressPolicy()
+	for _, target := range []string{
+		"http://127.0.0.1:8080/admin",
+		"http://[::1]/",
+		"http://10.1.2.3/",
+		"http://172.20.0.1/",
+		"http://192.168.1.1/",
+		"http://[fd00::1]/",
+		"http://0.0.0.0:8080/",
+		"http://[::]:8080/",
+		"http://100.64.0.1/",
+	} {
+		parsed, err := url.Parse(target)
+		require.NoError(t, err)
+		assert.ErrorIs(t, policy.CheckURL(parsed), executor.ErrEgressDenied, target)
+	}
+
+	// Opting in lifts the private ranges but never the link-local ones
+	policy, err := executor.NewEgressPolicy(executor.EgressPolicyConfig{AllowPrivateNetworks: true})
+	require.NoError(t, err)
+	loopback, _ := url.Parse("http://127.0.0.1:8080/")
+	assert.NoError(t, policy.CheckURL(loopback))
+	metadata, _ := url.Parse("http://169.254.169.254/")
+	assert.ErrorIs(t, policy.CheckURL(metadata), executor.ErrEgressDenied)
+}
+
+func TestEgressPolicyProxiesNeedOptIn(t *testing.T) {
+	proxy, err := url.Parse("http://proxy.example.com:3128")
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, executor.DefaultEgressPolicy().CheckProxy(proxy), executor.ErrEgressDenied)
+
+	policy, err := executor.NewEgressPolicy(executor.EgressPolicyConfig{AllowAnyProxy: true})
+	require.NoError(t, err)
+	assert.NoError(t, policy.CheckProxy(proxy))
+}
+
+func TestEgressPolicyRules(t *testing.T) {
+	policy, err := executor.NewEgressPolicy(executor.EgressPolicyConfig{
+		Allow:                executor.EgressRules{Hosts: []string{"*.partner.example.com", "127.0.0.1"}},
+		Deny:                 executor.EgressRules{Hosts: []string{"admin.partner.example.com"}, Ports: []int{22}},
+		AllowPrivateNetworks: true,
+	})
+	require.NoError(t, err)
+
+	cases := []struct {
+		url     string
+		allowed bool
+	}{
+		{"https://api.partner.example.com/v1", true},
+		{"https://admin.partner.example.com/", false},
+		{"https://api.partner.example.com:22/", false},
+		{"https://evil.example.org/", false},
+		{"ftp://api.partner.example.com/", false},
+		{"http://127.0.0.1:8080/", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.url, func(t *testing.T) {
+			target, err := url.Parse(tc.url)
+			require.NoError(t, err)
+			err = policy.CheckURL(target)
+			if tc.allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, executor.ErrEgressDenied)
+			}
+		})
+	}
+
+	_, err = executor.NewEgressPolicy(executor.EgressPolicyConfig{Deny: executor.EgressRules{CIDRs: []string{"not-a-cidr"}}})
+	assert.Error(t, err)
+}
+
+func TestEgressPolicyChecksResolvedAddresses(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer server.Close()
+
+	// The hostname passes the host rules but resolves into a denied range,
+	// which is what a DNS rebinding attack looks like from the dialer
+	policy, err := executor.NewEgressPolicy(executor.EgressPolicyConfig{
+		Allow: executor.EgressRules{Hosts: []string{"localhost"}},
+		Deny:  executor.EgressRules{CIDRs: []string{"127.0.0.0/8", "::1/128"}},
+	})
+	require.NoError(t, err)
+
+	httpExecutor := executor.NewHTTPExecutor(executor.WithEgressPolicy(policy))
+	target := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+
+	result := httpExecutor.Execute(newTask(target, nil))
+	assert.False(t, result.Success)
+	require.NotNil(t, result.ErrorMessage)
+	assert.Contains(t, *result.ErrorMessage, "egress policy violation")
+	assert.Equal(t, int32(0), atomic.LoadInt32(&hits))
+}
+
+func TestHTTPExecutorRoutesThroughProxy(t *testing.T) {
+	var proxied int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&proxied, 1)
+		assert.Equal(t, "partner.example.com", r.URL.Host)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer proxy.Close()
+
+	proxyHost := strings.TrimPrefix(proxy.URL, "http://")
+	policy, err := executor.NewEgressPolicy(executor.EgressPolicyConfig{
+		Deny:    executor.EgressRules{CIDRs: []string{"127.0.0.0/8"}},
+		Proxies: []string{proxyHost},
+	})
+	require.NoError(t, err)
+
+	httpExecutor := executor.NewHTTPExecutor(executor.WithEgressPolicy(policy))
+
+	task := newTask("http://partner.example.com/ping", nil)
+	task.Proxy = &proxy.URL
+	result := httpExecutor.Execute(task)
+	assert.True(t, result.Success, "error: %v", result.ErrorMessage)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&proxied))
+
+	unlisted := "http://10.0.0.1:3128"
+	task.Proxy = &unlisted
+	assert.ErrorIs(t, httpExecutor.ValidateTask(task), executor.ErrEgressDenied)
+
+	unsupported := "ftp://proxy.example.com"
+	task.Proxy = &unsupported
+	assert.Error(t, httpExecutor.ValidateTask(task))
+}
+
+func TestListedProxyIsOnlyTrustedAsProxy(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	// localhost passes the host rules; only the dialer sees it is loopback
+	local := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	policy, err := executor.NewEgressPolicy(executor.EgressPolicyConfig{
+		Proxies: []string{strings.TrimPrefix(local, "http://")},
+	})
+	require.NoError(t, err)
+
+	// Naming the proxy's address as the target gets no pass
+	result := executor.NewHTTPExecutor(executor.WithEgressPolicy(policy)).Execute(newTask(local+"/admin", nil))
+	assert.False(t, result.Success)
+	require.NotNil(t, result.ErrorMessage)
+	assert.Contains(t, *result.ErrorMessage, "egress policy violation")
+	assert.Equal(t, int32(0), atomic.LoadInt32(&hits))
+}
+
+func TestProxiedRedirectChecksResolvedTarget(t *testing.T) {
+	var proxied int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&proxied, 1)
+		// localhost passes the host rules but resolves to loopback
+		http.Redirect(w, r, "http://localhost/admin", http.StatusFound)
+	}))
+	defer proxy.Close()
+
+	policy, err := executor.NewEgressPolicy(executor.EgressPolicyConfig{
+		Proxies: []string{strings.TrimPrefix(proxy.URL, "http://")},
+	})
+	require.NoError(t, err)
+
+	task := newTask("http://partner.example.com/start", nil)
+	task.Proxy = &proxy.URL
+	result := executor.NewHTTPExecutor(executor.WithEgressPolicy(policy)).Execute(task)
+	assert.False(t, result.Success)
+	require.NotNil(t, result.ErrorMessage)
+	assert.Contains(t, *result.ErrorMessage, "egress policy violation")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&proxied))
+}
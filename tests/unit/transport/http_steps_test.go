@@ -0,0 +1,202 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"task-scheduler/internal/executor"
+	"task-scheduler/internal/models"
+	"task-scheduler/internal/ratelimit"
+)
+
+func newStepsTask(t *testing.T, steps ...models.HTTPStep) *models.Task {
+	config, err := json.Marshal(models.HTTPStepsAction{Steps: steps})
+	require.NoError(t, err)
+	return &models.Task{ID: uuid.New(), Name: "export", ActionType: models.ActionTypeHTTPSteps, ActionConfig: config}
+}
+
+func newExportServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s-1", Path: "/"})
+		io.WriteString(w, `{"data":{"access_token":"t-123"}}`)
+	})
+	mux.HandleFunc("/exports", func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("session")
+		if r.Header.Get("Authorization") != "Bearer t-123" || err != nil || cookie.Value != "s-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		io.WriteString(w, `{"id":"e-9"}`)
+	})
+	mux.HandleFunc("/exports/e-9", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ready")
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestStepsPassExtractedValuesAndCookies(t *testing.T) {
+	server := newExportServer(t)
+	stepsExecutor := executor.NewHTTPStepsExecutor(executor.NewHTTPExecutor(executor.WithEgressPolicy(executor.PermissiveEgressPolicy())))
+
+	task := newStepsTask(t,
+		models.HTTPStep{
+			Name:    "login",
+			Method:  "POST",
+			URL:     server.URL + "/login",
+			Extract: map[string]string{"token": "json:data.access_token"},
+		},
+		models.HTTPStep{
+			Name:    "export",
+			Method:  "POST",
+			URL:     server.URL + "/exports",
+			Headers: map[string]string{"Authorization": "Bearer {{.Vars.token}}"},
+			Extract: map[string]string{"export_id": "json:id", "code": "status"},
+		},
+		models.HTTPStep{
+			Name:   "confirm",
+			If:     "{{eq .Prev.StatusCode 202}}",
+			Method: "GET",
+			URL:    server.URL + "/exports/{{.Vars.export_id}}",
+		},
+	)
+	require.NoError(t, stepsExecutor.ValidateTask(task))
+
+	result := stepsExecutor.Execute(task)
+	require.True(t, result.Success, "error: %v", result.ErrorMessage)
+	require.Len(t, result.Steps, 3)
+
+	assert.Equal(t, map[string]string{"token": "[REDACTED]"}, result.Steps[0].Extracted)
+	assert.Equal(t, map[string]string{"export_id": "e-9", "code": "202"}, result.Steps[1].Extracted)
+	assert.Equal(t, []string{"[REDACTED]"}, result.Steps[1].Request.Headers["Authorization"])
+	assert.False(t, result.Steps[2].Skipped)
+
+	// The combined result mirrors the last step
+	assert.Equal(t, http.StatusOK, *result.StatusCode)
+	assert.Equal(t, "ready", *result.ResponseBody)
+}
+
+func TestStepConditionsAndFailures(t *testing.T) {
+	server := newExportServer(t)
+	stepsExecutor := executor.NewHTTPStepsExecutor(executor.NewHTTPExecutor(executor.WithEgressPolicy(executor.PermissiveEgressPolicy())))
+
+	// Without logging in the export is refused; continue_on_failure lets the
+	// next step react to the 401
+	result := stepsExecutor.Execute(newStepsTask(t,
+		models.HTTPStep{Name: "export", Method: "POST", URL: server.URL + "/exports", ContinueOnFailure: true},
+		models.HTTPStep{Name: "skipped", If: `{{eq .Prev.StatusCode 202}}`, Method: "GET", URL: server.URL + "/exports/e-9"},
+		models.HTTPStep{Name: "relogin", If: `{{eq .Prev.StatusCode 401}}`, Method: "POST", URL: server.URL + "/login"},
+	))
+	require.True(t, result.Success, "error: %v", result.ErrorMessage)
+	require.Len(t, result.Steps, 3)
+	assert.False(t, result.Steps[0].Success)
+	assert.True(t, result.Steps[1].Skipped)
+	assert.True(t, result.Steps[2].Success)
+
+	// A failed step stops the sequence
+	result = stepsExecutor.Execute(newStepsTask(t,
+		models.HTTPStep{Method: "POST", URL: server.URL + "/exports"},
+		models.HTTPStep{Method: "GET", URL: server.URL + "/exports/e-9"},
+	))
+	assert.False(t, result.Success)
+	require.Len(t, result.Steps, 1)
+	assert.Equal(t, "step1", result.Steps[0].Name)
+	assert.Contains(t, *result.ErrorMessage, "Step step1 failed")
+
+	// So does an extractor that finds nothing
+	result = stepsExecutor.Execute(newStepsTask(t,
+		models.HTTPStep{Method: "POST", URL: server.URL + "/login", Extract: map[string]string{"id": "json:data.missing"}},
+	))
+	assert.False(t, result.Success)
+	assert.Contains(t, *result.ErrorMessage, "extract id")
+}
+
+func TestStepsValidation(t *testing.T) {
+	stepsExecutor := executor.NewHTTPStepsExecutor(executor.NewHTTPExecutor(executor.WithEgressPolicy(executor.PermissiveEgressPolicy())))
+	step := models.HTTPStep{Method: "GET", URL: "https://api.example.com/{{.Vars.id}}"}
+
+	assert.NoError(t, stepsExecutor.ValidateTask(newStepsTask(t, step)))
+	assert.Error(t, stepsExecutor.ValidateTask(newStepsTask(t)))
+
+	duplicate := step
+	duplicate.Name = "step1"
+	assert.Error(t, stepsExecutor.ValidateTask(newStepsTask(t, step, duplicate)))
+
+	badExtractor := step
+	badExtractor.Extract = map[string]string{"id": "xpath://id"}
+	assert.Error(t, stepsExecutor.ValidateTask(newStepsTask(t, badExtractor)))
+
+	badTemplate := step
+	badTemplate.If = "{{eq .Prev.StatusCode"
+	assert.Error(t, stepsExecutor.ValidateTask(newStepsTask(t, badTemplate)))
+
+	metadata := models.HTTPStep{Method: "GET", URL: "http://169.254.169.254/latest/meta-data"}
+	assert.Error(t, stepsExecutor.ValidateTask(newStepsTask(t, metadata)))
+}
+
+func TestStepsApplyHostLimitsPerStep(t *testing.T) {
+	server := newExportServer(t)
+	limiter, err := ratelimit.NewHostLimiter(ratelimit.Config{
+		Default:        ratelimit.Limit{Rate: 0.001, Burst: 1},
+		MaxWaitSeconds: 1,
+	})
+	require.NoError(t, err)
+	stepsExecutor := executor.NewHTTPStepsExecutor(executor.NewHTTPExecutor(executor.WithEgressPolicy(executor.PermissiveEgressPolicy())))
+	stepsExecutor.EnableHostLimits(context.Background(), limiter, nil)
+
+	task := newStepsTask(t,
+		models.HTTPStep{Name: "login", Method: "POST", URL: server.URL + "/login"},
+		models.HTTPStep{Name: "ready", Method: "GET", URL: server.URL + "/exports/e-9"},
+	)
+
+	// The first step uses the host's only token, so the second is kept back
+	result := stepsExecutor.Execute(task)
+	require.Len(t, result.Steps, 2)
+	assert.True(t, result.Steps[0].Success)
+	assert.Equal(t, models.OutcomeRateLimited, result.Steps[1].Outcome)
+	assert.False(t, result.Success)
+	assert.Empty(t, result.Outcome)
+
+	// Kept back before anything went out, the whole run is deferred
+	result = stepsExecutor.Execute(task)
+	require.Len(t, result.Steps, 1)
+	assert.Equal(t, models.OutcomeRateLimited, result.Outcome)
+	assert.Positive(t, result.DeferFor)
+}
+
+func TestStepsTripHostCircuitBreaker(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/down", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	breakers := executor.NewCircuitBreakers(executor.CircuitBreakerConfig{
+		WindowSize: 1, MinRequests: 1, FailureRateThreshold: 1,
+	})
+	stepsExecutor := executor.NewHTTPStepsExecutor(executor.NewHTTPExecutor(executor.WithEgressPolicy(executor.PermissiveEgressPolicy())))
+	stepsExecutor.EnableHostLimits(context.Background(), nil, breakers)
+
+	task := newStepsTask(t, models.HTTPStep{Name: "poll", Method: "GET", URL: server.URL + "/down"})
+
+	result := stepsExecutor.Execute(task)
+	assert.False(t, result.Success)
+	assert.Empty(t, result.Outcome)
+
+	result = stepsExecutor.Execute(task)
+	assert.Equal(t, models.OutcomeCircuitOpen, result.Outcome)
+	assert.Equal(t, models.OutcomeCircuitOpen, result.Steps[0].Outcome)
+	assert.Equal(t, executor.CircuitOpen, breakers.Snapshot()[0].State)
+}
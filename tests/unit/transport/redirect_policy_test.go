@@ -0,0 +1,130 @@
+package transport
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"task-scheduler/internal/executor"
+	"task-scheduler/internal/models"
+)
+
+type memoryCookieStore struct {
+	mu   sync.Mutex
+	jars map[uuid.UUID]models.StoredCookies
+}
+
+func (s *memoryCookieStore) LoadCookies(taskID uuid.UUID) (models.StoredCookies, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.jars[taskID], nil
+}
+
+func (s *memoryCookieStore) SaveCookies(taskID uuid.UUID, cookies models.StoredCookies) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jars[taskID] = cookies
+	return nil
+}
+
+func newRedirectServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/b", http.StatusFound)
+	})
+	mux.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/c", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/c", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRedirectPolicies(t *testing.T) {
+	server := newRedirectServer(t)
+	httpExecutor := executor.NewHTTPExecutor(executor.WithEgressPolicy(executor.PermissiveEgressPolicy()))
+
+	follow := newTask(server.URL+"/a", nil)
+	result := httpExecutor.Execute(follow)
+	require.True(t, result.Success, "error: %v", result.ErrorMessage)
+	require.Len(t, result.RedirectChain, 2)
+	assert.Equal(t, http.StatusFound, result.RedirectChain[0].StatusCode)
+	assert.True(t, strings.HasSuffix(result.RedirectChain[0].URL, "/a"))
+	assert.True(t, strings.HasSuffix(result.RedirectChain[1].Location, "/c"))
+
+	none := newTask(server.URL+"/a", nil)
+	none.Redirects = &models.RedirectPolicy{Mode: models.RedirectNone}
+	result = httpExecutor.Execute(none)
+	assert.True(t, result.Success)
+	assert.Equal(t, http.StatusFound, *result.StatusCode)
+	assert.Len(t, result.RedirectChain, 1)
+
+	limited := newTask(server.URL+"/a", nil)
+	limited.Redirects = &models.RedirectPolicy{Mode: models.RedirectFollow, Max: 1}
+	result = httpExecutor.Execute(limited)
+	assert.False(t, result.Success)
+	require.NotNil(t, result.ErrorMessage)
+	assert.Contains(t, *result.ErrorMessage, "stopped after 1 redirects")
+
+	assert.Error(t, httpExecutor.ValidateTask(&models.Task{
+		URL:       server.URL,
+		Redirects: &models.RedirectPolicy{Mode: "sometimes"},
+	}))
+}
+
+func TestSameHostRedirectPolicy(t *testing.T) {
+	target := newRedirectServer(t)
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL+"/c", http.StatusFound)
+	}))
+	defer origin.Close()
+
+	task := newTask(origin.URL, nil)
+	task.Redirects = &models.RedirectPolicy{Mode: models.RedirectSameHost}
+
+	result := executor.NewHTTPExecutor(executor.WithEgressPolicy(executor.PermissiveEgressPolicy())).Execute(task)
+	assert.False(t, result.Success)
+	require.NotNil(t, result.ErrorMessage)
+	assert.Contains(t, *result.ErrorMessage, "another host")
+}
+
+func TestCookieJarPersistsAcrossRuns(t *testing.T) {
+	var seen []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("session")
+		if err != nil {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "s-1", Path: "/"})
+			seen = append(seen, "")
+			return
+		}
+		seen = append(seen, cookie.Value)
+	}))
+	defer server.Close()
+
+	store := &memoryCookieStore{jars: make(map[uuid.UUID]models.StoredCookies)}
+	task := newTask(server.URL+"/login", nil)
+	task.CookieJar = true
+
+	// A fresh executor per run shows the jar comes from the store, not memory
+	require.True(t, executor.NewHTTPExecutor(executor.WithEgressPolicy(executor.PermissiveEgressPolicy()), executor.WithCookieStore(store)).Execute(task).Success)
+	require.True(t, executor.NewHTTPExecutor(executor.WithEgressPolicy(executor.PermissiveEgressPolicy()), executor.WithCookieStore(store)).Execute(task).Success)
+
+	assert.Equal(t, []string{"", "s-1"}, seen)
+	require.Len(t, store.jars[task.ID], 1)
+	assert.Equal(t, "session", store.jars[task.ID][0].Name)
+
+	// Jars are isolated per task
+	other := newTask(server.URL+"/login", nil)
+	other.CookieJar = true
+	require.True(t, executor.NewHTTPExecutor(executor.WithEgressPolicy(executor.PermissiveEgressPolicy()), executor.WithCookieStore(store)).Execute(other).Success)
+	assert.Equal(t, "", seen[2])
+}
@@ -0,0 +1,92 @@
+package transport
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"task-scheduler/internal/executor"
+	"task-scheduler/internal/models"
+)
+
+func TestResultKeepsMultiValuedHeadersAndRequestSnapshot(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/start", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/final?api_key=abc&page=2", http.StatusTemporaryRedirect)
+	})
+	mux.HandleFunc("/final", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Set-Cookie", "a=1")
+		w.Header().Add("Set-Cookie", "b=2")
+		w.WriteHeader(http.StatusOK)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	payload := `{"report":"daily"}`
+	task := newTask(server.URL+"/start", nil)
+	task.Method = "POST"
+	task.Payload = &payload
+	task.Headers = models.Headers{
+		"Authorization": "Bearer secret-token",
+		"X-Request-Id":  "req-1",
+	}
+
+	result := executor.NewHTTPExecutor(executor.WithEgressPolicy(executor.PermissiveEgressPolicy())).Execute(task)
+	require.True(t, result.Success, "error: %v", result.ErrorMessage)
+
+	assert.Equal(t, []string{"a=1", "b=2"}, result.ResponseHeaders["Set-Cookie"])
+
+	snapshot := result.Request
+	require.NotNil(t, snapshot)
+	assert.Equal(t, "POST", snapshot.Method)
+	assert.Contains(t, snapshot.URL, "/final?")
+	assert.Contains(t, snapshot.URL, "page=2")
+	assert.NotContains(t, snapshot.URL, "abc")
+	assert.Equal(t, []string{"[REDACTED]"}, snapshot.Headers["Authorization"])
+	assert.Equal(t, "req-1", snapshot.Headers.Get("x-request-id"))
+
+	sum := sha256.Sum256([]byte(payload))
+	assert.Equal(t, hex.EncodeToString(sum[:]), snapshot.BodySHA256)
+	assert.Equal(t, len(payload), snapshot.BodySize)
+}
+
+func TestRequestSnapshotAfterRedirectToGet(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/start", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/final", http.StatusFound)
+	})
+	mux.HandleFunc("/final", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	payload := `{"report":"daily"}`
+	task := newTask(server.URL+"/start", nil)
+	task.Method = "POST"
+	task.Payload = &payload
+
+	result := executor.NewHTTPExecutor(executor.WithEgressPolicy(executor.PermissiveEgressPolicy())).Execute(task)
+	require.True(t, result.Success, "error: %v", result.ErrorMessage)
+
+	// The last request sent was a GET without the payload
+	snapshot := result.Request
+	require.NotNil(t, snapshot)
+	assert.Equal(t, "GET", snapshot.Method)
+	assert.Empty(t, snapshot.BodySHA256)
+	assert.Zero(t, snapshot.BodySize)
+}
+
+func TestMultiHeadersScanAcceptsLegacyRows(t *testing.T) {
+	var headers models.MultiHeaders
+	require.NoError(t, headers.Scan([]byte(`{"Content-Type":"application/json"}`)))
+	assert.Equal(t, "application/json", headers.Get("content-type"))
+
+	require.NoError(t, headers.Scan([]byte(`{"Vary":["Accept","Origin"]}`)))
+	assert.Equal(t, []string{"Accept", "Origin"}, headers["Vary"])
+}
@@ -0,0 +1,176 @@
+package transport
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"task-scheduler/internal/executor"
+	"task-scheduler/internal/models"
+)
+
+type testPKI struct {
+	caCert *x509.Certificate
+	caKey  *ecdsa.PrivateKey
+	caPEM  []byte
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testPKI{
+		caCert: cert,
+		caKey:  key,
+		caPEM:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+func (p *testPKI) issue(t *testing.T, name string, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, p.caCert, &key.PublicKey, p.caKey)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, data, 0600))
+	return path
+}
+
+func newMTLSServer(t *testing.T, pki *testPKI) *httptest.Server {
+	serverCert, serverKey := pki.issue(t, "internal.test", x509.ExtKeyUsageServerAuth)
+	cert, err := tls.X509KeyPair(serverCert, serverKey)
+	require.NoError(t, err)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(pki.caCert)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTask(url string, profile *string) *models.Task {
+	return &models.Task{
+		ID:         uuid.New(),
+		Name:       "mtls task",
+		Method:     "GET",
+		URL:        url,
+		TLSProfile: profile,
+	}
+}
+
+func TestHTTPExecutorUsesTLSProfile(t *testing.T) {
+	pki := newTestPKI(t)
+	server := newMTLSServer(t, pki)
+
+	dir := t.TempDir()
+	clientCert, clientKey := pki.issue(t, "scheduler-client", x509.ExtKeyUsageClientAuth)
+
+	profiles, err := executor.NewTLSProfiles([]executor.TLSProfile{{
+		Name:       "internal",
+		CertFile:   writeFile(t, dir, "client.pem", clientCert),
+		KeyFile:    writeFile(t, dir, "client-key.pem", clientKey),
+		CAFile:     writeFile(t, dir, "ca.pem", pki.caPEM),
+		ServerName: "internal.test",
+		MinVersion: "1.2",
+	}}, false)
+	require.NoError(t, err)
+
+	httpExecutor := executor.NewHTTPExecutor(executor.WithEgressPolicy(executor.PermissiveEgressPolicy()), executor.WithTLSProfiles(profiles))
+
+	profile := "internal"
+	result := httpExecutor.Execute(newTask(server.URL, &profile))
+	require.NotNil(t, result)
+	assert.True(t, result.Success, "error: %v", result.ErrorMessage)
+	require.NotNil(t, result.ResponseBody)
+	assert.Equal(t, "scheduler-client", *result.ResponseBody)
+
+	// Without the profile the private CA is not trusted
+	result = httpExecutor.Execute(newTask(server.URL, nil))
+	assert.False(t, result.Success)
+	require.NotNil(t, result.ErrorMessage)
+}
+
+func TestTLSProfileValidation(t *testing.T) {
+	_, err := executor.NewTLSProfiles([]executor.TLSProfile{{
+		Name:               "lax",
+		InsecureSkipVerify: true,
+	}}, false)
+	assert.Error(t, err, "insecure profiles must be gated by admin config")
+
+	profiles, err := executor.NewTLSProfiles([]executor.TLSProfile{{
+		Name:               "lax",
+		InsecureSkipVerify: true,
+	}}, true)
+	require.NoError(t, err)
+
+	_, err = executor.NewTLSProfiles([]executor.TLSProfile{{Name: "old", MinVersion: "0.9"}}, false)
+	assert.Error(t, err)
+
+	httpExecutor := executor.NewHTTPExecutor(executor.WithEgressPolicy(executor.PermissiveEgressPolicy()), executor.WithTLSProfiles(profiles))
+
+	known := "lax"
+	assert.NoError(t, httpExecutor.ValidateTask(newTask("https://example.com", &known)))
+
+	unknown := "missing"
+	assert.Error(t, httpExecutor.ValidateTask(newTask("https://example.com", &unknown)))
+}
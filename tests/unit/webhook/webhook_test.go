@@ -0,0 +1,78 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"task-scheduler/internal/executor"
+	"task-scheduler/internal/handlers"
+	"task-scheduler/internal/models"
+)
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	body := []byte(`{"ref":"main"}`)
+	signature := sign("s3cret", string(body))
+
+	assert.True(t, handlers.VerifyWebhookSignature("s3cret", body, signature))
+	assert.True(t, handlers.VerifyWebhookSignature("s3cret", body, "sha256="+signature))
+	assert.False(t, handlers.VerifyWebhookSignature("other", body, signature))
+	assert.False(t, handlers.VerifyWebhookSignature("s3cret", []byte(`{"ref":"dev"}`), signature))
+	assert.False(t, handlers.VerifyWebhookSignature("s3cret", body, ""))
+	assert.False(t, handlers.VerifyWebhookSignature("s3cret", body, "not-hex"))
+}
+
+func TestWebhookTaskRendersDelivery(t *testing.T) {
+	var gotBody, gotHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotBody, gotHeader = string(body), r.Header.Get("X-Delivery")
+	}))
+	defer server.Close()
+
+	payload := `{"branch": "{{.Webhook.JSON.ref}}", "raw": {{json .Webhook.Body}}}`
+	task := &models.Task{
+		ID:           uuid.New(),
+		Name:         "deploy",
+		TriggerType:  models.TriggerTypeWebhook,
+		TriggerValue: "token",
+		Method:       "POST",
+		URL:          server.URL,
+		Headers:      models.Headers{"X-Delivery": `{{.Webhook.Header "X-GitHub-Delivery"}}`},
+		Payload:      &payload,
+	}
+
+	httpExecutor := executor.NewHTTPExecutor(executor.WithEgressPolicy(executor.PermissiveEgressPolicy()))
+	require.NoError(t, httpExecutor.ValidateTask(task))
+
+	task.Inbound = &models.InboundRequest{
+		Body:    `{"ref":"main"}`,
+		Headers: map[string][]string{"X-Github-Delivery": {"d-42"}},
+		JSON:    map[string]interface{}{"ref": "main"},
+	}
+	result := httpExecutor.Execute(task)
+	require.True(t, result.Success, "%v", result.ErrorMessage)
+	assert.Equal(t, `{"branch": "main", "raw": "{\"ref\":\"main\"}"}`, gotBody)
+	assert.Equal(t, "d-42", gotHeader)
+
+	// The stored payload is left as a template
+	assert.Equal(t, `{"branch": "{{.Webhook.JSON.ref}}", "raw": {{json .Webhook.Body}}}`, *task.Payload)
+
+	broken := `{{.Webhook.Body`
+	task.Payload = &broken
+	assert.Error(t, httpExecutor.ValidateTask(task))
+}
@@ -0,0 +1,103 @@
+package workflow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"task-scheduler/internal/models"
+	"task-scheduler/internal/workflow"
+)
+
+func nodes(ids ...string) []models.WorkflowNode {
+	var out []models.WorkflowNode
+	for _, id := range ids {
+		out = append(out, models.WorkflowNode{ID: id, TaskID: uuid.New()})
+	}
+	return out
+}
+
+func TestValidateRejectsBadGraphs(t *testing.T) {
+	abc := nodes("a", "b", "c")
+
+	assert.NoError(t, workflow.Validate(abc, []models.WorkflowEdge{
+		{From: "a", To: "b"}, {From: "a", To: "c", Condition: models.EdgeOnFailure}, {From: "b", To: "c"},
+	}))
+
+	err := workflow.Validate(abc, []models.WorkflowEdge{
+		{From: "a", To: "b"}, {From: "b", To: "c"}, {From: "c", To: "a"},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cycle")
+
+	assert.Error(t, workflow.Validate(abc, []models.WorkflowEdge{{From: "a", To: "a"}}))
+	assert.Error(t, workflow.Validate(abc, []models.WorkflowEdge{{From: "a", To: "z"}}))
+	assert.Error(t, workflow.Validate(abc, []models.WorkflowEdge{{From: "a", To: "b", Condition: "sometimes"}}))
+	assert.Error(t, workflow.Validate(abc, []models.WorkflowEdge{{From: "a", To: "b"}, {From: "a", To: "b"}}))
+	assert.Error(t, workflow.Validate(nodes("a", "a"), nil))
+	assert.Error(t, workflow.Validate(nil, nil))
+}
+
+// finish marks a running node and advances the run, returning what starts next
+func finish(wf *models.Workflow, run *models.WorkflowRun, id string, state models.NodeState) []string {
+	node := run.Nodes[id]
+	node.State = state
+	run.Nodes[id] = node
+	return workflow.Advance(wf, run, time.Now())
+}
+
+func TestAdvanceWaitsForFanIn(t *testing.T) {
+	// a fans out to b and c; d runs once both have finished, whatever the outcome
+	wf := &models.Workflow{
+		Nodes: nodes("a", "b", "c", "d"),
+		Edges: []models.WorkflowEdge{
+			{From: "a", To: "b"},
+			{From: "a", To: "c"},
+			{From: "b", To: "d", Condition: models.EdgeAlways},
+			{From: "c", To: "d", Condition: models.EdgeAlways},
+		},
+	}
+	run := workflow.NewRun(wf, time.Now())
+
+	assert.Equal(t, []string{"a"}, workflow.Advance(wf, run, time.Now()))
+	assert.ElementsMatch(t, []string{"b", "c"}, finish(wf, run, "a", models.NodeStateSucceeded))
+	assert.Empty(t, finish(wf, run, "b", models.NodeStateFailed))
+	assert.Equal(t, []string{"d"}, finish(wf, run, "c", models.NodeStateSucceeded))
+	assert.Equal(t, models.WorkflowRunRunning, run.Status)
+
+	assert.Empty(t, finish(wf, run, "d", models.NodeStateSucceeded))
+	assert.Equal(t, models.WorkflowRunFailed, run.Status)
+	assert.NotNil(t, run.FinishedAt)
+}
+
+func TestAdvanceFollowsConditionsAndPropagatesSkips(t *testing.T) {
+	// a -> b on success -> c; a -> cleanup on failure
+	wf := &models.Workflow{
+		Nodes: nodes("a", "b", "c", "cleanup"),
+		Edges: []models.WorkflowEdge{
+			{From: "a", To: "b"},
+			{From: "b", To: "c", Condition: models.EdgeAlways},
+			{From: "a", To: "cleanup", Condition: models.EdgeOnFailure},
+		},
+	}
+
+	run := workflow.NewRun(wf, time.Now())
+	workflow.Advance(wf, run, time.Now())
+	assert.Equal(t, []string{"cleanup"}, finish(wf, run, "a", models.NodeStateFailed))
+	assert.Equal(t, models.NodeStateSkipped, run.Nodes["b"].State)
+	assert.Equal(t, models.NodeStateSkipped, run.Nodes["c"].State)
+
+	assert.Empty(t, finish(wf, run, "cleanup", models.NodeStateSucceeded))
+	assert.Equal(t, models.WorkflowRunFailed, run.Status)
+
+	run = workflow.NewRun(wf, time.Now())
+	workflow.Advance(wf, run, time.Now())
+	assert.Equal(t, []string{"b"}, finish(wf, run, "a", models.NodeStateSucceeded))
+	assert.Equal(t, models.NodeStateSkipped, run.Nodes["cleanup"].State)
+	assert.Equal(t, []string{"c"}, finish(wf, run, "b", models.NodeStateSucceeded))
+	finish(wf, run, "c", models.NodeStateSucceeded)
+	assert.Equal(t, models.WorkflowRunSucceeded, run.Status)
+}
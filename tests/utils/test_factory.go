@@ -143,7 +143,7 @@ func (f *ResultFactory) CreateSuccessResult(taskID uuid.UUID) *models.TaskResult
 		RunAt:           time.Now(),
 		StatusCode:      intPtr(200),
 		Success:         true,
-		ResponseHeaders: models.Headers{"Content-Type": "application/json"},
+		ResponseHeaders: models.MultiHeaders{"Content-Type": {"application/json"}},
 		ResponseBody:    stringPtr(`{"status": "success"}`),
 		DurationMs:      150,
 		CreatedAt:       time.Now(),
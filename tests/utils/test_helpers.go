@@ -97,7 +97,7 @@ func (h *TestHelper) SetupTaskHandlers(router *gin.Engine) (*handlers.TaskHandle
 	taskRepo := repository.NewTaskRepository(h.db.DB)
 	resultRepo := repository.NewResultRepository(h.db.DB)
 	taskHandler := handlers.NewTaskHandler(taskRepo, resultRepo)
-	resultHandler := handlers.NewResultHandler(resultRepo)
+	resultHandler := handlers.NewResultHandler(resultRepo, nil)
 
 	// Setup routes
 	v1 := router.Group("/api/v1")